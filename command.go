@@ -66,6 +66,43 @@ func decodeAddressCmd(c *cli.Context) error {
 	return nil
 }
 
+func deriveAddressCmd(c *cli.Context) error {
+	var master *common.ExtendedKey
+	if seed := c.String("seed"); len(seed) > 0 {
+		b, err := hex.DecodeString(seed)
+		if err != nil {
+			return err
+		}
+		master, err = common.NewExtendedKeyFromSeed(b)
+		if err != nil {
+			return err
+		}
+	} else {
+		key, err := common.NewExtendedKeyFromString(c.String("key"))
+		if err != nil {
+			return err
+		}
+		master = key
+	}
+	key, err := master.DerivePath(c.String("path"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("address:\t%s\n", key.Address.String())
+	if key.Address.PrivateViewKey != nil {
+		fmt.Printf("view key:\t%s\n", key.Address.PrivateViewKey.String())
+	}
+	if key.Address.PrivateSpendKey != nil {
+		fmt.Printf("spend key:\t%s\n", key.Address.PrivateSpendKey.String())
+	}
+	fmt.Printf("extended key:\t%s\n", key.String())
+	if key.Address.PrivateViewKey != nil {
+		fmt.Printf("extended view:\t%s\n", key.Neuter(false).String())
+	}
+	fmt.Printf("extended public:\t%s\n", key.Neuter(true).String())
+	return nil
+}
+
 func decryptGhostCmd(c *cli.Context) error {
 	view, err := crypto.PrivateKeyFromString(c.String("view"))
 	if err != nil {
@@ -89,6 +126,63 @@ func decryptGhostCmd(c *cli.Context) error {
 	return nil
 }
 
+func scanOutputsCmd(c *cli.Context) error {
+	view, err := crypto.PrivateKeyFromString(c.String("view"))
+	if err != nil {
+		return err
+	}
+	account := common.Address{PrivateViewKey: view, PublicViewKey: view.Public()}
+	if c.String("spend") != "" {
+		spend, err := crypto.PrivateKeyFromString(c.String("spend"))
+		if err != nil {
+			return err
+		}
+		account.PrivateSpendKey = spend
+		account.PublicSpendKey = spend.Public()
+	} else {
+		addr, err := common.NewAddressFromString(c.String("address"))
+		if err != nil {
+			return err
+		}
+		account.PublicSpendKey = addr.PublicSpendKey
+	}
+
+	custom, err := config.Initialize(c.String("dir") + "/config.toml")
+	if err != nil {
+		return err
+	}
+	store, err := storage.NewBadgerStore(custom, c.String("dir"))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return common.ScanOutputs(store, account, c.Uint64("offset"), c.Uint64("count"), func(out *common.ScannedOutput) error {
+		item := map[string]interface{}{
+			"snapshot": out.Snapshot,
+			"topology": out.Topology,
+			"hash":     out.Transaction,
+			"index":    out.Index,
+			"type":     out.Output.Type,
+			"asset":    out.Asset,
+			"amount":   out.Output.Amount,
+			"key":      out.Output.Keys[out.KeyIndex],
+			"mask":     out.Output.Mask,
+			"script":   out.Output.Script,
+			"state":    out.State,
+		}
+		if out.PrivateKey != nil {
+			item["private"] = out.PrivateKey.String()
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	})
+}
+
 func updateHeadReference(c *cli.Context) error {
 	custom, err := config.Initialize(c.String("dir") + "/config.toml")
 	if err != nil {
@@ -170,6 +264,29 @@ func validateGraphEntries(c *cli.Context) error {
 	return nil
 }
 
+func repairGraphEntries(c *cli.Context) error {
+	custom, err := config.Initialize(c.String("dir") + "/config.toml")
+	if err != nil {
+		return err
+	}
+	store, err := storage.NewBadgerStore(custom, c.String("dir"))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := store.RepairGraphEntries(c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	for _, i := range report.Issues {
+		fmt.Printf("%s %s fixable: %t\n", i.Type, i.Detail, i.Fixable)
+	}
+	fmt.Printf("topology: %d snapshots: %d uniques: %d rounds: %d issues: %d applied: %t\n",
+		report.Topology, report.Snapshots, report.Uniques, report.Rounds, len(report.Issues), report.Applied)
+	return nil
+}
+
 func decodeTransactionCmd(c *cli.Context) error {
 	raw, err := hex.DecodeString(c.String("raw"))
 	if err != nil {
@@ -197,15 +314,120 @@ func signTransactionCmd(c *cli.Context) error {
 	}
 	raw.Node = c.String("node")
 
-	seed, err := hex.DecodeString(c.String("seed"))
+	tx, err := buildRawTransaction(raw, c.String("seed"), c.Int("stamp"))
+	if err != nil {
+		return err
+	}
+	accounts, err := parseSignerKeys(c.StringSlice("key"))
+	if err != nil {
+		return err
+	}
+
+	signed := tx.AsLatestVersion()
+	for i := range signed.Inputs {
+		err := signed.SignInput(raw, i, accounts)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println(hex.EncodeToString(signed.Marshal()))
+	return nil
+}
+
+func createPartialTransactionCmd(c *cli.Context) error {
+	var raw signerInput
+	err := json.Unmarshal([]byte(c.String("raw")), &raw)
+	if err != nil {
+		return err
+	}
+	raw.Node = c.String("node")
+
+	tx, err := buildRawTransaction(raw, c.String("seed"), c.Int("stamp"))
+	if err != nil {
+		return err
+	}
+	pt, err := common.NewPartialTransaction(tx, raw)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(pt.Marshal()))
+	return nil
+}
+
+func signPartialTransactionCmd(c *cli.Context) error {
+	pt, err := decodePartialTransaction(c.String("partial"))
+	if err != nil {
+		return err
+	}
+	accounts, err := parseSignerKeys(c.StringSlice("key"))
+	if err != nil {
+		return err
+	}
+	signed, err := pt.Sign(accounts)
+	if err != nil {
+		return err
+	}
+	if signed == 0 {
+		return fmt.Errorf("no input key matches the signer keys")
+	}
+	fmt.Println(hex.EncodeToString(pt.Marshal()))
+	return nil
+}
+
+func combinePartialTransactionsCmd(c *cli.Context) error {
+	partials := c.StringSlice("partial")
+	if len(partials) == 0 {
+		return fmt.Errorf("no partial transaction to combine")
+	}
+	pt, err := decodePartialTransaction(partials[0])
+	if err != nil {
+		return err
+	}
+	for _, p := range partials[1:] {
+		other, err := decodePartialTransaction(p)
+		if err != nil {
+			return err
+		}
+		err = pt.Combine(other)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println(hex.EncodeToString(pt.Marshal()))
+	return nil
+}
+
+func finalizePartialTransactionCmd(c *cli.Context) error {
+	pt, err := decodePartialTransaction(c.String("partial"))
+	if err != nil {
+		return err
+	}
+	ver, err := pt.Finalize()
 	if err != nil {
 		return err
 	}
+	fmt.Println(hex.EncodeToString(ver.Marshal()))
+	return nil
+}
+
+func decodePartialTransaction(s string) (*common.PartialTransaction, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return common.UnmarshalPartialTransaction(data)
+}
+
+func buildRawTransaction(raw signerInput, seedHex string, stamp int) (*common.Transaction, error) {
+	seed, err := hex.DecodeString(seedHex)
+	if err != nil {
+		return nil, err
+	}
 	if len(seed) != 64 {
 		seed = make([]byte, 64)
 		_, err := rand.Read(seed)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -236,25 +458,28 @@ func signTransactionCmd(c *cli.Context) error {
 
 	extra, err := hex.DecodeString(raw.Extra)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tx.Extra = extra
+	err = tx.Stamp(stamp)
+	return tx, err
+}
 
-	keys := c.StringSlice("key")
+func parseSignerKeys(keys []string) ([]common.Address, error) {
 	var accounts []common.Address
 	for _, s := range keys {
 		if len(s) != crypto.KeySize*4 {
-			return fmt.Errorf("invalid key length %d", len(s))
+			return nil, fmt.Errorf("invalid key length %d", len(s))
 		}
 
 		view, err := crypto.PrivateKeyFromString(s[:crypto.KeySize*2])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		spend, err := crypto.PrivateKeyFromString(s[crypto.KeySize*2:])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var account common.Address
@@ -262,16 +487,7 @@ func signTransactionCmd(c *cli.Context) error {
 		account.PrivateSpendKey = spend
 		accounts = append(accounts, account)
 	}
-
-	signed := tx.AsLatestVersion()
-	for i := range signed.Inputs {
-		err := signed.SignInput(raw, i, accounts)
-		if err != nil {
-			return err
-		}
-	}
-	fmt.Println(hex.EncodeToString(signed.Marshal()))
-	return nil
+	return accounts, nil
 }
 
 func sendTransactionCmd(c *cli.Context) error {
@@ -284,6 +500,16 @@ func sendTransactionCmd(c *cli.Context) error {
 	return err
 }
 
+func validateTransactionCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "validaterawtransaction", []interface{}{
+		c.String("raw"),
+	}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
 func pledgeNodeCmd(c *cli.Context) error {
 	seed := make([]byte, 64)
 	_, err := rand.Read(seed)
@@ -558,6 +784,29 @@ func getUTXOCmd(c *cli.Context) error {
 	return err
 }
 
+func getConflictsCmd(c *cli.Context) error {
+	params := []interface{}{c.String("hash")}
+	if c.IsSet("index") {
+		params = append(params, c.Uint64("index"))
+	}
+	data, err := callRPC(c.String("node"), "getconflicts", params, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
+func listConflictsCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "listconflicts", []interface{}{
+		c.Uint64("since"),
+		c.Uint64("count"),
+	}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
 func listMintDistributionsCmd(c *cli.Context) error {
 	data, err := callRPC(c.String("node"), "listmintdistributions", []interface{}{
 		c.Uint64("since"),
@@ -578,6 +827,30 @@ func listAllNodesCmd(c *cli.Context) error {
 	return err
 }
 
+func listConsensusHistoryCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "listconsensushistory", []interface{}{}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
+func listDomainsCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "listdomains", []interface{}{}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
+func listDomainCustodiesCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "listdomaincustodies", []interface{}{}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
 func getConsensusKeysCmd(c *cli.Context) error {
 	data, err := callRPC(c.String("node"), "getconsensuskeys", []interface{}{c.Uint64("timestamp")}, c.Bool("time"))
 	if err == nil {
@@ -602,7 +875,35 @@ func dumpGraphHeadCmd(c *cli.Context) error {
 	return err
 }
 
+func getChainHealthCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "getchainhealth", []interface{}{}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
+func controlChainCmd(c *cli.Context) error {
+	data, err := callRPC(c.String("node"), "controlchain", []interface{}{
+		c.String("id"),
+		c.String("action"),
+	}, c.Bool("time"))
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	return err
+}
+
 func setupTestNetCmd(c *cli.Context) error {
+	network := c.String("network")
+	if network == "" {
+		network = common.TestNetworkId
+	}
+	err := common.SetNetworkId(network)
+	if err != nil {
+		return err
+	}
+
 	var signers, payees []common.Address
 
 	randomPubAccount := func() common.Address {
@@ -672,8 +973,9 @@ memory-cache-size = 128
 cache-ttl = 3600
 ring-cache-size = 4096
 ring-final-size = 16384
+network = "%s"
 [network]
-listener = "%s"`, a.PrivateSpendKey.String(), nodes[i]["host"]))
+listener = "%s"`, a.PrivateSpendKey.String(), network, nodes[i]["host"]))
 
 		err = ioutil.WriteFile(dir+"/config.toml", configData, 0644)
 		if err != nil {
@@ -751,6 +1053,7 @@ type signerInput struct {
 		Deposit *common.DepositData `json:"deposit,omitempty"`
 		Keys    []crypto.Key        `json:"keys"`
 		Mask    crypto.Key          `json:"mask"`
+		Script  common.Script       `json:"script,omitempty"`
 	} `json:"inputs"`
 	Outputs []struct {
 		Type     uint8            `json:"type"`
@@ -772,6 +1075,7 @@ func (raw signerInput) ReadUTXO(hash crypto.Hash, index int) (*common.UTXOWithLo
 		if in.Hash == hash && in.Index == index && len(in.Keys) > 0 {
 			utxo.Keys = in.Keys
 			utxo.Mask = in.Mask
+			utxo.Script = in.Script
 			return utxo, nil
 		}
 	}
@@ -790,6 +1094,7 @@ func (raw signerInput) ReadUTXO(hash crypto.Hash, index int) (*common.UTXOWithLo
 	}
 	utxo.Keys = out.Keys
 	utxo.Mask = out.Mask
+	utxo.Script = out.Script
 	return utxo, nil
 }
 
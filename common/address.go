@@ -3,6 +3,7 @@ package common
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -10,7 +11,51 @@ import (
 	"github.com/btcsuite/btcutil/base58"
 )
 
-const MainNetworkId = "XIN"
+const (
+	MainNetworkId = "XIN"
+	TestNetworkId = "XTN"
+)
+
+// The address prefix of the active network, all addresses are encoded with
+// it and only addresses with it are accepted, so an address of one network
+// can never be mistaken for another network. It should be chosen only once
+// on startup, before any address is parsed.
+var activeNetworkId = MainNetworkId
+
+func NetworkId() string {
+	return activeNetworkId
+}
+
+func SetNetworkId(id string) error {
+	err := validateNetworkId(id)
+	if err != nil {
+		return err
+	}
+	activeNetworkId = id
+	return nil
+}
+
+// AddressNetworkId returns the network prefix of the address string s, it
+// recognizes all valid prefixes without verifying the address keys.
+func AddressNetworkId(s string) (string, error) {
+	if len(s) < len(MainNetworkId) {
+		return "", errors.New("invalid address network")
+	}
+	id := s[:len(MainNetworkId)]
+	return id, validateNetworkId(id)
+}
+
+func validateNetworkId(id string) error {
+	if len(id) != len(MainNetworkId) {
+		return fmt.Errorf("invalid network id %s", id)
+	}
+	for _, c := range id {
+		if c < 'A' || c > 'Z' {
+			return fmt.Errorf("invalid network id %s", id)
+		}
+	}
+	return nil
+}
 
 type Address struct {
 	PrivateSpendKey crypto.PrivateKey
@@ -35,15 +80,19 @@ func NewAddressFromSeed(seed []byte) Address {
 }
 
 func NewAddressFromString(s string) (Address, error) {
+	return NewAddressFromNetworkString(activeNetworkId, s)
+}
+
+func NewAddressFromNetworkString(network, s string) (Address, error) {
 	var a Address
-	if !strings.HasPrefix(s, MainNetworkId) {
+	if !strings.HasPrefix(s, network) {
 		return a, errors.New("invalid address network")
 	}
-	data := base58.Decode(s[len(MainNetworkId):])
+	data := base58.Decode(s[len(network):])
 	if len(data) != crypto.KeySize*2+4 {
 		return a, errors.New("invalid address format")
 	}
-	checksum := crypto.NewHash(append([]byte(MainNetworkId), data[:crypto.KeySize*2]...))
+	checksum := crypto.NewHash(append([]byte(network), data[:crypto.KeySize*2]...))
 	if !bytes.Equal(checksum[:4], data[crypto.KeySize*2:]) {
 		return a, errors.New("invalid address checksum")
 	}
@@ -64,11 +113,15 @@ func NewAddressFromString(s string) (Address, error) {
 }
 
 func (a Address) String() string {
+	return a.NetworkString(activeNetworkId)
+}
+
+func (a Address) NetworkString(network string) string {
 	keyBts := a.PublicKeyBytes()
-	data := append([]byte(MainNetworkId), keyBts...)
+	data := append([]byte(network), keyBts...)
 	checksum := crypto.NewHash(data)
 	data = append(keyBts, checksum[:4]...)
-	return MainNetworkId + base58.Encode(data)
+	return network + base58.Encode(data)
 }
 
 func (a Address) PublicKeyBytes() []byte {
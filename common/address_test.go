@@ -56,3 +56,39 @@ func TestAddress(t *testing.T) {
 	z := NewAddressFromSeed(make([]byte, 64))
 	assert.Equal("XIN8b7CsqwqaBP7576hvWzo7uDgbU9TB5KGU4jdgYpQTi2qrQGpBtrW49ENQiLGNrYU45e2wwKRD7dEUPtuaJYps2jbR4dH", z.String())
 }
+
+func TestAddressNetwork(t *testing.T) {
+	assert := assert.New(t)
+	defer SetNetworkId(MainNetworkId)
+
+	a := NewAddressFromSeed(make([]byte, 64))
+	main := a.String()
+	test := a.NetworkString(TestNetworkId)
+	assert.Equal("XIN", main[:3])
+	assert.Equal("XTN", test[:3])
+	id, err := AddressNetworkId(test)
+	assert.Nil(err)
+	assert.Equal(TestNetworkId, id)
+	_, err = AddressNetworkId("xin")
+	assert.NotNil(err)
+
+	_, err = NewAddressFromString(test)
+	assert.NotNil(err)
+	_, err = NewAddressFromString("XTN" + main[3:])
+	assert.NotNil(err)
+
+	assert.NotNil(SetNetworkId("XT"))
+	assert.NotNil(SetNetworkId("xtn"))
+	assert.Equal(MainNetworkId, NetworkId())
+	assert.Nil(SetNetworkId(TestNetworkId))
+	assert.Equal(TestNetworkId, NetworkId())
+	assert.Equal(test, a.String())
+	b, err := NewAddressFromString(test)
+	assert.Nil(err)
+	assert.Equal(a.Hash(), b.Hash())
+	_, err = NewAddressFromString(main)
+	assert.NotNil(err)
+	b, err = NewAddressFromNetworkString(MainNetworkId, main)
+	assert.Nil(err)
+	assert.Equal(a.Hash(), b.Hash())
+}
@@ -1,11 +1,8 @@
 package common
 
 import (
-	"fmt"
-
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/domains/bitcoin"
-	"github.com/MixinNetwork/mixin/domains/ethereum"
+	"github.com/MixinNetwork/mixin/domains"
 )
 
 var (
@@ -22,33 +19,25 @@ func init() {
 }
 
 func (a *Asset) Verify() error {
-	switch a.ChainId {
-	case ethereum.EthereumChainId:
-		return ethereum.VerifyAssetKey(a.AssetKey)
-	case bitcoin.BitcoinChainId:
-		return bitcoin.VerifyAssetKey(a.AssetKey)
-	default:
-		return fmt.Errorf("invalid chain id %s", a.ChainId)
+	d := domains.Get(a.ChainId)
+	if d == nil {
+		return NewValidationError(ErrorCodeAsset, "invalid chain id %s", a.ChainId)
 	}
+	return d.VerifyAssetKey(a.AssetKey)
 }
 
 func (a *Asset) AssetId() crypto.Hash {
-	switch a.ChainId {
-	case ethereum.EthereumChainId:
-		return ethereum.GenerateAssetId(a.AssetKey)
-	case bitcoin.BitcoinChainId:
-		return bitcoin.GenerateAssetId(a.AssetKey)
-	default:
+	d := domains.Get(a.ChainId)
+	if d == nil {
 		return crypto.Hash{}
 	}
+	return d.GenerateAssetId(a.AssetKey)
 }
 
 func (a *Asset) FeeAssetId() crypto.Hash {
-	switch a.ChainId {
-	case ethereum.EthereumChainId:
-		return ethereum.EthereumChainId
-	case bitcoin.BitcoinChainId:
-		return bitcoin.BitcoinChainId
+	d := domains.Get(a.ChainId)
+	if d == nil {
+		return crypto.Hash{}
 	}
-	return crypto.Hash{}
+	return d.FeeAssetId()
 }
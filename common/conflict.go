@@ -0,0 +1,16 @@
+package common
+
+import (
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+// UTXOConflict records a transaction trying to lock a UTXO already locked by
+// another one, Pruned means the locker was dropped in favor of the spender.
+type UTXOConflict struct {
+	Hash      crypto.Hash
+	Index     int
+	Locker    crypto.Hash
+	Spender   crypto.Hash
+	Pruned    bool
+	Timestamp uint64
+}
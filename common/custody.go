@@ -0,0 +1,159 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+type DomainCustody struct {
+	Domain Address
+	Asset  crypto.Hash
+	Amount Integer
+}
+
+func (tx *SignedTransaction) validateDomainCustody(store DataStore, inputs map[string]*UTXO, msg []byte) error {
+	if tx.Asset == XINAssetId {
+		return NewValidationError(ErrorCodeAsset, "invalid custody asset %s", tx.Asset.String())
+	}
+	for _, in := range inputs {
+		if in.Type != OutputTypeScript && in.Type != OutputTypeDomainAssetRelease {
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
+		}
+	}
+	if len(tx.Outputs) > 2 {
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for custody transaction", len(tx.Outputs))
+	}
+	if len(tx.Outputs) == 2 && tx.Outputs[1].Type != OutputTypeScript {
+		return NewValidationError(ErrorCodeOutput, "invalid change type %d for custody transaction", tx.Outputs[1].Type)
+	}
+	if tx.Outputs[0].Type != OutputTypeDomainAssetCustody {
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for custody transaction", tx.Outputs[0].Type)
+	}
+
+	domain, err := tx.custodyDomain(store)
+	if err != nil {
+		return err
+	}
+	err = tx.verifyCustodyAsset()
+	if err != nil {
+		return err
+	}
+	return validateDomainSignature(domain, msg, tx.Signatures[len(tx.Inputs)])
+}
+
+func (tx *SignedTransaction) validateDomainRelease(store DataStore, inputs map[string]*UTXO, msg []byte) error {
+	if tx.Asset == XINAssetId {
+		return NewValidationError(ErrorCodeAsset, "invalid custody asset %s", tx.Asset.String())
+	}
+	for _, in := range inputs {
+		if in.Type != OutputTypeDomainAssetCustody {
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
+		}
+	}
+	for _, out := range tx.Outputs {
+		if out.Type != OutputTypeDomainAssetRelease {
+			return NewValidationError(ErrorCodeOutput, "invalid output type %d for release transaction", out.Type)
+		}
+	}
+
+	domain, err := tx.custodyDomain(store)
+	if err != nil {
+		return err
+	}
+	err = tx.verifyCustodyAsset()
+	if err != nil {
+		return err
+	}
+	for _, in := range tx.Inputs {
+		custody, _, err := store.ReadTransaction(in.Hash)
+		if err != nil {
+			return err
+		}
+		if custody == nil {
+			return NewValidationError(ErrorCodeInputNotFound, "custody transaction not found %s", in.Hash)
+		}
+		if bytes.Compare(custody.Extra, tx.Extra) != 0 {
+			return NewValidationError(ErrorCodeInput, "invalid custody and release domain %s %s", hex.EncodeToString(custody.Extra), hex.EncodeToString(tx.Extra))
+		}
+	}
+	err = validateDomainSignature(domain, msg, tx.Signatures[0])
+	if err != nil {
+		return err
+	}
+
+	amount := NewInteger(0)
+	for _, out := range tx.Outputs {
+		amount = amount.Add(out.Amount)
+	}
+	balance, err := store.ReadDomainCustody(domain.Account.PublicSpendKey.Key(), tx.Asset)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(amount) < 0 {
+		return NewValidationError(ErrorCodeAmount, "invalid custody balance %s %s", balance, amount)
+	}
+	return nil
+}
+
+// DomainCustodyExtra builds the extra of the custody and release transactions,
+// which is the domain public spend key, followed by the chain id and the asset
+// key of the custody asset.
+func DomainCustodyExtra(domain crypto.Key, asset *Asset) []byte {
+	extra := append(domain[:], asset.ChainId[:]...)
+	return append(extra, []byte(asset.AssetKey)...)
+}
+
+func (tx *SignedTransaction) verifyCustodyAsset() error {
+	offset := crypto.KeySize + len(crypto.Hash{})
+	asset := &Asset{AssetKey: string(tx.Extra[offset:])}
+	copy(asset.ChainId[:], tx.Extra[crypto.KeySize:offset])
+	if err := asset.Verify(); err != nil {
+		return NewValidationError(ErrorCodeAsset, "invalid custody asset data %s", err.Error())
+	}
+	if id := asset.AssetId(); id != tx.Asset {
+		return NewValidationError(ErrorCodeAsset, "invalid custody asset %s %s", tx.Asset, id)
+	}
+	return nil
+}
+
+func (tx *SignedTransaction) custodyDomain(store DataStore) (*Domain, error) {
+	if len(tx.Extra) <= crypto.KeySize+len(crypto.Hash{}) {
+		return nil, NewValidationError(ErrorCodeFormat, "invalid extra length %d for custody transaction", len(tx.Extra))
+	}
+	var domainSpend crypto.Key
+	copy(domainSpend[:], tx.Extra)
+	domains, err := store.ReadDomains()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if d.Account.PublicSpendKey.Key() == domainSpend {
+			return &d, nil
+		}
+	}
+	return nil, NewValidationError(ErrorCodeState, "invalid custody domain %s", domainSpend)
+}
+
+func validateDomainSignature(domain *Domain, msg []byte, sigs []crypto.Signature) error {
+	for _, sig := range sigs {
+		if domain.Account.PublicSpendKey.Verify(msg, &sig) {
+			return nil
+		}
+	}
+	return NewValidationError(ErrorCodeSignature, "invalid domain signature for custody transaction")
+}
+
+func (signed *SignedTransaction) SignDomain(key crypto.PrivateKey) error {
+	var index int
+	switch signed.TransactionType() {
+	case TransactionTypeDomainCustody:
+		index = len(signed.Inputs)
+	case TransactionTypeDomainRelease:
+		index = 0
+	default:
+		return NewValidationError(ErrorCodeType, "invalid transaction type %d for domain signature", signed.TransactionType())
+	}
+	return signed.appendSignature(key, index)
+}
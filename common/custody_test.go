@@ -0,0 +1,193 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	domains.Register(custodyDomainImpl{})
+}
+
+func TestDomainCustody(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 2; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	domain := NewAddressFromSeed(seed)
+	domainSpend := domain.PublicSpendKey.Key()
+	custody := &Asset{ChainId: custodyDomainImpl{}.ChainId(), AssetKey: "custody"}
+	asset := custody.AssetId()
+	store := custodyStoreImpl{
+		domainStoreImpl: domainStoreImpl{storeImpl: storeImpl{seed: seed, accounts: accounts}},
+		asset:           asset,
+	}
+
+	tx := NewTransaction(asset)
+	tx.AddInput(crypto.Hash{}, 0)
+	tx.AddInput(crypto.Hash{}, 1)
+	tx.AddOutputWithType(OutputTypeDomainAssetCustody, nil, Script{}, NewInteger(15000), []byte{})
+	tx.AddScriptOutput(accounts[:1], NewThresholdScript(1), NewInteger(5000), seed)
+	tx.Extra = DomainCustodyExtra(domainSpend, custody)
+	ver := tx.AsLatestVersion()
+	assert.Equal(uint8(TransactionTypeDomainCustody), ver.TransactionType())
+	for i := range ver.Inputs {
+		err := ver.SignInput(store, i, accounts[0:i+1])
+		assert.Nil(err)
+	}
+	err := ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid tx signature number")
+
+	err = ver.SignDomain(domain.PrivateSpendKey)
+	assert.Nil(err)
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid custody domain")
+
+	store.domains = []Domain{{Account: domain, State: DomainStateAccepted}}
+	err = ver.Validate(store)
+	assert.Nil(err)
+
+	ver.Extra = DomainCustodyExtra(domainSpend, &Asset{ChainId: custody.ChainId, AssetKey: "other"})
+	err = ver.verifyCustodyAsset()
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAsset, ValidationErrorCode(err))
+	assert.Contains(err.Error(), "invalid custody asset")
+	ver.Extra = DomainCustodyExtra(domainSpend, &Asset{ChainId: crypto.NewHash([]byte("chain")), AssetKey: "custody"})
+	err = ver.verifyCustodyAsset()
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAsset, ValidationErrorCode(err))
+	assert.Contains(err.Error(), "invalid custody asset data")
+	ver.Extra = DomainCustodyExtra(domainSpend, custody)
+	assert.Nil(ver.Validate(store))
+
+	utxos := ver.UnspentOutputs()
+	assert.Len(utxos, 2)
+	assert.Equal(uint8(OutputTypeDomainAssetCustody), utxos[0].Type)
+	assert.Equal("15000.00000000", utxos[0].Amount.String())
+
+	ver.Signatures[len(ver.Inputs)][0] = ver.Signatures[0][0]
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid domain signature")
+
+	store.custody = ver
+	release := NewTransaction(asset)
+	release.AddInput(ver.PayloadHash(), 0)
+	release.AddOutputWithType(OutputTypeDomainAssetRelease, accounts[1:], NewThresholdScript(1), NewInteger(15000), seed)
+	release.Extra = DomainCustodyExtra(domainSpend, custody)
+	rver := release.AsLatestVersion()
+	assert.Equal(uint8(TransactionTypeDomainRelease), rver.TransactionType())
+	err = rver.SignDomain(accounts[0].PrivateSpendKey)
+	assert.Nil(err)
+	err = rver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid domain signature")
+
+	rver.Signatures = nil
+	err = rver.SignDomain(domain.PrivateSpendKey)
+	assert.Nil(err)
+	err = rver.Validate(store)
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAmount, ValidationErrorCode(err))
+	assert.Contains(err.Error(), "invalid custody balance 0.00000000 15000.00000000")
+
+	store.balance = NewInteger(15000)
+	err = rver.Validate(store)
+	assert.Nil(err)
+
+	utxos = rver.UnspentOutputs()
+	assert.Len(utxos, 1)
+	assert.Equal(uint8(OutputTypeDomainAssetRelease), utxos[0].Type)
+	assert.Len(utxos[0].Keys, 1)
+}
+
+type custodyStoreImpl struct {
+	domainStoreImpl
+	asset   crypto.Hash
+	balance Integer
+	custody *VersionedTransaction
+}
+
+func (store custodyStoreImpl) ReadDomainCustody(domain crypto.Key, asset crypto.Hash) (Integer, error) {
+	if store.balance.Sign() == 0 {
+		return NewInteger(0), nil
+	}
+	return store.balance, nil
+}
+
+func (store custodyStoreImpl) ReadUTXO(hash crypto.Hash, index int) (*UTXOWithLock, error) {
+	if store.custody != nil && hash == store.custody.PayloadHash() {
+		for _, utxo := range store.custody.UnspentOutputs() {
+			if utxo.Index == index {
+				return &UTXOWithLock{UTXO: *utxo}, nil
+			}
+		}
+		return nil, nil
+	}
+	utxo, err := store.domainStoreImpl.ReadUTXO(hash, index)
+	if err != nil {
+		return nil, err
+	}
+	utxo.Asset = store.asset
+	return utxo, nil
+}
+
+func (store custodyStoreImpl) ReadTransaction(hash crypto.Hash) (*VersionedTransaction, string, error) {
+	if store.custody != nil && hash == store.custody.PayloadHash() {
+		return store.custody, "", nil
+	}
+	return nil, "", nil
+}
+
+type custodyDomainImpl struct{}
+
+func (d custodyDomainImpl) ChainId() crypto.Hash {
+	return crypto.NewHash([]byte("custody-test-chain"))
+}
+
+func (d custodyDomainImpl) VerifyAssetKey(assetKey string) error {
+	if assetKey == "" {
+		return fmt.Errorf("invalid asset key %s", assetKey)
+	}
+	return nil
+}
+
+func (d custodyDomainImpl) VerifyAddress(address string, fork bool) error {
+	return nil
+}
+
+func (d custodyDomainImpl) VerifyTag(address, tag string, fork bool) error {
+	return nil
+}
+
+func (d custodyDomainImpl) VerifyTransactionHash(hash string) error {
+	return nil
+}
+
+func (d custodyDomainImpl) VerifyAmount(assetKey string, amount string) error {
+	return nil
+}
+
+func (d custodyDomainImpl) GenerateAssetId(assetKey string) crypto.Hash {
+	chain := d.ChainId()
+	return crypto.NewHash(append(chain[:], []byte(assetKey)...))
+}
+
+func (d custodyDomainImpl) FeeAssetId() crypto.Hash {
+	return d.GenerateAssetId("fee")
+}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/domains/bitcoin"
-	"github.com/MixinNetwork/mixin/domains/ethereum"
+	"github.com/MixinNetwork/mixin/domains"
 )
 
 type DepositData struct {
@@ -38,50 +37,55 @@ func (tx *SignedTransaction) DepositData() *DepositData {
 func (tx *SignedTransaction) verifyDepositFormat() error {
 	deposit := tx.Inputs[0].Deposit
 	if err := deposit.Asset().Verify(); err != nil {
-		return fmt.Errorf("invalid asset data %s", err.Error())
+		return NewValidationError(ErrorCodeAsset, "invalid asset data %s", err.Error())
 	}
 	if id := deposit.Asset().AssetId(); id != tx.Asset {
-		return fmt.Errorf("invalid asset %s %s", tx.Asset, id)
+		return NewValidationError(ErrorCodeAsset, "invalid asset %s %s", tx.Asset, id)
 	}
 	if deposit.Amount.Sign() <= 0 {
-		return fmt.Errorf("invalid amount %s", deposit.Amount.String())
+		return NewValidationError(ErrorCodeAmount, "invalid amount %s", deposit.Amount.String())
 	}
 
-	switch deposit.Asset().ChainId {
-	case ethereum.EthereumChainId:
-		return ethereum.VerifyTransactionHash(deposit.TransactionHash)
-	case bitcoin.BitcoinChainId:
-		return bitcoin.VerifyTransactionHash(deposit.TransactionHash)
+	d := domains.Get(deposit.Chain)
+	if d == nil {
+		return NewValidationError(ErrorCodeAsset, "invalid chain id %s", deposit.Chain)
+	}
+	if err := d.VerifyTransactionHash(deposit.TransactionHash); err != nil {
+		return NewValidationError(ErrorCodeFormat, "invalid deposit transaction hash %s", err.Error())
 	}
 	return nil
 }
 
 func (tx *SignedTransaction) validateDeposit(store DataStore, msg []byte, payloadHash crypto.Hash) error {
 	if len(tx.Inputs) != 1 {
-		return fmt.Errorf("invalid inputs count %d for deposit", len(tx.Inputs))
+		return NewValidationError(ErrorCodeFormat, "invalid inputs count %d for deposit", len(tx.Inputs))
 	}
 	if len(tx.Outputs) != 1 {
-		return fmt.Errorf("invalid outputs count %d for deposit", len(tx.Outputs))
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for deposit", len(tx.Outputs))
 	}
 	if tx.Outputs[0].Type != OutputTypeScript {
-		return fmt.Errorf("invalid deposit output type %d", tx.Outputs[0].Type)
+		return NewValidationError(ErrorCodeOutput, "invalid deposit output type %d", tx.Outputs[0].Type)
 	}
 	if len(tx.Signatures) != 1 || len(tx.Signatures[0]) != 1 {
-		return fmt.Errorf("invalid signatures count %d for deposit", len(tx.Signatures))
+		return NewValidationError(ErrorCodeSignature, "invalid signatures count %d for deposit", len(tx.Signatures))
 	}
 	err := tx.verifyDepositFormat()
 	if err != nil {
 		return err
 	}
 
+	domains, err := store.ReadDomains()
+	if err != nil {
+		return err
+	}
 	sig, valid := tx.Signatures[0][0], false
-	for _, d := range store.ReadDomains() {
+	for _, d := range domains {
 		if d.Account.PublicSpendKey.Verify(msg, &sig) {
 			valid = true
 		}
 	}
 	if !valid {
-		return fmt.Errorf("invalid domain signature for deposit")
+		return NewValidationError(ErrorCodeSignature, "invalid domain signature for deposit")
 	}
 
 	return store.CheckDepositInput(tx.Inputs[0].Deposit, payloadHash)
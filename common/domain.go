@@ -1,5 +1,161 @@
 package common
 
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const (
+	DomainStateAccepted = "ACCEPTED"
+	DomainStateRemoved  = "REMOVED"
+)
+
 type Domain struct {
-	Account Address
+	Account     Address
+	State       string
+	Transaction crypto.Hash
+	Timestamp   uint64
+}
+
+func (tx *SignedTransaction) validateDomainAccept(store DataStore, inputs map[string]*UTXO) error {
+	if tx.Asset != XINAssetId {
+		return NewValidationError(ErrorCodeAsset, "invalid domain asset %s", tx.Asset.String())
+	}
+	for _, in := range inputs {
+		if in.Type != OutputTypeScript {
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
+		}
+	}
+	if len(tx.Outputs) > 2 {
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for domain accept transaction", len(tx.Outputs))
+	}
+	if len(tx.Outputs) == 2 && tx.Outputs[1].Type != OutputTypeScript {
+		return NewValidationError(ErrorCodeOutput, "invalid change type %d for domain accept transaction", tx.Outputs[1].Type)
+	}
+	accept := tx.Outputs[0]
+	if accept.Type != OutputTypeDomainAccept {
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for domain accept transaction", accept.Type)
+	}
+	if accept.Amount.Cmp(NewIntegerFromString(config.DomainPledgeAmount)) != 0 {
+		return NewValidationError(ErrorCodeAmount, "invalid output amount %s for domain accept transaction", accept.Amount)
+	}
+	if len(tx.Extra) != crypto.KeySize {
+		return NewValidationError(ErrorCodeFormat, "invalid extra length %d for domain accept transaction", len(tx.Extra))
+	}
+	var domainSpend crypto.Key
+	copy(domainSpend[:], tx.Extra)
+	if _, err := domainSpend.AsPublicKey(); err != nil {
+		return NewValidationError(ErrorCodeState, "invalid domain key %s %s", domainSpend, err.Error())
+	}
+	domains, err := store.ReadDomains()
+	if err != nil {
+		return err
+	}
+	for _, d := range domains {
+		if d.Account.PublicSpendKey.Key() == domainSpend {
+			return NewValidationError(ErrorCodeState, "invalid domain key %s already accepted", domainSpend)
+		}
+	}
+	return nil
+}
+
+func (tx *SignedTransaction) validateDomainRemove(store DataStore, inputs map[string]*UTXO) error {
+	if tx.Asset != XINAssetId {
+		return NewValidationError(ErrorCodeAsset, "invalid domain asset %s", tx.Asset.String())
+	}
+	if len(tx.Inputs) != 1 {
+		return NewValidationError(ErrorCodeFormat, "invalid inputs count %d for domain remove transaction", len(tx.Inputs))
+	}
+	if len(tx.Outputs) != 1 {
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for domain remove transaction", len(tx.Outputs))
+	}
+	for _, in := range inputs {
+		if in.Type != OutputTypeDomainAccept {
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
+		}
+	}
+	remove := tx.Outputs[0]
+	if remove.Type != OutputTypeDomainRemove {
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for domain remove transaction", remove.Type)
+	}
+	if len(remove.Keys) != 1 {
+		return NewValidationError(ErrorCodeOutput, "invalid output keys count %d for domain remove transaction", len(remove.Keys))
+	}
+
+	accept, _, err := store.ReadTransaction(tx.Inputs[0].Hash)
+	if err != nil {
+		return err
+	}
+	if accept == nil {
+		return NewValidationError(ErrorCodeInputNotFound, "domain accept transaction not found %s", tx.Inputs[0].Hash)
+	}
+	if accept.PayloadHash() != tx.Inputs[0].Hash {
+		return NewValidationError(ErrorCodeInput, "accept transaction malformed %s %s", tx.Inputs[0].Hash, accept.PayloadHash())
+	}
+	if tx.Inputs[0].Index != 0 || len(accept.Outputs) < 1 || accept.Outputs[0].Type != OutputTypeDomainAccept {
+		return NewValidationError(ErrorCodeInput, "invalid domain accept utxo %s:%d", tx.Inputs[0].Hash, tx.Inputs[0].Index)
+	}
+	if bytes.Compare(accept.Extra, tx.Extra) != 0 {
+		return NewValidationError(ErrorCodeInput, "invalid accept and remove key %s %s", hex.EncodeToString(accept.Extra), hex.EncodeToString(tx.Extra))
+	}
+
+	var domainSpend crypto.Key
+	copy(domainSpend[:], tx.Extra)
+	domains, err := store.ReadDomains()
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, d := range domains {
+		found = found || d.Account.PublicSpendKey.Key() == domainSpend
+	}
+	if !found {
+		return NewValidationError(ErrorCodeState, "invalid domain key %s not accepted", domainSpend)
+	}
+	return nil
+}
+
+// ConsensusSignatures returns the consensus nodes signatures of a domain accept
+// or remove transaction, they are verified by the kernel against the consensus
+// keys and threshold at the snapshot timestamp.
+func (signed *SignedTransaction) ConsensusSignatures() []crypto.Signature {
+	index := signed.consensusSignatureIndex()
+	if index < 0 || index >= len(signed.Signatures) {
+		return nil
+	}
+	return signed.Signatures[index]
+}
+
+func (signed *SignedTransaction) consensusSignatureIndex() int {
+	switch signed.TransactionType() {
+	case TransactionTypeDomainAccept:
+		return len(signed.Inputs)
+	case TransactionTypeDomainRemove:
+		return 0
+	}
+	return -1
+}
+
+func (signed *SignedTransaction) SignConsensus(key crypto.PrivateKey) error {
+	index := signed.consensusSignatureIndex()
+	if index < 0 {
+		return NewValidationError(ErrorCodeType, "invalid transaction type %d for consensus signature", signed.TransactionType())
+	}
+	return signed.appendSignature(key, index)
+}
+
+func (signed *SignedTransaction) appendSignature(key crypto.PrivateKey, index int) error {
+	msg := MsgpackMarshalPanic(signed.Transaction)
+	sig, err := key.Sign(msg)
+	if err != nil {
+		return err
+	}
+	for len(signed.Signatures) <= index {
+		signed.Signatures = append(signed.Signatures, []crypto.Signature{})
+	}
+	signed.Signatures[index] = append(signed.Signatures[index], *sig)
+	return nil
 }
@@ -0,0 +1,151 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainAccept(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 5; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	signers := make([]Address, 0)
+	for i := 0; i < 4; i++ {
+		seed := make([]byte, 64)
+		rand.Read(seed)
+		signers = append(signers, NewAddressFromSeed(seed))
+	}
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	domain := NewAddressFromSeed(seed)
+	store := domainStoreImpl{storeImpl: storeImpl{seed: seed, accounts: accounts}}
+
+	tx := NewTransaction(XINAssetId)
+	for i := range accounts {
+		tx.AddInput(crypto.Hash{}, i)
+	}
+	tx.AddOutputWithType(OutputTypeDomainAccept, nil, Script{}, NewIntegerFromString(config.DomainPledgeAmount), []byte{})
+	domainSpend := domain.PublicSpendKey.Key()
+	tx.Extra = domainSpend[:]
+	ver := tx.AsLatestVersion()
+	for i := range ver.Inputs {
+		err := ver.SignInput(store, i, accounts[0:i+1])
+		assert.Nil(err)
+	}
+	err := ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid tx signature number")
+
+	for _, s := range signers[:3] {
+		err = ver.SignConsensus(s.PrivateSpendKey)
+		assert.Nil(err)
+	}
+	assert.Len(ver.ConsensusSignatures(), 3)
+	err = ver.Validate(store)
+	assert.Nil(err)
+
+	store.domains = []Domain{{Account: domain, State: DomainStateAccepted}}
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "already accepted")
+}
+
+func TestDomainRemove(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 5; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	signer := NewAddressFromSeed(seed)
+	rand.Read(seed)
+	domain := NewAddressFromSeed(seed)
+	domainSpend := domain.PublicSpendKey.Key()
+	store := domainStoreImpl{storeImpl: storeImpl{seed: seed, accounts: accounts}}
+
+	accept := NewTransaction(XINAssetId)
+	accept.AddInput(crypto.Hash{}, 0)
+	accept.AddOutputWithType(OutputTypeDomainAccept, nil, Script{}, NewIntegerFromString(config.DomainPledgeAmount), []byte{})
+	accept.Extra = domainSpend[:]
+	store.accept = accept.AsLatestVersion()
+
+	tx := NewTransaction(XINAssetId)
+	tx.AddInput(store.accept.PayloadHash(), 0)
+	tx.AddOutputWithType(OutputTypeDomainRemove, []Address{accounts[0]}, Script{OperatorCmp, OperatorSum, 1}, NewIntegerFromString(config.DomainPledgeAmount), seed)
+	tx.Extra = domainSpend[:]
+	ver := tx.AsLatestVersion()
+	err := ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid tx signature number")
+
+	err = ver.SignConsensus(signer.PrivateSpendKey)
+	assert.Nil(err)
+	assert.Len(ver.ConsensusSignatures(), 1)
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeState, ValidationErrorCode(err))
+	assert.Contains(err.Error(), "not accepted")
+
+	store.domains = []Domain{{Account: domain, State: DomainStateAccepted}}
+	err = ver.Validate(store)
+	assert.Nil(err)
+
+	other := accounts[1].PublicSpendKey.Key()
+	ver.Extra = other[:]
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeInput, ValidationErrorCode(err))
+	assert.Contains(err.Error(), "invalid accept and remove key")
+
+	ver.Extra = domainSpend[:]
+	ver.Outputs[0].Type = OutputTypeScript
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "domain accept input used for invalid transaction type")
+}
+
+type domainStoreImpl struct {
+	storeImpl
+	accept  *VersionedTransaction
+	domains []Domain
+}
+
+func (store domainStoreImpl) ReadUTXO(hash crypto.Hash, index int) (*UTXOWithLock, error) {
+	if store.accept == nil || hash != store.accept.PayloadHash() {
+		return store.storeImpl.ReadUTXO(hash, index)
+	}
+	utxo := &UTXOWithLock{
+		UTXO: UTXO{
+			Input:  Input{Hash: hash, Index: index},
+			Output: *store.accept.Outputs[index],
+			Asset:  store.accept.Asset,
+		},
+	}
+	return utxo, nil
+}
+
+func (store domainStoreImpl) ReadTransaction(hash crypto.Hash) (*VersionedTransaction, string, error) {
+	if store.accept == nil || hash != store.accept.PayloadHash() {
+		return nil, "", nil
+	}
+	return store.accept, "", nil
+}
+
+func (store domainStoreImpl) ReadDomains() ([]Domain, error) {
+	return store.domains, nil
+}
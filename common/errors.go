@@ -0,0 +1,78 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies a validation failure, the numbers are part of the RPC
+// and log output so never reorder or reuse them.
+type ErrorCode int
+
+const (
+	ErrorCodeUnknown       ErrorCode = 0
+	ErrorCodeVersion       ErrorCode = 1001
+	ErrorCodeType          ErrorCode = 1002
+	ErrorCodeFormat        ErrorCode = 1003
+	ErrorCodeStamp         ErrorCode = 1004
+	ErrorCodeSignature     ErrorCode = 1005
+	ErrorCodeInputNotFound ErrorCode = 1006
+	ErrorCodeInputLocked   ErrorCode = 1007
+	ErrorCodeAsset         ErrorCode = 1008
+	ErrorCodeAmount        ErrorCode = 1009
+	ErrorCodeInput         ErrorCode = 1010
+	ErrorCodeOutput        ErrorCode = 1011
+	ErrorCodeState         ErrorCode = 1012
+	ErrorCodeSnapshot      ErrorCode = 1013
+	ErrorCodeDoubleSpend   ErrorCode = 1014
+	ErrorCodeCapacity      ErrorCode = 1015
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrorCodeUnknown:       "unknown",
+	ErrorCodeVersion:       "version",
+	ErrorCodeType:          "type",
+	ErrorCodeFormat:        "format",
+	ErrorCodeStamp:         "stamp",
+	ErrorCodeSignature:     "signature",
+	ErrorCodeInputNotFound: "input_not_found",
+	ErrorCodeInputLocked:   "input_locked",
+	ErrorCodeAsset:         "asset",
+	ErrorCodeAmount:        "amount",
+	ErrorCodeInput:         "input",
+	ErrorCodeOutput:        "output",
+	ErrorCodeState:         "state",
+	ErrorCodeSnapshot:      "snapshot",
+	ErrorCodeDoubleSpend:   "double_spend",
+	ErrorCodeCapacity:      "capacity",
+}
+
+func (c ErrorCode) String() string {
+	if n, found := errorCodeNames[c]; found {
+		return n
+	}
+	return fmt.Sprintf("code_%d", int(c))
+}
+
+type ValidationError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func NewValidationError(code ErrorCode, format string, a ...interface{}) error {
+	return &ValidationError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrorCode returns ErrorCodeUnknown for nil and for errors not
+// raised by validation, e.g. storage failures.
+func ValidationErrorCode(err error) ErrorCode {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Code
+	}
+	return ErrorCodeUnknown
+}
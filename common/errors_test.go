@@ -0,0 +1,70 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationError(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 2; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	store := storeImpl{seed: seed, accounts: accounts}
+
+	assert.Equal(ErrorCodeUnknown, ValidationErrorCode(nil))
+	assert.Equal(ErrorCodeUnknown, ValidationErrorCode(errors.New("storage failure")))
+	err := NewValidationError(ErrorCodeInputLocked, "input locked for transaction %s", crypto.Hash{})
+	assert.Equal(ErrorCodeInputLocked, ValidationErrorCode(err))
+	assert.Equal(ErrorCodeInputLocked, ValidationErrorCode(fmt.Errorf("wrapped %w", err)))
+	assert.Equal("input_locked", ErrorCodeInputLocked.String())
+	assert.Equal("code_2000", ErrorCode(2000).String())
+
+	tx := NewTransaction(XINAssetId)
+	tx.AddInput(crypto.Hash{}, 0)
+	tx.AddScriptOutput(accounts[:1], NewThresholdScript(1), NewInteger(10000), seed)
+	ver := tx.AsLatestVersion()
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeSignature, ValidationErrorCode(err))
+
+	err = ver.SignInput(store, 0, accounts[:1])
+	assert.Nil(err)
+	assert.Nil(ver.Validate(store))
+
+	ver.Asset = crypto.NewHash([]byte("asset"))
+	err = ver.Validate(store)
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAsset, ValidationErrorCode(err))
+	asset := &Asset{ChainId: crypto.NewHash([]byte("chain")), AssetKey: "asset"}
+	err = asset.Verify()
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAsset, ValidationErrorCode(err))
+	assert.Equal(crypto.Hash{}, asset.AssetId())
+	assert.Equal(crypto.Hash{}, asset.FeeAssetId())
+
+	tx = NewTransaction(XINAssetId)
+	tx.AddDepositInput(&DepositData{
+		Chain:           asset.ChainId,
+		AssetKey:        asset.AssetKey,
+		TransactionHash: "hash",
+		Amount:          NewInteger(1),
+	})
+	tx.AddScriptOutput(accounts[:1], NewThresholdScript(1), NewInteger(1), seed)
+	err = tx.AsLatestVersion().verifyDepositFormat()
+	assert.NotNil(err)
+	assert.Equal(ErrorCodeAsset, ValidationErrorCode(err))
+}
@@ -0,0 +1,218 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const (
+	ExtendedKeyPrefix = "XINHD"
+	HardenedKeyStart  = uint32(0x80000000)
+
+	ExtendedKeyPublic  = 0
+	ExtendedKeyView    = 1
+	ExtendedKeyPrivate = 2
+
+	extendedKeySize = 1 + 1 + 4 + 32 + crypto.KeySize*2 + 4
+)
+
+// ExtendedKey derives child addresses from one master secret. Non-hardened
+// children are derived from the public keys, so a public extended key could
+// generate all child addresses without any secret, and a view extended key
+// could further derive the child private view keys to scan the outputs.
+// Hardened children require the private spend key.
+type ExtendedKey struct {
+	Address   Address
+	ChainCode crypto.Hash
+	Depth     uint8
+	Index     uint32
+}
+
+func NewExtendedKeyFromSeed(seed []byte) (*ExtendedKey, error) {
+	if len(seed) != 64 {
+		return nil, fmt.Errorf("invalid extended key seed size %d", len(seed))
+	}
+	return &ExtendedKey{
+		Address:   NewAddressFromSeed(seed),
+		ChainCode: crypto.NewHash(append([]byte(ExtendedKeyPrefix), seed...)),
+	}, nil
+}
+
+func (k *ExtendedKey) Kind() int {
+	if k.Address.PrivateSpendKey != nil {
+		return ExtendedKeyPrivate
+	}
+	if k.Address.PrivateViewKey != nil {
+		return ExtendedKeyView
+	}
+	return ExtendedKeyPublic
+}
+
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if k.Depth == 255 {
+		return nil, errors.New("invalid extended key depth")
+	}
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], index)
+
+	if index >= HardenedKeyStart {
+		if k.Kind() != ExtendedKeyPrivate {
+			return nil, fmt.Errorf("invalid hardened derivation %d without private spend key", index)
+		}
+		spend, view := k.Address.PrivateSpendKey.Key(), k.Address.PrivateViewKey.Key()
+		data := append([]byte{ExtendedKeyPrivate}, spend[:]...)
+		data = append(append(data, view[:]...), buf[:]...)
+		seed, chain := k.derive(data)
+		return &ExtendedKey{
+			Address:   NewAddressFromSeed(seed),
+			ChainCode: chain,
+			Depth:     k.Depth + 1,
+			Index:     index,
+		}, nil
+	}
+
+	data := append([]byte{ExtendedKeyPublic}, k.Address.PublicKeyBytes()...)
+	data = append(data, buf[:]...)
+	seed, chain := k.derive(data)
+	spendTweak := crypto.PrivateKeyFromSeed(seed)
+	h := crypto.NewHash(seed)
+	viewTweak := crypto.PrivateKeyFromSeed(append(h[:], chain[:]...))
+
+	child := &ExtendedKey{
+		ChainCode: chain,
+		Depth:     k.Depth + 1,
+		Index:     index,
+	}
+	child.Address.PublicSpendKey = k.Address.PublicSpendKey.AddPublic(spendTweak.Public())
+	child.Address.PublicViewKey = k.Address.PublicViewKey.AddPublic(viewTweak.Public())
+	if k.Address.PrivateViewKey != nil {
+		child.Address.PrivateViewKey = k.Address.PrivateViewKey.AddPrivate(viewTweak)
+	}
+	if k.Address.PrivateSpendKey != nil {
+		child.Address.PrivateSpendKey = k.Address.PrivateSpendKey.AddPrivate(spendTweak)
+	}
+	return child, nil
+}
+
+// DerivePath accepts paths like m/0'/1/2, where ' or h marks hardened index.
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %s", path)
+	}
+	key := k
+	for _, p := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") {
+			offset = HardenedKeyStart
+			p = p[:len(p)-1]
+		}
+		index, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid derivation path %s", path)
+		}
+		key, err = key.Child(uint32(index) + offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Neuter returns the extended key without private spend key, and also without
+// the private view key if public is true.
+func (k *ExtendedKey) Neuter(public bool) *ExtendedKey {
+	n := *k
+	n.Address.PrivateSpendKey = nil
+	if public {
+		n.Address.PrivateViewKey = nil
+	}
+	return &n
+}
+
+func (k *ExtendedKey) String() string {
+	kind := k.Kind()
+	data := make([]byte, 6)
+	data[0], data[1] = byte(kind), k.Depth
+	binary.BigEndian.PutUint32(data[2:], k.Index)
+	data = append(data, k.ChainCode[:]...)
+	var spend, view crypto.Key
+	switch kind {
+	case ExtendedKeyPrivate:
+		spend, view = k.Address.PrivateSpendKey.Key(), k.Address.PrivateViewKey.Key()
+	case ExtendedKeyView:
+		spend, view = k.Address.PublicSpendKey.Key(), k.Address.PrivateViewKey.Key()
+	default:
+		spend, view = k.Address.PublicSpendKey.Key(), k.Address.PublicViewKey.Key()
+	}
+	data = append(append(data, spend[:]...), view[:]...)
+	checksum := crypto.NewHash(append([]byte(ExtendedKeyPrefix), data...))
+	return ExtendedKeyPrefix + base58.Encode(append(data, checksum[:4]...))
+}
+
+func NewExtendedKeyFromString(s string) (*ExtendedKey, error) {
+	if !strings.HasPrefix(s, ExtendedKeyPrefix) {
+		return nil, errors.New("invalid extended key prefix")
+	}
+	data := base58.Decode(s[len(ExtendedKeyPrefix):])
+	if len(data) != extendedKeySize {
+		return nil, errors.New("invalid extended key format")
+	}
+	payload := data[:len(data)-4]
+	checksum := crypto.NewHash(append([]byte(ExtendedKeyPrefix), payload...))
+	if !bytes.Equal(checksum[:4], data[len(payload):]) {
+		return nil, errors.New("invalid extended key checksum")
+	}
+
+	k := &ExtendedKey{
+		Depth: payload[1],
+		Index: binary.BigEndian.Uint32(payload[2:6]),
+	}
+	copy(k.ChainCode[:], payload[6:38])
+	var spend, view crypto.Key
+	copy(spend[:], payload[38:38+crypto.KeySize])
+	copy(view[:], payload[38+crypto.KeySize:])
+
+	var err error
+	switch payload[0] {
+	case ExtendedKeyPrivate:
+		if k.Address.PrivateSpendKey, err = spend.AsPrivateKey(); err != nil {
+			return nil, err
+		}
+		k.Address.PublicSpendKey = k.Address.PrivateSpendKey.Public()
+		fallthrough
+	case ExtendedKeyView:
+		if k.Address.PrivateViewKey, err = view.AsPrivateKey(); err != nil {
+			return nil, err
+		}
+		k.Address.PublicViewKey = k.Address.PrivateViewKey.Public()
+		if k.Address.PublicSpendKey == nil {
+			if k.Address.PublicSpendKey, err = spend.AsPublicKey(); err != nil {
+				return nil, err
+			}
+		}
+	case ExtendedKeyPublic:
+		if k.Address.PublicSpendKey, err = spend.AsPublicKey(); err != nil {
+			return nil, err
+		}
+		if k.Address.PublicViewKey, err = view.AsPublicKey(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid extended key kind %d", payload[0])
+	}
+	return k, nil
+}
+
+func (k *ExtendedKey) derive(data []byte) ([]byte, crypto.Hash) {
+	h1 := crypto.NewHash(append(k.ChainCode[:], data...))
+	h2 := crypto.NewHash(h1[:])
+	return append(h1[:], h2[:]...), crypto.NewHash(append(h2[:], k.ChainCode[:]...))
+}
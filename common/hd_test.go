@@ -0,0 +1,74 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedKey(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := make([]byte, 64)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	master, err := NewExtendedKeyFromSeed(seed)
+	assert.Nil(err)
+	assert.Equal(ExtendedKeyPrivate, master.Kind())
+	_, err = NewExtendedKeyFromSeed(seed[:32])
+	assert.NotNil(err)
+
+	parent, err := master.DerivePath("m/44'/1h")
+	assert.Nil(err)
+	assert.Equal(uint8(2), parent.Depth)
+	assert.Equal(HardenedKeyStart+1, parent.Index)
+	again, err := master.DerivePath("m/44'/1'")
+	assert.Nil(err)
+	assert.Equal(parent.String(), again.String())
+
+	view := parent.Neuter(false)
+	public := parent.Neuter(true)
+	assert.Equal(ExtendedKeyView, view.Kind())
+	assert.Equal(ExtendedKeyPublic, public.Kind())
+	_, err = public.Child(HardenedKeyStart)
+	assert.NotNil(err)
+
+	for i := uint32(0); i < 8; i++ {
+		priv, err := parent.Child(i)
+		assert.Nil(err)
+		vc, err := view.Child(i)
+		assert.Nil(err)
+		pc, err := public.Child(i)
+		assert.Nil(err)
+		assert.Equal(priv.Address.String(), vc.Address.String())
+		assert.Equal(priv.Address.String(), pc.Address.String())
+		assert.Equal(priv.Address.PrivateSpendKey.Public().String(), priv.Address.PublicSpendKey.String())
+		assert.Equal(priv.Address.PrivateViewKey.String(), vc.Address.PrivateViewKey.String())
+		assert.Nil(vc.Address.PrivateSpendKey)
+		assert.Nil(pc.Address.PrivateViewKey)
+	}
+	c0, _ := parent.Child(0)
+	c1, _ := parent.Child(1)
+	assert.NotEqual(c0.Address.String(), c1.Address.String())
+
+	for _, k := range []*ExtendedKey{parent, view, public} {
+		decoded, err := NewExtendedKeyFromString(k.String())
+		assert.Nil(err)
+		assert.Equal(k.String(), decoded.String())
+		assert.Equal(k.Address.String(), decoded.Address.String())
+		assert.Equal(k.Kind(), decoded.Kind())
+	}
+	s := public.String()
+	_, err = NewExtendedKeyFromString(s[:len(s)-1] + "1")
+	assert.NotNil(err)
+
+	_, err = master.DerivePath("0/1")
+	assert.NotNil(err)
+	_, err = master.DerivePath("m/x")
+	assert.NotNil(err)
+	_, err = master.DerivePath("m/2147483648")
+	assert.NotNil(err)
+}
@@ -1,8 +1,6 @@
 package common
 
 import (
-	"fmt"
-
 	"github.com/MixinNetwork/mixin/crypto"
 )
 
@@ -34,20 +32,20 @@ func (m *MintData) Distribute(tx crypto.Hash) *MintDistribution {
 
 func (tx *VersionedTransaction) validateMint(store DataStore) error {
 	if len(tx.Inputs) != 1 {
-		return fmt.Errorf("invalid inputs count %d for mint", len(tx.Inputs))
+		return NewValidationError(ErrorCodeFormat, "invalid inputs count %d for mint", len(tx.Inputs))
 	}
 	for _, out := range tx.Outputs {
 		if out.Type != OutputTypeScript {
-			return fmt.Errorf("invalid mint output type %d", out.Type)
+			return NewValidationError(ErrorCodeOutput, "invalid mint output type %d", out.Type)
 		}
 	}
 	if tx.Asset != XINAssetId {
-		return fmt.Errorf("invalid mint asset %s", tx.Asset.String())
+		return NewValidationError(ErrorCodeAsset, "invalid mint asset %s", tx.Asset.String())
 	}
 
 	mint := tx.Inputs[0].Mint
 	if mint.Group != MintGroupKernelNode {
-		return fmt.Errorf("invalid mint group %s", mint.Group)
+		return NewValidationError(ErrorCodeState, "invalid mint group %s", mint.Group)
 	}
 
 	dist, err := store.ReadLastMintDistribution(mint.Group)
@@ -58,10 +56,10 @@ func (tx *VersionedTransaction) validateMint(store DataStore) error {
 		return nil
 	}
 	if mint.Batch < dist.Batch {
-		return fmt.Errorf("backward mint batch %d %d", dist.Batch, mint.Batch)
+		return NewValidationError(ErrorCodeState, "backward mint batch %d %d", dist.Batch, mint.Batch)
 	}
 	if dist.Transaction != tx.PayloadHash() || dist.Amount.Cmp(mint.Amount) != 0 {
-		return fmt.Errorf("invalid mint lock %s %s", dist.Transaction.String(), tx.PayloadHash().String())
+		return NewValidationError(ErrorCodeInputLocked, "invalid mint lock %s %s", dist.Transaction.String(), tx.PayloadHash().String())
 	}
 	return nil
 }
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const PartialTransactionVersion = 1
+
+// PartialInput carries everything an offline signer needs to sign the input,
+// the signatures are indexed by the position of the key in the UTXO keys.
+type PartialInput struct {
+	Keys       []crypto.Key
+	Mask       crypto.Key
+	Script     Script
+	Signatures map[int]crypto.Signature
+}
+
+type PartialTransaction struct {
+	Version     uint8
+	Transaction *Transaction
+	Inputs      []*PartialInput
+}
+
+func NewPartialTransaction(tx *Transaction, reader UTXOReader) (*PartialTransaction, error) {
+	pt := &PartialTransaction{
+		Version:     PartialTransactionVersion,
+		Transaction: tx,
+	}
+	for _, in := range tx.Inputs {
+		if !in.Hash.HasValue() || in.Deposit != nil || in.Mint != nil || len(in.Genesis) > 0 {
+			return nil, fmt.Errorf("invalid input format for partial transaction")
+		}
+		utxo, err := reader.ReadUTXO(in.Hash, in.Index)
+		if err != nil {
+			return nil, err
+		}
+		if utxo == nil {
+			return nil, fmt.Errorf("input not found %s:%d", in.Hash.String(), in.Index)
+		}
+		if len(utxo.Keys) == 0 || !utxo.Mask.HasValue() {
+			return nil, fmt.Errorf("invalid input keys %s:%d", in.Hash.String(), in.Index)
+		}
+		err = utxo.Script.VerifyFormat()
+		if err != nil {
+			return nil, err
+		}
+		pt.Inputs = append(pt.Inputs, &PartialInput{
+			Keys:       utxo.Keys,
+			Mask:       utxo.Mask,
+			Script:     utxo.Script,
+			Signatures: make(map[int]crypto.Signature),
+		})
+	}
+	return pt, nil
+}
+
+func UnmarshalPartialTransaction(data []byte) (*PartialTransaction, error) {
+	var pt PartialTransaction
+	err := MsgpackUnmarshal(data, &pt)
+	if err != nil {
+		return nil, err
+	}
+	if pt.Version != PartialTransactionVersion || pt.Transaction == nil {
+		return nil, fmt.Errorf("invalid partial transaction version %d", pt.Version)
+	}
+	if len(pt.Inputs) != len(pt.Transaction.Inputs) {
+		return nil, fmt.Errorf("invalid partial transaction inputs %d %d", len(pt.Inputs), len(pt.Transaction.Inputs))
+	}
+	for _, in := range pt.Inputs {
+		if in.Signatures == nil {
+			in.Signatures = make(map[int]crypto.Signature)
+		}
+	}
+	return &pt, nil
+}
+
+func (pt *PartialTransaction) Marshal() []byte {
+	return MsgpackMarshalPanic(pt)
+}
+
+func (pt *PartialTransaction) PayloadHash() crypto.Hash {
+	return crypto.NewHash(MsgpackMarshalPanic(pt.Transaction))
+}
+
+// Sign adds the signatures of all accounts owning any key of the inputs, and
+// returns the number of signatures added. Accounts owning none are ignored, so
+// each signer could sign with only the keys it holds.
+func (pt *PartialTransaction) Sign(accounts []Address) (int, error) {
+	msg := MsgpackMarshalPanic(pt.Transaction)
+	var signed int
+	for i, in := range pt.Inputs {
+		mask, err := in.Mask.AsPublicKey()
+		if err != nil {
+			return signed, err
+		}
+		index := uint64(pt.Transaction.Inputs[i].Index)
+		for _, acc := range accounts {
+			priv := crypto.DeriveGhostPrivateKey(mask, acc.PrivateViewKey, acc.PrivateSpendKey, index)
+			pub := priv.Public().Key()
+			for j, k := range in.Keys {
+				if k != pub {
+					continue
+				}
+				sig, err := priv.Sign(msg)
+				if err != nil {
+					return signed, err
+				}
+				in.Signatures[j] = *sig
+				signed += 1
+			}
+		}
+	}
+	return signed, nil
+}
+
+func (pt *PartialTransaction) Combine(other *PartialTransaction) error {
+	if pt.PayloadHash() != other.PayloadHash() {
+		return fmt.Errorf("invalid partial transaction payload %s %s", pt.PayloadHash(), other.PayloadHash())
+	}
+	msg := MsgpackMarshalPanic(pt.Transaction)
+	for i, in := range pt.Inputs {
+		for j, sig := range other.Inputs[i].Signatures {
+			if j < 0 || j >= len(in.Keys) {
+				return fmt.Errorf("invalid partial signature index %d/%d", j, len(in.Keys))
+			}
+			key, err := in.Keys[j].AsPublicKey()
+			if err != nil {
+				return err
+			}
+			if !key.Verify(msg, &sig) {
+				return fmt.Errorf("invalid partial signature for input %d key %d", i, j)
+			}
+			in.Signatures[j] = sig
+		}
+	}
+	return nil
+}
+
+// Finalize orders the signatures by key position as required by validation,
+// and fails if any input doesn't reach its script threshold.
+func (pt *PartialTransaction) Finalize() (*VersionedTransaction, error) {
+	ver := pt.Transaction.AsLatestVersion()
+	for i, in := range pt.Inputs {
+		indexes := make([]int, 0, len(in.Signatures))
+		for j := range in.Signatures {
+			indexes = append(indexes, j)
+		}
+		sort.Ints(indexes)
+		sigs := make([]crypto.Signature, len(indexes))
+		for k, j := range indexes {
+			sigs[k] = in.Signatures[j]
+		}
+		err := in.Script.Validate(len(sigs))
+		if err != nil {
+			return nil, fmt.Errorf("input %d not fully signed %s", i, err.Error())
+		}
+		ver.Signatures = append(ver.Signatures, sigs)
+	}
+	return ver, nil
+}
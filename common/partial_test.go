@@ -0,0 +1,76 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialTransaction(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 3; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	store := storeImpl{seed: seed, accounts: accounts}
+	genesis := crypto.NewHash([]byte("genesis"))
+
+	tx := NewTransaction(XINAssetId)
+	tx.AddInput(genesis, 0)
+	tx.AddInput(genesis, 1)
+	tx.AddScriptOutput(accounts[:1], NewThresholdScript(1), NewInteger(20000), seed)
+	pt, err := NewPartialTransaction(tx, store)
+	assert.Nil(err)
+	assert.Len(pt.Inputs, 2)
+	assert.Len(pt.Inputs[1].Keys, 2)
+
+	first, err := UnmarshalPartialTransaction(pt.Marshal())
+	assert.Nil(err)
+	second, err := UnmarshalPartialTransaction(pt.Marshal())
+	assert.Nil(err)
+	assert.Equal(pt.PayloadHash(), first.PayloadHash())
+
+	signed, err := first.Sign(accounts[:1])
+	assert.Nil(err)
+	assert.Equal(2, signed)
+	_, err = first.Finalize()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "input 1 not fully signed")
+
+	signed, err = second.Sign(accounts[1:])
+	assert.Nil(err)
+	assert.Equal(1, signed)
+	second, err = UnmarshalPartialTransaction(second.Marshal())
+	assert.Nil(err)
+
+	err = first.Combine(second)
+	assert.Nil(err)
+	ver, err := first.Finalize()
+	assert.Nil(err)
+	assert.Nil(ver.Validate(store))
+
+	forged, err := UnmarshalPartialTransaction(pt.Marshal())
+	assert.Nil(err)
+	forged.Inputs[0].Signatures[0] = ver.Signatures[1][0]
+	err = first.Combine(forged)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid partial signature")
+
+	other := NewTransaction(XINAssetId)
+	other.AddInput(genesis, 0)
+	other.AddScriptOutput(accounts[:1], NewThresholdScript(1), NewInteger(10000), seed)
+	opt, err := NewPartialTransaction(other, store)
+	assert.Nil(err)
+	err = first.Combine(opt)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid partial transaction payload")
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const (
+	ScanBatchSize = 500
+
+	OutputStateUnspent = "unspent"
+	OutputStateLocked  = "locked"
+	OutputStateSpent   = "spent"
+)
+
+type OutputScanner interface {
+	UTXOReader
+	ReadTransaction(hash crypto.Hash) (*VersionedTransaction, string, error)
+	ReadSnapshotWithTransactionsSinceTopology(topologyOffset, count uint64) ([]*SnapshotWithTopologicalOrder, []*VersionedTransaction, error)
+}
+
+type ScannedOutput struct {
+	Snapshot    crypto.Hash
+	Topology    uint64
+	Transaction crypto.Hash
+	Index       int
+	KeyIndex    int
+	Asset       crypto.Hash
+	Output      *Output
+	State       string
+	PrivateKey  crypto.PrivateKey
+}
+
+// ScanOutputs walks the snapshots in topology range [offset, offset+count)
+// and calls hook for every output owned by the account. The account needs the
+// private view key and the public spend key, and the ghost private key is only
+// derived if the private spend key is present, or the scan is watch-only.
+func ScanOutputs(reader OutputScanner, account Address, offset, count uint64, hook func(out *ScannedOutput) error) error {
+	if account.PrivateViewKey == nil || account.PublicSpendKey == nil {
+		return fmt.Errorf("invalid scan account without view key or spend key")
+	}
+	spend := account.PublicSpendKey.Key()
+
+	for end := offset + count; offset < end; {
+		batch := end - offset
+		if batch > ScanBatchSize {
+			batch = ScanBatchSize
+		}
+		snapshots, transactions, err := reader.ReadSnapshotWithTransactionsSinceTopology(offset, batch)
+		if err != nil {
+			return err
+		}
+		for i, s := range snapshots {
+			err := scanTransactionOutputs(reader, account, spend, s, transactions[i], hook)
+			if err != nil {
+				return err
+			}
+		}
+		if uint64(len(snapshots)) < batch {
+			return nil
+		}
+		offset = snapshots[len(snapshots)-1].TopologicalOrder + 1
+	}
+	return nil
+}
+
+func scanTransactionOutputs(reader OutputScanner, account Address, spend crypto.Key, s *SnapshotWithTopologicalOrder, tx *VersionedTransaction, hook func(out *ScannedOutput) error) error {
+	hash := tx.PayloadHash()
+	for i, out := range tx.Outputs {
+		if !out.Mask.HasValue() {
+			continue
+		}
+		mask, err := out.Mask.AsPublicKey()
+		if err != nil {
+			continue
+		}
+		for j, k := range out.Keys {
+			key, err := k.AsPublicKey()
+			if err != nil {
+				continue
+			}
+			if crypto.ViewGhostOutputKey(mask, key, account.PrivateViewKey, uint64(i)).Key() != spend {
+				continue
+			}
+			state, err := scanOutputState(reader, hash, i)
+			if err != nil {
+				return err
+			}
+			owned := &ScannedOutput{
+				Snapshot:    s.Hash,
+				Topology:    s.TopologicalOrder,
+				Transaction: hash,
+				Index:       i,
+				KeyIndex:    j,
+				Asset:       tx.Asset,
+				Output:      out,
+				State:       state,
+			}
+			if account.PrivateSpendKey != nil {
+				owned.PrivateKey = crypto.DeriveGhostPrivateKey(mask, account.PrivateViewKey, account.PrivateSpendKey, uint64(i))
+			}
+			err = hook(owned)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func scanOutputState(reader OutputScanner, hash crypto.Hash, index int) (string, error) {
+	utxo, err := reader.ReadUTXO(hash, index)
+	if err != nil {
+		return "", err
+	}
+	if utxo == nil {
+		return OutputStateSpent, nil
+	}
+	if !utxo.LockHash.HasValue() {
+		return OutputStateUnspent, nil
+	}
+	_, snap, err := reader.ReadTransaction(utxo.LockHash)
+	if err != nil {
+		return "", err
+	}
+	if len(snap) > 0 {
+		return OutputStateSpent, nil
+	}
+	return OutputStateLocked, nil
+}
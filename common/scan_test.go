@@ -0,0 +1,104 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanOutputs(t *testing.T) {
+	assert := assert.New(t)
+
+	accounts := make([]Address, 0)
+	for i := 0; i < 2; i++ {
+		seed := make([]byte, 64)
+		seed[i] = byte(i)
+		accounts = append(accounts, NewAddressFromSeed(seed))
+	}
+	scanner := &scanStoreImpl{locks: make(map[crypto.Hash]crypto.Hash), finals: make(map[crypto.Hash]bool)}
+	for i := 0; i < 1200; i++ {
+		seed := make([]byte, 64)
+		rand.Read(seed)
+		tx := NewTransaction(XINAssetId)
+		tx.AddInput(crypto.NewHash(seed), 0)
+		tx.AddScriptOutput(accounts[1:], NewThresholdScript(1), NewInteger(1), seed)
+		if i%100 == 0 {
+			tx.AddScriptOutput(accounts, NewThresholdScript(1), NewInteger(uint64(i+1)), seed)
+		}
+		ver := tx.AsLatestVersion()
+		s := &SnapshotWithTopologicalOrder{TopologicalOrder: uint64(i)}
+		s.Hash = crypto.NewHash(seed)
+		s.Transaction = ver.PayloadHash()
+		scanner.snapshots = append(scanner.snapshots, s)
+		scanner.transactions = append(scanner.transactions, ver)
+	}
+	spent, locked := scanner.transactions[300].PayloadHash(), scanner.transactions[500].PayloadHash()
+	scanner.locks[spent] = crypto.NewHash([]byte("spent"))
+	scanner.finals[crypto.NewHash([]byte("spent"))] = true
+	scanner.locks[locked] = crypto.NewHash([]byte("locked"))
+
+	var outputs []*ScannedOutput
+	err := ScanOutputs(scanner, accounts[0], 0, 2000, func(out *ScannedOutput) error {
+		outputs = append(outputs, out)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Len(outputs, 12)
+	for i, out := range outputs {
+		assert.Equal(uint64(i*100), out.Topology)
+		assert.Equal(1, out.Index)
+		assert.Equal(0, out.KeyIndex)
+		assert.Equal(NewInteger(uint64(i*100+1)).String(), out.Output.Amount.String())
+		assert.Equal(out.Output.Keys[0], out.PrivateKey.Public().Key())
+	}
+	assert.Equal(OutputStateSpent, outputs[3].State)
+	assert.Equal(OutputStateLocked, outputs[5].State)
+	assert.Equal(OutputStateUnspent, outputs[7].State)
+
+	watch := Address{PrivateViewKey: accounts[1].PrivateViewKey, PublicSpendKey: accounts[1].PublicSpendKey}
+	outputs = nil
+	err = ScanOutputs(scanner, watch, 150, 100, func(out *ScannedOutput) error {
+		outputs = append(outputs, out)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Len(outputs, 101)
+	assert.Equal(uint64(150), outputs[0].Topology)
+	assert.Nil(outputs[0].PrivateKey)
+	assert.Equal(0, outputs[50].KeyIndex)
+	assert.Equal(1, outputs[51].KeyIndex)
+}
+
+type scanStoreImpl struct {
+	storeImpl
+	snapshots    []*SnapshotWithTopologicalOrder
+	transactions []*VersionedTransaction
+	locks        map[crypto.Hash]crypto.Hash
+	finals       map[crypto.Hash]bool
+}
+
+func (store *scanStoreImpl) ReadUTXO(hash crypto.Hash, index int) (*UTXOWithLock, error) {
+	return &UTXOWithLock{LockHash: store.locks[hash]}, nil
+}
+
+func (store *scanStoreImpl) ReadTransaction(hash crypto.Hash) (*VersionedTransaction, string, error) {
+	if store.finals[hash] {
+		return nil, "snapshot", nil
+	}
+	return nil, "", nil
+}
+
+func (store *scanStoreImpl) ReadSnapshotWithTransactionsSinceTopology(offset, count uint64) ([]*SnapshotWithTopologicalOrder, []*VersionedTransaction, error) {
+	if offset >= uint64(len(store.snapshots)) {
+		return nil, nil, nil
+	}
+	end := offset + count
+	if end > uint64(len(store.snapshots)) {
+		end = uint64(len(store.snapshots))
+	}
+	return store.snapshots[offset:end], store.transactions[offset:end], nil
+}
@@ -2,7 +2,6 @@ package common
 
 import (
 	"encoding/hex"
-	"fmt"
 	"strconv"
 )
 
@@ -21,13 +20,13 @@ func NewThresholdScript(threshold uint8) Script {
 
 func (s Script) VerifyFormat() error {
 	if len(s) != 3 {
-		return fmt.Errorf("invalid script length %d", len(s))
+		return NewValidationError(ErrorCodeOutput, "invalid script length %d", len(s))
 	}
 	if s[0] != OperatorCmp || s[1] != OperatorSum {
-		return fmt.Errorf("invalid script operators %d %d", s[0], s[1])
+		return NewValidationError(ErrorCodeOutput, "invalid script operators %d %d", s[0], s[1])
 	}
 	if s[2] > Operator64 {
-		return fmt.Errorf("invalid script threshold %d", s[2])
+		return NewValidationError(ErrorCodeOutput, "invalid script threshold %d", s[2])
 	}
 	return nil
 }
@@ -38,7 +37,7 @@ func (s Script) Validate(sum int) error {
 		return err
 	}
 	if sum < int(s[2]) {
-		return fmt.Errorf("invalid signature keys %d %d", sum, s[2])
+		return NewValidationError(ErrorCodeSignature, "invalid signature keys %d %d", sum, s[2])
 	}
 	return nil
 }
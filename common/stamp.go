@@ -0,0 +1,68 @@
+package common
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const (
+	StampNonceSize = 8
+	StampSizeUnit  = 1024
+)
+
+// StampDifficulty is the leading zero bits required for a transaction payload
+// of the size, one more bit for every doubling of the size unit.
+func StampDifficulty(base, size int) int {
+	if base <= 0 {
+		return 0
+	}
+	return base + bits.Len(uint(size/StampSizeUnit))
+}
+
+func StampWork(hash crypto.Hash) int {
+	var work int
+	for _, b := range hash {
+		if b != 0 {
+			return work + bits.LeadingZeros8(b)
+		}
+		work += 8
+	}
+	return work
+}
+
+// Stamp appends a nonce to the transaction extra, and increments it until the
+// payload hash has enough leading zero bits for the base difficulty.
+func (tx *Transaction) Stamp(base int) error {
+	if base <= 0 {
+		return nil
+	}
+	if len(tx.Extra)+StampNonceSize > ExtraSizeLimit {
+		return NewValidationError(ErrorCodeStamp, "invalid extra size %d for stamp", len(tx.Extra))
+	}
+	extra := tx.Extra
+	tx.Extra = make([]byte, len(extra)+StampNonceSize)
+	copy(tx.Extra, extra)
+	nonce := tx.Extra[len(extra):]
+
+	for i := uint64(0); ; i++ {
+		binary.BigEndian.PutUint64(nonce, i)
+		msg := MsgpackMarshalPanic(tx)
+		if StampWork(crypto.NewHash(msg)) >= StampDifficulty(base, len(msg)) {
+			return nil
+		}
+	}
+}
+
+func (ver *VersionedTransaction) ValidateStamp(base int) error {
+	if base <= 0 || ver.TransactionType() != TransactionTypeScript {
+		return nil
+	}
+	msg := ver.PayloadMarshal()
+	work, difficulty := StampWork(crypto.NewHash(msg)), StampDifficulty(base, len(msg))
+	if work < difficulty {
+		return NewValidationError(ErrorCodeStamp, "invalid transaction stamp work %d/%d", work, difficulty)
+	}
+	return nil
+}
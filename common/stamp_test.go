@@ -0,0 +1,45 @@
+// +build ed25519 !custom_alg
+
+package common
+
+import (
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStamp(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, StampDifficulty(0, 4096))
+	assert.Equal(8, StampDifficulty(8, 100))
+	assert.Equal(9, StampDifficulty(8, 1024))
+	assert.Equal(10, StampDifficulty(8, 3000))
+
+	var hash crypto.Hash
+	assert.Equal(256, StampWork(hash))
+	hash[0] = 0x01
+	assert.Equal(7, StampWork(hash))
+	hash[0], hash[1] = 0, 0x10
+	assert.Equal(11, StampWork(hash))
+
+	accounts := []Address{randomAccount()}
+	tx := NewTransaction(XINAssetId)
+	tx.AddInput(crypto.Hash{}, 0)
+	tx.AddRandomScriptOutput(accounts, NewThresholdScript(1), NewInteger(10000))
+	tx.Extra = []byte("memo")
+	ver := tx.AsLatestVersion()
+	assert.Nil(ver.ValidateStamp(0))
+
+	err := tx.Stamp(10)
+	assert.Nil(err)
+	assert.Len(tx.Extra, 4+StampNonceSize)
+	assert.Equal("memo", string(tx.Extra[:4]))
+	ver = tx.AsLatestVersion()
+	assert.Nil(ver.ValidateStamp(10))
+	assert.True(StampWork(ver.PayloadHash()) >= 10)
+
+	tx.Extra = make([]byte, ExtraSizeLimit)
+	assert.NotNil(tx.Stamp(10))
+}
@@ -38,6 +38,8 @@ const (
 	TransactionTypeDomainAccept     = 0x10
 	TransactionTypeDomainRemove     = 0x11
 	TransactionTypeNodeCancel       = 0x12
+	TransactionTypeDomainCustody    = 0x13
+	TransactionTypeDomainRelease    = 0x14
 	TransactionTypeUnknown          = 0xff
 )
 
@@ -137,6 +139,10 @@ func (tx *SignedTransaction) TransactionType() uint8 {
 			return TransactionTypeDomainAccept
 		case OutputTypeDomainRemove:
 			return TransactionTypeDomainRemove
+		case OutputTypeDomainAssetCustody:
+			return TransactionTypeDomainCustody
+		case OutputTypeDomainAssetRelease:
+			return TransactionTypeDomainRelease
 		}
 		isScript = isScript && out.Type == OutputTypeScript
 	}
@@ -137,8 +137,12 @@ func (store storeImpl) LockUTXO(hash crypto.Hash, index int, tx crypto.Hash, for
 	return nil
 }
 
-func (store storeImpl) ReadDomains() []Domain {
-	return nil
+func (store storeImpl) ReadDomains() ([]Domain, error) {
+	return nil, nil
+}
+
+func (store storeImpl) ReadDomainCustody(domain crypto.Key, asset crypto.Hash) (Integer, error) {
+	return NewInteger(0), nil
 }
 
 func (store storeImpl) ReadAllNodes() []*Node {
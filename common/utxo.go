@@ -38,7 +38,8 @@ type NodeReader interface {
 }
 
 type DomainReader interface {
-	ReadDomains() []Domain
+	ReadDomains() ([]Domain, error)
+	ReadDomainCustody(domain crypto.Key, asset crypto.Hash) (Integer, error)
 }
 
 type DataStore interface {
@@ -59,6 +60,9 @@ func (tx *VersionedTransaction) UnspentOutputs() []*UTXO {
 			OutputTypeNodeAccept,
 			OutputTypeNodeRemove,
 			OutputTypeDomainAccept,
+			OutputTypeDomainRemove,
+			OutputTypeDomainAssetCustody,
+			OutputTypeDomainAssetRelease,
 			OutputTypeWithdrawalFuel,
 			OutputTypeWithdrawalClaim:
 		case OutputTypeWithdrawalSubmit:
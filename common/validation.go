@@ -13,22 +13,30 @@ func (ver *VersionedTransaction) Validate(store DataStore) error {
 	txType := tx.TransactionType()
 
 	if ver.Version != TxVersion || tx.Version != TxVersion {
-		return fmt.Errorf("invalid tx version %d %d", ver.Version, tx.Version)
+		return NewValidationError(ErrorCodeVersion, "invalid tx version %d %d", ver.Version, tx.Version)
 	}
 	if txType == TransactionTypeUnknown {
-		return fmt.Errorf("invalid tx type %d", txType)
+		return NewValidationError(ErrorCodeType, "invalid tx type %d", txType)
 	}
 	if len(tx.Inputs) < 1 || len(tx.Outputs) < 1 {
-		return fmt.Errorf("invalid tx inputs or outputs %d %d", len(tx.Inputs), len(tx.Outputs))
+		return NewValidationError(ErrorCodeFormat, "invalid tx inputs or outputs %d %d", len(tx.Inputs), len(tx.Outputs))
 	}
-	if len(tx.Inputs) != len(tx.Signatures) && txType != TransactionTypeNodeAccept && txType != TransactionTypeNodeRemove {
-		return fmt.Errorf("invalid tx signature number %d %d %d", len(tx.Inputs), len(tx.Signatures), txType)
+	switch txType {
+	case TransactionTypeNodeAccept, TransactionTypeNodeRemove:
+	case TransactionTypeDomainAccept, TransactionTypeDomainCustody:
+		if len(tx.Inputs)+1 != len(tx.Signatures) {
+			return NewValidationError(ErrorCodeSignature, "invalid tx signature number %d %d %d", len(tx.Inputs), len(tx.Signatures), txType)
+		}
+	default:
+		if len(tx.Inputs) != len(tx.Signatures) {
+			return NewValidationError(ErrorCodeSignature, "invalid tx signature number %d %d %d", len(tx.Inputs), len(tx.Signatures), txType)
+		}
 	}
 	if len(tx.Extra) > ExtraSizeLimit {
-		return fmt.Errorf("invalid extra size %d", len(tx.Extra))
+		return NewValidationError(ErrorCodeFormat, "invalid extra size %d", len(tx.Extra))
 	}
 	if len(ver.Marshal()) > config.TransactionMaximumSize {
-		return fmt.Errorf("invalid transaction size %d", len(msg))
+		return NewValidationError(ErrorCodeFormat, "invalid transaction size %d", len(msg))
 	}
 
 	inputsFilter, inputAmount, err := validateInputs(store, tx, msg, ver.PayloadHash(), txType)
@@ -41,7 +49,7 @@ func (ver *VersionedTransaction) Validate(store DataStore) error {
 	}
 
 	if inputAmount.Sign() <= 0 || inputAmount.Cmp(outputAmount) != 0 {
-		return fmt.Errorf("invalid input output amount %s %s", inputAmount.String(), outputAmount.String())
+		return NewValidationError(ErrorCodeAmount, "invalid input output amount %s %s", inputAmount.String(), outputAmount.String())
 	}
 
 	switch txType {
@@ -64,21 +72,25 @@ func (ver *VersionedTransaction) Validate(store DataStore) error {
 	case TransactionTypeNodeAccept:
 		return tx.validateNodeAccept(store)
 	case TransactionTypeNodeResign:
-		return fmt.Errorf("invalid transaction type %d", txType)
+		return NewValidationError(ErrorCodeType, "invalid transaction type %d", txType)
 	// case TransactionTypeNodeRemove:
 	// 	return tx.validateNodeRemove(store)
 	case TransactionTypeDomainAccept:
-		return fmt.Errorf("invalid transaction type %d", txType)
+		return tx.validateDomainAccept(store, inputsFilter)
 	case TransactionTypeDomainRemove:
-		return fmt.Errorf("invalid transaction type %d", txType)
+		return tx.validateDomainRemove(store, inputsFilter)
+	case TransactionTypeDomainCustody:
+		return tx.validateDomainCustody(store, inputsFilter, msg)
+	case TransactionTypeDomainRelease:
+		return tx.validateDomainRelease(store, inputsFilter, msg)
 	}
-	return fmt.Errorf("invalid transaction type %d", txType)
+	return NewValidationError(ErrorCodeType, "invalid transaction type %d", txType)
 }
 
 func validateScriptTransaction(inputs map[string]*UTXO) error {
 	for _, in := range inputs {
-		if in.Type != OutputTypeScript && in.Type != OutputTypeNodeRemove {
-			return fmt.Errorf("invalid utxo type %d", in.Type)
+		if in.Type != OutputTypeScript && in.Type != OutputTypeNodeRemove && in.Type != OutputTypeDomainRemove && in.Type != OutputTypeDomainAssetRelease {
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
 		}
 	}
 	return nil
@@ -99,7 +111,7 @@ func validateInputs(store DataStore, tx *SignedTransaction, msg []byte, hash cry
 
 		fk := fmt.Sprintf("%s:%d", in.Hash.String(), in.Index)
 		if inputsFilter[fk] != nil {
-			return inputsFilter, inputAmount, fmt.Errorf("invalid input %s", fk)
+			return inputsFilter, inputAmount, NewValidationError(ErrorCodeInput, "invalid input %s", fk)
 		}
 
 		utxo, err := store.ReadUTXO(in.Hash, in.Index)
@@ -107,13 +119,13 @@ func validateInputs(store DataStore, tx *SignedTransaction, msg []byte, hash cry
 			return inputsFilter, inputAmount, err
 		}
 		if utxo == nil {
-			return inputsFilter, inputAmount, fmt.Errorf("input not found %s:%d", in.Hash.String(), in.Index)
+			return inputsFilter, inputAmount, NewValidationError(ErrorCodeInputNotFound, "input not found %s:%d", in.Hash.String(), in.Index)
 		}
 		if utxo.Asset != tx.Asset {
-			return inputsFilter, inputAmount, fmt.Errorf("invalid input asset %s %s", utxo.Asset.String(), tx.Asset.String())
+			return inputsFilter, inputAmount, NewValidationError(ErrorCodeAsset, "invalid input asset %s %s", utxo.Asset.String(), tx.Asset.String())
 		}
 		if utxo.LockHash.HasValue() && utxo.LockHash != hash {
-			return inputsFilter, inputAmount, fmt.Errorf("input locked for transaction %s", utxo.LockHash)
+			return inputsFilter, inputAmount, NewValidationError(ErrorCodeInputLocked, "input locked for transaction %s", utxo.LockHash)
 		}
 
 		err = validateUTXO(i, &utxo.UTXO, tx.Signatures, msg, txType)
@@ -132,7 +144,7 @@ func validateOutputs(store DataStore, tx *SignedTransaction) (Integer, error) {
 	outputsFilter := make(map[crypto.Key]bool)
 	for _, o := range tx.Outputs {
 		if o.Amount.Sign() <= 0 {
-			return outputAmount, fmt.Errorf("invalid output amount %s", o.Amount.String())
+			return outputAmount, NewValidationError(ErrorCodeAmount, "invalid output amount %s", o.Amount.String())
 		}
 
 		if o.Withdrawal != nil {
@@ -142,17 +154,17 @@ func validateOutputs(store DataStore, tx *SignedTransaction) (Integer, error) {
 
 		for _, k := range o.Keys {
 			if outputsFilter[k] {
-				return outputAmount, fmt.Errorf("invalid output key %s", k.String())
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output key %s", k.String())
 			}
 			outputsFilter[k] = true
 			if _, err := k.AsPublicKey(); err != nil {
-				return outputAmount, fmt.Errorf("invalid output key format %s", k.String())
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output key format %s", k.String())
 			}
 			exist, err := store.CheckGhost(k)
 			if err != nil {
 				return outputAmount, err
 			} else if exist {
-				return outputAmount, fmt.Errorf("invalid output key %s", k.String())
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output key %s", k.String())
 			}
 		}
 
@@ -162,15 +174,17 @@ func validateOutputs(store DataStore, tx *SignedTransaction) (Integer, error) {
 			OutputTypeWithdrawalClaim,
 			OutputTypeNodePledge,
 			OutputTypeNodeCancel,
-			OutputTypeNodeAccept:
+			OutputTypeNodeAccept,
+			OutputTypeDomainAccept,
+			OutputTypeDomainAssetCustody:
 			if len(o.Keys) != 0 {
-				return outputAmount, fmt.Errorf("invalid output keys count %d for kernel multisig transaction", len(o.Keys))
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output keys count %d for kernel multisig transaction", len(o.Keys))
 			}
 			if len(o.Script) != 0 {
-				return outputAmount, fmt.Errorf("invalid output script %s for kernel multisig transaction", o.Script)
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output script %s for kernel multisig transaction", o.Script)
 			}
 			if o.Mask.HasValue() {
-				return outputAmount, fmt.Errorf("invalid output empty mask %s for kernel multisig transaction", o.Mask)
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid output empty mask %s for kernel multisig transaction", o.Mask)
 			}
 		default:
 			err := o.Script.VerifyFormat()
@@ -178,10 +192,10 @@ func validateOutputs(store DataStore, tx *SignedTransaction) (Integer, error) {
 				return outputAmount, err
 			}
 			if !o.Mask.HasValue() {
-				return outputAmount, fmt.Errorf("invalid script output empty mask %s", o.Mask)
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid script output empty mask %s", o.Mask)
 			}
 			if o.Withdrawal != nil {
-				return outputAmount, fmt.Errorf("invalid script output with withdrawal %s", o.Withdrawal.Address)
+				return outputAmount, NewValidationError(ErrorCodeOutput, "invalid script output with withdrawal %s", o.Withdrawal.Address)
 			}
 		}
 		outputAmount = outputAmount.Add(o.Amount)
@@ -191,7 +205,7 @@ func validateOutputs(store DataStore, tx *SignedTransaction) (Integer, error) {
 
 func validateUTXO(index int, utxo *UTXO, sigs [][]crypto.Signature, msg []byte, txType uint8) error {
 	switch utxo.Type {
-	case OutputTypeScript, OutputTypeNodeRemove:
+	case OutputTypeScript, OutputTypeNodeRemove, OutputTypeDomainRemove, OutputTypeDomainAssetRelease:
 		var offset, valid int
 		for _, sig := range sigs[index] {
 			for i, k := range utxo.Keys {
@@ -213,15 +227,25 @@ func validateUTXO(index int, utxo *UTXO, sigs [][]crypto.Signature, msg []byte,
 		if txType == TransactionTypeNodeAccept || txType == TransactionTypeNodeCancel {
 			return nil
 		}
-		return fmt.Errorf("pledge input used for invalid transaction type %d", txType)
+		return NewValidationError(ErrorCodeType, "pledge input used for invalid transaction type %d", txType)
 	case OutputTypeNodeAccept:
 		if txType == TransactionTypeNodeRemove {
 			return nil
 		}
-		return fmt.Errorf("accept input used for invalid transaction type %d", txType)
+		return NewValidationError(ErrorCodeType, "accept input used for invalid transaction type %d", txType)
+	case OutputTypeDomainAccept:
+		if txType == TransactionTypeDomainRemove {
+			return nil
+		}
+		return NewValidationError(ErrorCodeType, "domain accept input used for invalid transaction type %d", txType)
+	case OutputTypeDomainAssetCustody:
+		if txType == TransactionTypeDomainRelease {
+			return nil
+		}
+		return NewValidationError(ErrorCodeType, "custody input used for invalid transaction type %d", txType)
 	case OutputTypeNodeCancel:
-		return fmt.Errorf("should do more validation on those %d UTXOs", utxo.Type)
+		return NewValidationError(ErrorCodeType, "should do more validation on those %d UTXOs", utxo.Type)
 	default:
-		return fmt.Errorf("invalid input type %d", utxo.Type)
+		return NewValidationError(ErrorCodeInput, "invalid input type %d", utxo.Type)
 	}
 }
@@ -1,12 +1,8 @@
 package common
 
 import (
-	"fmt"
-
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/domains/bitcoin"
-	"github.com/MixinNetwork/mixin/domains/ethereum"
 )
 
 type WithdrawalData struct {
@@ -26,73 +22,68 @@ func (w *WithdrawalData) Asset() *Asset {
 func (tx *SignedTransaction) validateWithdrawalSubmit(inputs map[string]*UTXO) error {
 	for _, in := range inputs {
 		if in.Type != OutputTypeScript {
-			return fmt.Errorf("invalid utxo type %d", in.Type)
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
 		}
 	}
 
 	if len(tx.Outputs) > 2 {
-		return fmt.Errorf("invalid outputs count %d for withdrawal submit transaction", len(tx.Outputs))
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for withdrawal submit transaction", len(tx.Outputs))
 	}
 	if len(tx.Outputs) == 2 && tx.Outputs[1].Type != OutputTypeScript {
-		return fmt.Errorf("invalid change type %d for withdrawal submit transaction", tx.Outputs[1].Type)
+		return NewValidationError(ErrorCodeOutput, "invalid change type %d for withdrawal submit transaction", tx.Outputs[1].Type)
 	}
 
 	submit := tx.Outputs[0]
 	if submit.Type != OutputTypeWithdrawalSubmit {
-		return fmt.Errorf("invalid output type %d for withdrawal submit transaction", submit.Type)
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for withdrawal submit transaction", submit.Type)
 	}
 	if submit.Withdrawal == nil {
-		return fmt.Errorf("invalid withdrawal submit data")
+		return NewValidationError(ErrorCodeFormat, "invalid withdrawal submit data")
 	}
 
 	if err := submit.Withdrawal.Asset().Verify(); err != nil {
-		return fmt.Errorf("invalid asset data %s", err.Error())
+		return NewValidationError(ErrorCodeAsset, "invalid asset data %s", err.Error())
 	}
 	if id := submit.Withdrawal.Asset().AssetId(); id != tx.Asset {
-		return fmt.Errorf("invalid asset %s %s", tx.Asset, id)
+		return NewValidationError(ErrorCodeAsset, "invalid asset %s %s", tx.Asset, id)
 	}
 
 	if len(submit.Keys) != 0 {
-		return fmt.Errorf("invalid withdrawal submit keys %d", len(submit.Keys))
+		return NewValidationError(ErrorCodeOutput, "invalid withdrawal submit keys %d", len(submit.Keys))
 	}
 	if len(submit.Script) != 0 {
-		return fmt.Errorf("invalid withdrawal submit script %s", submit.Script)
+		return NewValidationError(ErrorCodeOutput, "invalid withdrawal submit script %s", submit.Script)
 	}
 	if submit.Mask.HasValue() {
-		return fmt.Errorf("invalid withdrawal submit mask %s", submit.Mask)
+		return NewValidationError(ErrorCodeOutput, "invalid withdrawal submit mask %s", submit.Mask)
 	}
 
-	switch submit.Withdrawal.Asset().ChainId {
-	case ethereum.EthereumChainId:
-		return ethereum.VerifyAddress(submit.Withdrawal.Address)
-	case bitcoin.BitcoinChainId:
-		return bitcoin.VerifyAddress(submit.Withdrawal.Address)
-	}
+	// the address and tag are validated by the kernel with the snapshot timestamp
 	return nil
 }
 
 func (tx *SignedTransaction) validateWithdrawalFuel(store DataStore, inputs map[string]*UTXO) error {
 	for _, in := range inputs {
 		if in.Type != OutputTypeScript {
-			return fmt.Errorf("invalid utxo type %d", in.Type)
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
 		}
 	}
 
 	if len(tx.Outputs) > 2 {
-		return fmt.Errorf("invalid outputs count %d for withdrawal fuel transaction", len(tx.Outputs))
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for withdrawal fuel transaction", len(tx.Outputs))
 	}
 	if len(tx.Outputs) == 2 && tx.Outputs[1].Type != OutputTypeScript {
-		return fmt.Errorf("invalid change type %d for withdrawal fuel transaction", tx.Outputs[1].Type)
+		return NewValidationError(ErrorCodeOutput, "invalid change type %d for withdrawal fuel transaction", tx.Outputs[1].Type)
 	}
 
 	fuel := tx.Outputs[0]
 	if fuel.Type != OutputTypeWithdrawalFuel {
-		return fmt.Errorf("invalid output type %d for withdrawal fuel transaction", fuel.Type)
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for withdrawal fuel transaction", fuel.Type)
 	}
 
 	var hash crypto.Hash
 	if len(tx.Extra) != len(hash) {
-		return fmt.Errorf("invalid extra %d for withdrawal fuel transaction", len(tx.Extra))
+		return NewValidationError(ErrorCodeFormat, "invalid extra %d for withdrawal fuel transaction", len(tx.Extra))
 	}
 	copy(hash[:], tx.Extra)
 	submit, _, err := store.ReadTransaction(hash)
@@ -100,14 +91,17 @@ func (tx *SignedTransaction) validateWithdrawalFuel(store DataStore, inputs map[
 		return err
 	}
 	if submit == nil {
-		return fmt.Errorf("invalid withdrawal submit data")
+		return NewValidationError(ErrorCodeFormat, "invalid withdrawal submit data")
 	}
 	withdrawal := submit.Outputs[0].Withdrawal
 	if withdrawal == nil || submit.Outputs[0].Type != OutputTypeWithdrawalSubmit {
-		return fmt.Errorf("invalid withdrawal submit data")
+		return NewValidationError(ErrorCodeFormat, "invalid withdrawal submit data")
+	}
+	if err := withdrawal.Asset().Verify(); err != nil {
+		return NewValidationError(ErrorCodeAsset, "invalid asset data %s", err.Error())
 	}
 	if id := withdrawal.Asset().FeeAssetId(); id != tx.Asset {
-		return fmt.Errorf("invalid fee asset %s %s", tx.Asset, id)
+		return NewValidationError(ErrorCodeAsset, "invalid fee asset %s %s", tx.Asset, id)
 	}
 	return nil
 }
@@ -115,31 +109,31 @@ func (tx *SignedTransaction) validateWithdrawalFuel(store DataStore, inputs map[
 func (tx *SignedTransaction) validateWithdrawalClaim(store DataStore, inputs map[string]*UTXO, msg []byte) error {
 	for _, in := range inputs {
 		if in.Type != OutputTypeScript {
-			return fmt.Errorf("invalid utxo type %d", in.Type)
+			return NewValidationError(ErrorCodeInput, "invalid utxo type %d", in.Type)
 		}
 	}
 
 	if tx.Asset != XINAssetId {
-		return fmt.Errorf("invalid asset %s for withdrawal claim transaction", tx.Asset)
+		return NewValidationError(ErrorCodeAsset, "invalid asset %s for withdrawal claim transaction", tx.Asset)
 	}
 	if len(tx.Outputs) > 2 {
-		return fmt.Errorf("invalid outputs count %d for withdrawal claim transaction", len(tx.Outputs))
+		return NewValidationError(ErrorCodeFormat, "invalid outputs count %d for withdrawal claim transaction", len(tx.Outputs))
 	}
 	if len(tx.Outputs) == 2 && tx.Outputs[1].Type != OutputTypeScript {
-		return fmt.Errorf("invalid change type %d for withdrawal claim transaction", tx.Outputs[1].Type)
+		return NewValidationError(ErrorCodeOutput, "invalid change type %d for withdrawal claim transaction", tx.Outputs[1].Type)
 	}
 
 	claim := tx.Outputs[0]
 	if claim.Type != OutputTypeWithdrawalClaim {
-		return fmt.Errorf("invalid output type %d for withdrawal claim transaction", claim.Type)
+		return NewValidationError(ErrorCodeOutput, "invalid output type %d for withdrawal claim transaction", claim.Type)
 	}
 	if claim.Amount.Cmp(NewIntegerFromString(config.WithdrawalClaimFee)) < 0 {
-		return fmt.Errorf("invalid output amount %s for withdrawal claim transaction", claim.Amount)
+		return NewValidationError(ErrorCodeAmount, "invalid output amount %s for withdrawal claim transaction", claim.Amount)
 	}
 
 	var hash crypto.Hash
 	if len(tx.Extra) != len(hash) {
-		return fmt.Errorf("invalid extra %d for withdrawal claim transaction", len(tx.Extra))
+		return NewValidationError(ErrorCodeFormat, "invalid extra %d for withdrawal claim transaction", len(tx.Extra))
 	}
 	copy(hash[:], tx.Extra)
 	submit, _, err := store.ReadTransaction(hash)
@@ -147,15 +141,19 @@ func (tx *SignedTransaction) validateWithdrawalClaim(store DataStore, inputs map
 		return err
 	}
 	if submit == nil {
-		return fmt.Errorf("invalid withdrawal submit data")
+		return NewValidationError(ErrorCodeFormat, "invalid withdrawal submit data")
 	}
 	withdrawal := submit.Outputs[0].Withdrawal
 	if withdrawal == nil || submit.Outputs[0].Type != OutputTypeWithdrawalSubmit {
-		return fmt.Errorf("invalid withdrawal submit data")
+		return NewValidationError(ErrorCodeFormat, "invalid withdrawal submit data")
 	}
 
+	domains, err := store.ReadDomains()
+	if err != nil {
+		return err
+	}
 	var domainValid bool
-	for _, d := range store.ReadDomains() {
+	for _, d := range domains {
 		domainValid = true
 		view := d.Account.PublicSpendKey.DeterministicHashDerive()
 		for _, utxo := range inputs {
@@ -178,7 +176,7 @@ func (tx *SignedTransaction) validateWithdrawalClaim(store DataStore, inputs map
 		}
 	}
 	if !domainValid {
-		return fmt.Errorf("invalid domain signature for withdrawal claim")
+		return NewValidationError(ErrorCodeSignature, "invalid domain signature for withdrawal claim")
 	}
 	return nil
 }
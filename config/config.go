@@ -19,6 +19,7 @@ const (
 	SnapshotRoundSize          = 200
 	TransactionMaximumSize     = 1024 * 1024
 	WithdrawalClaimFee         = "0.0001"
+	DomainPledgeAmount         = "50000"
 	GossipSize                 = 3
 
 	KernelMintTimeBegin = 7
@@ -39,6 +40,7 @@ type Custom struct {
 		KernelOprationPeriod int               `toml:"kernel-operation-period"`
 		MemoryCacheSize      int               `toml:"memory-cache-size"`
 		CacheTTL             int               `toml:"cache-ttl"`
+		Network              string            `toml:"network"`
 	} `toml:"node"`
 	Storage struct {
 		Truncate   bool `toml:"truncate"`
@@ -50,6 +52,7 @@ type Custom struct {
 	} `toml:"network"`
 	RPC struct {
 		Runtime bool `toml:"runtime"`
+		Admin   bool `toml:"admin"`
 	} `toml:"rpc"`
 	Dev struct {
 		Profile bool `toml:"profile"`
@@ -24,6 +24,8 @@ func TestConfig(t *testing.T) {
 	assert.Equal(700, custom.Node.KernelOprationPeriod)
 	assert.Equal(16384, custom.Node.MemoryCacheSize)
 	assert.Equal(7200, custom.Node.CacheTTL)
+	assert.Equal("XIN", custom.Node.Network)
 	assert.Equal("mixin-node.example.com:7239", custom.Network.Listener)
 	assert.Equal(false, custom.RPC.Runtime)
+	assert.Equal(false, custom.RPC.Admin)
 }
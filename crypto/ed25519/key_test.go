@@ -53,6 +53,30 @@ func TestKey(t *testing.T) {
 	assert.True(key.Public().Verify(seed, sig))
 }
 
+func TestDeterministicNonce(t *testing.T) {
+	assert := assert.New(t)
+	seed := make([]byte, 64)
+	for i := 0; i < len(seed); i++ {
+		seed[i] = byte(i + 1)
+	}
+	key, _ := PrivateKeyFromSeed(seed)
+	other := randomKey()
+	message := []byte("deterministic-nonce")
+
+	n1 := crypto.DeterministicNonce(key, message, 0)
+	n2 := crypto.DeterministicNonce(key, message, 0)
+	assert.Equal(n1.Key(), n2.Key())
+	assert.NotEqual(n1.Key(), key.Key())
+	assert.NotEqual(n1.Key(), crypto.DeterministicNonce(key, message, 1).Key())
+	assert.NotEqual(n1.Key(), crypto.DeterministicNonce(key, seed, 0).Key())
+	assert.NotEqual(n1.Key(), crypto.DeterministicNonce(other, message, 0).Key())
+
+	hReduced := key.Public().Challenge(n1.Public(), message)
+	sig, err := key.SignWithChallenge(n1, message, hReduced)
+	assert.Nil(err)
+	assert.True(key.Public().VerifyWithChallenge(message, sig, hReduced))
+}
+
 func randomKey() *Key {
 	seed := make([]byte, 64)
 	rand.Read(seed)
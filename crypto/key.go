@@ -2,7 +2,10 @@ package crypto
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
 	"database/sql/driver"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -44,6 +47,19 @@ func PrivateKeyFromSeed(seed []byte) PrivateKey {
 	}
 }
 
+// DeterministicNonce derives a signing nonce from the private key, message
+// and counter in the manner of RFC6979, so the nonce never depends on the
+// random source, and a different counter always produces a different nonce.
+func DeterministicNonce(priv PrivateKey, message []byte, counter uint64) PrivateKey {
+	key := priv.Key()
+	mac := hmac.New(sha512.New, key[:])
+	mac.Write(message)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], counter)
+	mac.Write(buf[:])
+	return PrivateKeyFromSeed(mac.Sum(nil))
+}
+
 func PrivateKeyFromString(s string) (PrivateKey, error) {
 	key, err := KeyFromString(s)
 	if err != nil {
@@ -0,0 +1,45 @@
+package bitcoin
+
+import (
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+)
+
+type domain struct{}
+
+func init() {
+	domains.Register(domain{})
+}
+
+func (domain) ChainId() crypto.Hash {
+	return BitcoinChainId
+}
+
+func (domain) VerifyAssetKey(assetKey string) error {
+	return VerifyAssetKey(assetKey)
+}
+
+func (domain) VerifyAddress(address string, fork bool) error {
+	return VerifyAddress(address, fork)
+}
+
+func (domain) VerifyTag(address, tag string, fork bool) error {
+	return VerifyTag(tag, fork)
+}
+
+func (domain) VerifyTransactionHash(hash string) error {
+	return VerifyTransactionHash(hash)
+}
+
+// the bitcoin amounts have 8 decimals, the same precision as the kernel
+func (domain) VerifyAmount(assetKey string, amount string) error {
+	return nil
+}
+
+func (domain) GenerateAssetId(assetKey string) crypto.Hash {
+	return GenerateAssetId(assetKey)
+}
+
+func (domain) FeeAssetId() crypto.Hash {
+	return BitcoinChainId
+}
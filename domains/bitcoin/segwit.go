@@ -0,0 +1,86 @@
+package bitcoin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+const (
+	bech32Charset        = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Constant       = 1
+	bech32mConstant      = 0x2bc830a3
+	segwitVersionLegacy  = 0
+	segwitVersionTaproot = 1
+)
+
+// decodeSegwitAddress decodes a lowercase native segwit address, version 0
+// programs must use the bech32 checksum and later versions must use bech32m.
+func decodeSegwitAddress(hrp, address string) (byte, []byte, error) {
+	if strings.ToLower(address) != address {
+		return 0, nil, fmt.Errorf("invalid segwit address case %s", address)
+	}
+	if len(address) > 90 {
+		return 0, nil, fmt.Errorf("invalid segwit address length %d", len(address))
+	}
+	sep := strings.LastIndexByte(address, '1')
+	if sep < 1 || address[:sep] != hrp || sep+7 > len(address) {
+		return 0, nil, fmt.Errorf("invalid segwit address format %s", address)
+	}
+
+	data := make([]byte, 0, len(address)-sep-1)
+	for _, c := range address[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return 0, nil, fmt.Errorf("invalid segwit address character %s", address)
+		}
+		data = append(data, byte(i))
+	}
+	checksum := bech32Polymod(hrp, data)
+	data = data[:len(data)-6]
+	if len(data) < 1 {
+		return 0, nil, fmt.Errorf("invalid segwit address data %s", address)
+	}
+
+	version := data[0]
+	switch {
+	case version == segwitVersionLegacy && checksum != bech32Constant:
+		return 0, nil, fmt.Errorf("invalid segwit address checksum %s", address)
+	case version != segwitVersionLegacy && checksum != bech32mConstant:
+		return 0, nil, fmt.Errorf("invalid segwit address checksum %s", address)
+	case version > 16:
+		return 0, nil, fmt.Errorf("invalid segwit address version %d", version)
+	}
+
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid segwit address program %s %s", address, err.Error())
+	}
+	return version, program, nil
+}
+
+func bech32Polymod(hrp string, data []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	values := make([]byte, 0, len(hrp)*2+1+len(data))
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
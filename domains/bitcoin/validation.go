@@ -4,10 +4,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/MixinNetwork/mixin/crypto"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
+	"github.com/gofrs/uuid"
 )
 
 const (
@@ -16,34 +18,111 @@ const (
 )
 
 var (
-	BitcoinChainId    crypto.Hash
-	BitcoinOmniUSDTId crypto.Hash
+	BitcoinChainId    = crypto.NewHash([]byte(BitcoinChainAssetKey))
+	BitcoinOmniUSDTId = crypto.NewHash([]byte(BitcoinOmniUSDTAssetKey))
+
+	rulesMutex    sync.RWMutex
+	networkParams = []*chaincfg.Params{&chaincfg.MainNetParams}
+	coloredAssets = map[string]crypto.Hash{
+		BitcoinOmniUSDTAssetKey: BitcoinOmniUSDTId,
+	}
 )
 
-func init() {
-	BitcoinChainId = crypto.NewHash([]byte(BitcoinChainAssetKey))
-	BitcoinOmniUSDTId = crypto.NewHash([]byte(BitcoinOmniUSDTAssetKey))
+// Configure sets the consensus rules from the kernel constants, the mainnet
+// kernel uses the bitcoin mainnet, other kernel networks use both the testnet
+// and regtest, which share the legacy address prefixes. The colored assets
+// replace all the previous ones besides omni usdt.
+func Configure(mainnet bool, assetKeys []string) error {
+	params := []*chaincfg.Params{&chaincfg.MainNetParams}
+	if !mainnet {
+		params = []*chaincfg.Params{&chaincfg.TestNet3Params, &chaincfg.RegressionNetParams}
+	}
+
+	assets := map[string]crypto.Hash{
+		BitcoinOmniUSDTAssetKey: BitcoinOmniUSDTId,
+	}
+	for _, k := range assetKeys {
+		id, err := uuid.FromString(k)
+		if err != nil || id.String() != k || k == BitcoinChainAssetKey {
+			return fmt.Errorf("invalid bitcoin colored asset key %s", k)
+		}
+		assets[k] = crypto.NewHash([]byte(k))
+	}
+
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	networkParams = params
+	coloredAssets = assets
+	return nil
+}
+
+func rules() ([]*chaincfg.Params, map[string]crypto.Hash) {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+	return networkParams, coloredAssets
 }
 
 func VerifyAssetKey(assetKey string) error {
-	if assetKey == BitcoinChainAssetKey || assetKey == BitcoinOmniUSDTAssetKey {
+	if assetKey == BitcoinChainAssetKey {
+		return nil
+	}
+	_, assets := rules()
+	if _, found := assets[assetKey]; found {
 		return nil
 	}
 	return fmt.Errorf("invalid bitcoin asset key %s", assetKey)
 }
 
-func VerifyAddress(address string) error {
+// VerifyAddress validates the native segwit addresses with both bech32 and
+// bech32m checksums if fork is true, i.e. since the domains fork, otherwise
+// only the addresses decoded by btcutil are valid, which has no taproot.
+func VerifyAddress(address string, fork bool) error {
+	networks, _ := rules()
 	address = strings.TrimSpace(address)
-	btcAddress, err := btcutil.DecodeAddress(address, &chaincfg.MainNetParams)
+	for _, params := range networks {
+		if fork && strings.HasPrefix(strings.ToLower(address), params.Bech32HRPSegwit+"1") {
+			return verifySegwitAddress(params, address)
+		}
+	}
+
+	params := networks[0]
+	btcAddress, err := btcutil.DecodeAddress(address, params)
 	if err != nil {
 		return fmt.Errorf("invalid bitcoin address %s %s", address, err.Error())
 	}
+	if !btcAddress.IsForNet(params) {
+		return fmt.Errorf("invalid bitcoin address %s for %s", address, params.Name)
+	}
 	if btcAddress.String() != address {
 		return fmt.Errorf("invalid bitcoin address %s %s", btcAddress.String(), address)
 	}
 	return nil
 }
 
+func verifySegwitAddress(params *chaincfg.Params, address string) error {
+	version, program, err := decodeSegwitAddress(params.Bech32HRPSegwit, address)
+	if err != nil {
+		return fmt.Errorf("invalid bitcoin address %s %s", address, err.Error())
+	}
+	switch {
+	case version == segwitVersionLegacy && len(program) == 20:
+	case version == segwitVersionLegacy && len(program) == 32:
+	case version == segwitVersionTaproot && len(program) == 32:
+	default:
+		return fmt.Errorf("invalid bitcoin address %s version %d program %d", address, version, len(program))
+	}
+	return nil
+}
+
+// VerifyTag rejects any tag since the domains fork, the tags were ignored
+// before the fork.
+func VerifyTag(tag string, fork bool) error {
+	if fork && tag != "" {
+		return fmt.Errorf("invalid bitcoin tag %s", tag)
+	}
+	return nil
+}
+
 func VerifyTransactionHash(hash string) error {
 	if len(hash) != 64 {
 		return fmt.Errorf("invalid bitcoin transaction hash %s", hash)
@@ -62,12 +141,13 @@ func VerifyTransactionHash(hash string) error {
 }
 
 func GenerateAssetId(assetKey string) crypto.Hash {
-	switch assetKey {
-	case BitcoinChainAssetKey:
+	if assetKey == BitcoinChainAssetKey {
 		return BitcoinChainId
-	case BitcoinOmniUSDTAssetKey:
-		return BitcoinOmniUSDTId
-	default:
+	}
+	_, assets := rules()
+	id, found := assets[assetKey]
+	if !found {
 		panic(assetKey)
 	}
+	return id
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,14 +28,14 @@ func TestValidation(t *testing.T) {
 	assert.NotNil(VerifyAssetKey(strings.ToUpper(btc)))
 	assert.NotNil(VerifyAssetKey(strings.ToUpper(usdt)))
 
-	assert.Nil(VerifyAddress(addrLeg))
-	assert.Nil(VerifyAddress(addrSeg))
-	assert.NotNil(VerifyAddress(btc))
-	assert.NotNil(VerifyAddress(usdt))
-	assert.NotNil(VerifyAddress(addrCash))
-	assert.NotNil(VerifyAddress(addrLeg[1:]))
-	assert.NotNil(VerifyAddress(strings.ToUpper(addrLeg)))
-	assert.NotNil(VerifyAddress(strings.ToUpper(addrCash)))
+	assert.Nil(VerifyAddress(addrLeg, true))
+	assert.Nil(VerifyAddress(addrSeg, true))
+	assert.NotNil(VerifyAddress(btc, true))
+	assert.NotNil(VerifyAddress(usdt, true))
+	assert.NotNil(VerifyAddress(addrCash, true))
+	assert.NotNil(VerifyAddress(addrLeg[1:], true))
+	assert.NotNil(VerifyAddress(strings.ToUpper(addrLeg), true))
+	assert.NotNil(VerifyAddress(strings.ToUpper(addrCash), true))
 
 	assert.Nil(VerifyTransactionHash(tx))
 	assert.NotNil(VerifyTransactionHash(btc))
@@ -47,4 +48,80 @@ func TestValidation(t *testing.T) {
 	assert.Equal(crypto.NewHash([]byte("815b0b1a-2764-3736-8faa-42d694fa620a")), GenerateAssetId(usdt))
 	assert.Equal(crypto.NewHash([]byte("c6d0c728-2624-429b-8e0d-d9d19b6592fa")), BitcoinChainId)
 	assert.Equal(crypto.NewHash([]byte("815b0b1a-2764-3736-8faa-42d694fa620a")), BitcoinOmniUSDTId)
+
+	d := domains.Get(BitcoinChainId)
+	assert.NotNil(d)
+	assert.Equal(BitcoinChainId, d.ChainId())
+	assert.Equal(BitcoinChainId, d.FeeAssetId())
+	assert.Equal(GenerateAssetId(usdt), d.GenerateAssetId(usdt))
+	assert.Nil(d.VerifyTransactionHash(tx))
+	assert.Nil(d.VerifyTag("", "", true))
+	assert.NotNil(d.VerifyTag("", "1234567", true))
+	assert.Nil(d.VerifyTag("", "", false))
+	assert.Nil(d.VerifyTag("", "1234567", false))
+}
+
+func TestNetworks(t *testing.T) {
+	assert := assert.New(t)
+	defer Configure(true, nil)
+
+	mainLeg := "1zgmvYi5x1wy3hUh7AjKgpcVgpA8Lj9FA"
+	mainWpkh := "bc1qxenlll5m5zyp778j8jd6arkn99h956zkcye93n"
+	mainWsh := "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"
+	mainTaproot := "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"
+	testLeg := "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+	testWpkh := "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+	testWsh := "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"
+	testTaproot := "tb1p8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzwst8ll9s"
+	regWpkh := "bcrt1qe2tczyk2rw7u47kzxxee5g7ufkncdmlcf5v0dv"
+	regWsh := "bcrt1q8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzwsvf4sgk"
+	regTaproot := "bcrt1p8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzwsx74es2"
+
+	assert.Nil(Configure(true, nil))
+	assert.Nil(VerifyAddress(mainLeg, true))
+	assert.Nil(VerifyAddress(mainWpkh, true))
+	assert.Nil(VerifyAddress(mainWsh, true))
+	assert.Nil(VerifyAddress(mainTaproot, true))
+	assert.Nil(VerifyAddress("bc1p8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzwsu0fsll", true))
+	assert.NotNil(VerifyAddress(strings.ToUpper(mainTaproot), true))
+	assert.NotNil(VerifyAddress("bc1p8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzwsfneu6a", true))
+	assert.NotNil(VerifyAddress("bc1qe2tczyk2rw7u47kzxxee5g7ufkncdmlc587ay5", true))
+	assert.NotNil(VerifyAddress("bc1z8c37s9sq89v55vuffajkfcd3xj9m67sq3r2zcjktw0h2a4vuqzws5jsl35", true))
+	assert.NotNil(VerifyAddress("bc1pe2tczyk2rw7u47kzxxee5g7ufkncdmlclefkfl", true))
+	assert.NotNil(VerifyAddress(testLeg, true))
+	assert.NotNil(VerifyAddress(testWpkh, true))
+	assert.NotNil(VerifyAddress(testTaproot, true))
+	assert.NotNil(VerifyAddress(regWpkh, true))
+	assert.Nil(VerifyAddress(mainLeg, false))
+	assert.Nil(VerifyAddress(mainWpkh, false))
+	assert.Nil(VerifyAddress(mainWsh, false))
+	assert.NotNil(VerifyAddress(mainTaproot, false))
+	assert.NotNil(VerifyAddress(testWpkh, false))
+
+	assert.Nil(Configure(false, nil))
+	assert.Nil(VerifyAddress(testLeg, true))
+	assert.Nil(VerifyAddress(testWpkh, true))
+	assert.Nil(VerifyAddress(testWsh, true))
+	assert.Nil(VerifyAddress(testTaproot, true))
+	assert.Nil(VerifyAddress(regWpkh, true))
+	assert.Nil(VerifyAddress(regWsh, true))
+	assert.Nil(VerifyAddress(regTaproot, true))
+	assert.Nil(VerifyAddress(testLeg, false))
+	assert.Nil(VerifyAddress(testWpkh, false))
+	assert.NotNil(VerifyAddress(regWpkh, false))
+	assert.NotNil(VerifyAddress(mainLeg, true))
+	assert.NotNil(VerifyAddress(mainWpkh, true))
+	assert.NotNil(VerifyAddress(mainTaproot, true))
+
+	colored := "31d2ea9c-95eb-3355-b65b-ba096853bc18"
+	assert.NotNil(VerifyAssetKey(colored))
+	assert.NotNil(Configure(true, []string{strings.ToUpper(colored)}))
+	assert.NotNil(Configure(true, []string{BitcoinChainAssetKey}))
+	assert.Nil(Configure(true, []string{colored}))
+	assert.Nil(VerifyAssetKey(colored))
+	assert.Nil(VerifyAssetKey(BitcoinOmniUSDTAssetKey))
+	assert.Equal(crypto.NewHash([]byte(colored)), GenerateAssetId(colored))
+	assert.Nil(Configure(true, nil))
+	assert.NotNil(VerifyAssetKey(colored))
+	assert.Nil(VerifyAssetKey(BitcoinOmniUSDTAssetKey))
 }
@@ -0,0 +1,50 @@
+package eos
+
+import (
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+)
+
+type domain struct{}
+
+func init() {
+	domains.Register(domain{})
+}
+
+func (domain) ChainId() crypto.Hash {
+	return EOSChainId
+}
+
+func (domain) VerifyAssetKey(assetKey string) error {
+	return VerifyAssetKey(assetKey)
+}
+
+// the eos domain has no rules before the domains fork, so fork is ignored
+func (domain) VerifyAddress(address string, fork bool) error {
+	return VerifyAddress(address)
+}
+
+// the required tags of the exchange accounts are only checked since the domains fork
+func (domain) VerifyTag(address, tag string, fork bool) error {
+	if !fork {
+		return VerifyTag(tag)
+	}
+	return VerifyAddressTag(address, tag)
+}
+
+func (domain) VerifyTransactionHash(hash string) error {
+	return VerifyTransactionHash(hash)
+}
+
+// the eos token decimals are unknown to the kernel, so amounts are not checked
+func (domain) VerifyAmount(assetKey string, amount string) error {
+	return nil
+}
+
+func (domain) GenerateAssetId(assetKey string) crypto.Hash {
+	return GenerateAssetId(assetKey)
+}
+
+func (domain) FeeAssetId() crypto.Hash {
+	return EOSChainId
+}
@@ -0,0 +1,138 @@
+package eos
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	EOSChainAssetKey  = "eosio.token:EOS"
+	EOSTagMaximumSize = 256
+)
+
+var (
+	EOSChainBase = "6cfe566e-4aad-470b-8c9a-2fd35b49c68d"
+	EOSChainId   = crypto.NewHash([]byte(EOSChainBase))
+
+	EOSTagRequiredAccounts = map[string]bool{
+		"binancecleos": true,
+		"bitfinexdep1": true,
+		"gateiowallet": true,
+		"huobideposit": true,
+		"krakenkraken": true,
+		"kucoinrobot1": true,
+		"okbtothemoon": true,
+	}
+)
+
+func VerifyAssetKey(assetKey string) error {
+	parts := strings.Split(assetKey, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid eos asset key %s", assetKey)
+	}
+	if err := VerifyAddress(parts[0]); err != nil {
+		return fmt.Errorf("invalid eos asset key %s %s", assetKey, err.Error())
+	}
+	symbol := parts[1]
+	if len(symbol) < 1 || len(symbol) > 7 {
+		return fmt.Errorf("invalid eos asset key %s", assetKey)
+	}
+	for _, c := range symbol {
+		if c < 'A' || c > 'Z' {
+			return fmt.Errorf("invalid eos asset key %s", assetKey)
+		}
+	}
+	return nil
+}
+
+func VerifyAddress(address string) error {
+	if len(address) < 1 || len(address) > 12 {
+		return fmt.Errorf("invalid eos address %s", address)
+	}
+	if strings.HasSuffix(address, ".") {
+		return fmt.Errorf("invalid eos address %s", address)
+	}
+	for _, c := range address {
+		if c >= 'a' && c <= 'z' || c >= '1' && c <= '5' || c == '.' {
+			continue
+		}
+		return fmt.Errorf("invalid eos address %s", address)
+	}
+	return nil
+}
+
+func VerifyTag(tag string) error {
+	if len(tag) > EOSTagMaximumSize {
+		return fmt.Errorf("invalid eos tag size %d", len(tag))
+	}
+	if !utf8.ValidString(tag) {
+		return fmt.Errorf("invalid eos tag %s", tag)
+	}
+	if strings.TrimSpace(tag) != tag {
+		return fmt.Errorf("invalid eos tag %s", tag)
+	}
+	for _, c := range tag {
+		if unicode.IsControl(c) {
+			return fmt.Errorf("invalid eos tag %s", tag)
+		}
+	}
+	return nil
+}
+
+// VerifyAddressTag also requires the tag for the exchange accounts in
+// EOSTagRequiredAccounts, which credit the deposits by the memo only.
+func VerifyAddressTag(address, tag string) error {
+	err := VerifyTag(tag)
+	if err != nil {
+		return err
+	}
+	if tag == "" && EOSTagRequiredAccounts[address] {
+		return fmt.Errorf("invalid eos tag empty for %s", address)
+	}
+	return nil
+}
+
+func VerifyTransactionHash(hash string) error {
+	if len(hash) != 64 {
+		return fmt.Errorf("invalid eos transaction hash %s", hash)
+	}
+	if strings.ToLower(hash) != hash {
+		return fmt.Errorf("invalid eos transaction hash %s", hash)
+	}
+	h, err := hex.DecodeString(hash)
+	if err != nil {
+		return fmt.Errorf("invalid eos transaction hash %s %s", hash, err.Error())
+	}
+	if len(h) != 32 {
+		return fmt.Errorf("invalid eos transaction hash %s", hash)
+	}
+	return nil
+}
+
+func GenerateAssetId(assetKey string) crypto.Hash {
+	err := VerifyAssetKey(assetKey)
+	if err != nil {
+		panic(assetKey)
+	}
+
+	if assetKey == EOSChainAssetKey {
+		return EOSChainId
+	}
+
+	h := md5.New()
+	io.WriteString(h, EOSChainBase)
+	io.WriteString(h, assetKey)
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x30
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	id := uuid.FromBytesOrNil(sum).String()
+	return crypto.NewHash([]byte(id))
+}
@@ -0,0 +1,74 @@
+package eos
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	eos := "eosio.token:EOS"
+	usdt := "tethertether:USDT"
+	tx := "c5945a8571fc84cd6850b26b5771d76311ed56957a04e993927de07b83f07c91"
+	account := "mixinxinxin1"
+
+	assert.Nil(VerifyAssetKey(eos))
+	assert.Nil(VerifyAssetKey(usdt))
+	assert.NotNil(VerifyAssetKey("eosio.token"))
+	assert.NotNil(VerifyAssetKey("eosio.token:eos"))
+	assert.NotNil(VerifyAssetKey("eosio.token:EOSEOSEOS"))
+	assert.NotNil(VerifyAssetKey("eosio.token:EOS:EOS"))
+	assert.NotNil(VerifyAssetKey(strings.ToUpper(eos)))
+	assert.NotNil(VerifyAssetKey(tx))
+
+	assert.Nil(VerifyAddress(account))
+	assert.Nil(VerifyAddress("eosio"))
+	assert.Nil(VerifyAddress("eosio.token"))
+	assert.NotNil(VerifyAddress(""))
+	assert.NotNil(VerifyAddress("eosio."))
+	assert.NotNil(VerifyAddress("mixinxinxin16"))
+	assert.NotNil(VerifyAddress("mixinxinxin6"))
+	assert.NotNil(VerifyAddress(strings.ToUpper(account)))
+	assert.NotNil(VerifyAddress(tx))
+
+	assert.Nil(VerifyTag(""))
+	assert.Nil(VerifyTag("1234567"))
+	assert.Nil(VerifyTag("memo for the deposit"))
+	assert.NotNil(VerifyTag(" 1234567"))
+	assert.NotNil(VerifyTag("1234567\n"))
+	assert.NotNil(VerifyTag("12\x0034567"))
+	assert.NotNil(VerifyTag(strings.Repeat("m", 257)))
+	assert.NotNil(VerifyTag(string([]byte{0xff, 0xfe})))
+	assert.Nil(VerifyAddressTag("eosio.token", ""))
+	assert.Nil(VerifyAddressTag("huobideposit", "1234567"))
+	assert.NotNil(VerifyAddressTag("huobideposit", ""))
+	assert.NotNil(VerifyAddressTag("huobideposit", "1234567\n"))
+
+	assert.Nil(VerifyTransactionHash(tx))
+	assert.NotNil(VerifyTransactionHash("0x" + tx))
+	assert.NotNil(VerifyTransactionHash(tx[2:]))
+	assert.NotNil(VerifyTransactionHash(strings.ToUpper(tx)))
+	assert.NotNil(VerifyTransactionHash(eos))
+
+	assert.Equal(crypto.NewHash([]byte("6cfe566e-4aad-470b-8c9a-2fd35b49c68d")), GenerateAssetId(eos))
+	assert.Equal(crypto.NewHash([]byte("6cfe566e-4aad-470b-8c9a-2fd35b49c68d")), EOSChainId)
+	assert.NotEqual(EOSChainId, GenerateAssetId(usdt))
+
+	d := domains.Get(EOSChainId)
+	assert.NotNil(d)
+	assert.Equal(EOSChainId, d.ChainId())
+	assert.Equal(EOSChainId, d.FeeAssetId())
+	assert.Equal(GenerateAssetId(usdt), d.GenerateAssetId(usdt))
+	assert.Nil(d.VerifyTag("eosio.token", "1234567", true))
+	assert.Nil(d.VerifyTag("eosio.token", "", true))
+	assert.Nil(d.VerifyTag("binancecleos", "1234567", true))
+	assert.NotNil(d.VerifyTag("binancecleos", "", true))
+	assert.Nil(d.VerifyTag("binancecleos", "", false))
+	assert.NotNil(d.VerifyTag("binancecleos", " 1234567", false))
+	assert.Nil(d.VerifyTransactionHash(tx))
+}
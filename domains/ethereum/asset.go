@@ -0,0 +1,95 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	EthereumNativeAssetKey = "0x0000000000000000000000000000000000000000"
+
+	precision = 8
+)
+
+type AssetMetadata struct {
+	Key      string
+	Symbol   string
+	Decimals int32
+}
+
+var assetMetadata = map[string]*AssetMetadata{
+	EthereumNativeAssetKey: {
+		Key:      EthereumNativeAssetKey,
+		Symbol:   "ETH",
+		Decimals: 18,
+	},
+	"0xa974c709cfb4566686553a20790685a47aceaa33": {
+		Key:      "0xa974c709cfb4566686553a20790685a47aceaa33",
+		Symbol:   "XIN",
+		Decimals: 18,
+	},
+	"0xdac17f958d2ee523a2206206994597c13d831ec7": {
+		Key:      "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		Symbol:   "USDT",
+		Decimals: 6,
+	},
+}
+
+func IsNativeAsset(assetKey string) bool {
+	return assetKey == EthereumNativeAssetKey
+}
+
+func LookupAsset(assetKey string) *AssetMetadata {
+	return assetMetadata[assetKey]
+}
+
+// VerifyAmount checks the 8 digits decimal amount is exactly representable
+// by the token decimals, the amounts of the tokens not in the metadata table
+// are not checked.
+func VerifyAmount(assetKey string, amount string) error {
+	if LookupAsset(assetKey) == nil {
+		return nil
+	}
+	_, err := ConvertAmountFromPrecision(assetKey, amount)
+	return err
+}
+
+// ConvertAmountToPrecision converts the raw token amount to a decimal string
+// with at most 8 digits after the point, the remainder is truncated.
+func ConvertAmountToPrecision(assetKey string, amount *big.Int) (string, error) {
+	meta := LookupAsset(assetKey)
+	if meta == nil {
+		return "", fmt.Errorf("invalid ethereum asset key %s", assetKey)
+	}
+	if amount.Sign() <= 0 {
+		return "", fmt.Errorf("invalid ethereum amount %s", amount)
+	}
+	d := decimal.NewFromBigInt(amount, -meta.Decimals).Truncate(precision)
+	if d.Sign() <= 0 {
+		return "", fmt.Errorf("invalid ethereum amount %s too small", amount)
+	}
+	return d.StringFixed(precision), nil
+}
+
+// ConvertAmountFromPrecision converts the 8 digits decimal amount to the raw
+// token amount, and fails when the token decimals can't represent it exactly.
+func ConvertAmountFromPrecision(assetKey string, amount string) (*big.Int, error) {
+	meta := LookupAsset(assetKey)
+	if meta == nil {
+		return nil, fmt.Errorf("invalid ethereum asset key %s", assetKey)
+	}
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ethereum amount %s %s", amount, err.Error())
+	}
+	if d.Sign() <= 0 || !d.Equal(d.Truncate(precision)) {
+		return nil, fmt.Errorf("invalid ethereum amount %s", amount)
+	}
+	raw := d.Shift(meta.Decimals)
+	if !raw.Equal(raw.Truncate(0)) {
+		return nil, fmt.Errorf("invalid ethereum amount %s for %d decimals", amount, meta.Decimals)
+	}
+	return raw.BigInt(), nil
+}
@@ -0,0 +1,59 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	xin := "0xa974c709cfb4566686553a20790685a47aceaa33"
+	usdt := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	unknown := "0x1111111111111111111111111111111111111111"
+
+	assert.True(IsNativeAsset(EthereumNativeAssetKey))
+	assert.False(IsNativeAsset(xin))
+	assert.Equal("ETH", LookupAsset(EthereumNativeAssetKey).Symbol)
+	assert.Equal("XIN", LookupAsset(xin).Symbol)
+	assert.Equal(int32(18), LookupAsset(xin).Decimals)
+	assert.Equal("USDT", LookupAsset(usdt).Symbol)
+	assert.Equal(int32(6), LookupAsset(usdt).Decimals)
+	assert.Nil(LookupAsset(unknown))
+
+	wei, _ := new(big.Int).SetString("1234567891234567891", 10)
+	amount, err := ConvertAmountToPrecision(xin, wei)
+	assert.Nil(err)
+	assert.Equal("1.23456789", amount)
+	amount, err = ConvertAmountToPrecision(usdt, big.NewInt(1234567))
+	assert.Nil(err)
+	assert.Equal("1.23456700", amount)
+	_, err = ConvertAmountToPrecision(xin, big.NewInt(1))
+	assert.NotNil(err)
+	_, err = ConvertAmountToPrecision(xin, big.NewInt(0))
+	assert.NotNil(err)
+	_, err = ConvertAmountToPrecision(unknown, wei)
+	assert.NotNil(err)
+
+	raw, err := ConvertAmountFromPrecision(xin, "1.23456789")
+	assert.Nil(err)
+	assert.Equal("1234567890000000000", raw.String())
+	raw, err = ConvertAmountFromPrecision(usdt, "1.23456700")
+	assert.Nil(err)
+	assert.Equal("1234567", raw.String())
+	_, err = ConvertAmountFromPrecision(usdt, "1.23456789")
+	assert.NotNil(err)
+	_, err = ConvertAmountFromPrecision(xin, "1.234567891")
+	assert.NotNil(err)
+	_, err = ConvertAmountFromPrecision(xin, "0")
+	assert.NotNil(err)
+	_, err = ConvertAmountFromPrecision(unknown, "1")
+	assert.NotNil(err)
+
+	assert.Nil(VerifyAmount(xin, "1.23456789"))
+	assert.Nil(VerifyAmount(usdt, "1.23456700"))
+	assert.NotNil(VerifyAmount(usdt, "1.23456789"))
+	assert.Nil(VerifyAmount(unknown, "1.23456789"))
+}
@@ -0,0 +1,44 @@
+package ethereum
+
+import (
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+)
+
+type domain struct{}
+
+func init() {
+	domains.Register(domain{})
+}
+
+func (domain) ChainId() crypto.Hash {
+	return EthereumChainId
+}
+
+func (domain) VerifyAssetKey(assetKey string) error {
+	return VerifyAssetKey(assetKey)
+}
+
+func (domain) VerifyAddress(address string, fork bool) error {
+	return VerifyAddress(address)
+}
+
+func (domain) VerifyTag(address, tag string, fork bool) error {
+	return VerifyTag(tag, fork)
+}
+
+func (domain) VerifyTransactionHash(hash string) error {
+	return VerifyTransactionHash(hash)
+}
+
+func (domain) VerifyAmount(assetKey string, amount string) error {
+	return VerifyAmount(assetKey, amount)
+}
+
+func (domain) GenerateAssetId(assetKey string) crypto.Hash {
+	return GenerateAssetId(assetKey)
+}
+
+func (domain) FeeAssetId() crypto.Hash {
+	return EthereumChainId
+}
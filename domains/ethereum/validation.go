@@ -13,14 +13,9 @@ import (
 )
 
 var (
-	EthereumChainBase string
-	EthereumChainId   crypto.Hash
-)
-
-func init() {
 	EthereumChainBase = "43d61dcd-e413-450d-80b8-101d5e903357"
-	EthereumChainId = crypto.NewHash([]byte(EthereumChainBase))
-}
+	EthereumChainId   = crypto.NewHash([]byte(EthereumChainBase))
+)
 
 func VerifyAssetKey(assetKey string) error {
 	if len(assetKey) != 42 {
@@ -46,22 +41,41 @@ func VerifyAddress(address string) error {
 	if len(address) != 42 {
 		return fmt.Errorf("invalid ethereum address %s", address)
 	}
-	if !strings.HasPrefix(address, "0x") {
-		return fmt.Errorf("invalid ethereum address %s", address)
-	}
-	form, err := formatAddress(address)
+	form, err := NormalizeAddress(address)
 	if err != nil {
-		return fmt.Errorf("invalid ethereum address %s", address)
+		return err
 	}
 	if form != address {
 		return fmt.Errorf("invalid ethereum address %s", address)
 	}
-	a, err := hex.DecodeString(address[2:])
+	return nil
+}
+
+// NormalizeAddress returns the EIP-55 checksum form of the address, all lower
+// or all upper case hex is accepted, mixed case must carry a valid checksum.
+func NormalizeAddress(address string) (string, error) {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return "", fmt.Errorf("invalid ethereum address %s", address)
+	}
+	form, err := formatAddress(address)
 	if err != nil {
-		return fmt.Errorf("invalid ethereum address %s %s", address, err.Error())
+		return "", fmt.Errorf("invalid ethereum address %s %s", address, err.Error())
 	}
-	if len(a) != 20 {
-		return fmt.Errorf("invalid ethereum address %s", address)
+	body := address[2:]
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return form, nil
+	}
+	if form != address {
+		return "", fmt.Errorf("invalid ethereum address checksum %s %s", address, form)
+	}
+	return form, nil
+}
+
+// VerifyTag rejects any tag since the domains fork, the tags were ignored
+// before the fork.
+func VerifyTag(tag string, fork bool) error {
+	if fork && tag != "" {
+		return fmt.Errorf("invalid ethereum tag %s", tag)
 	}
 	return nil
 }
@@ -92,7 +106,7 @@ func GenerateAssetId(assetKey string) crypto.Hash {
 		panic(assetKey)
 	}
 
-	if assetKey == "0x0000000000000000000000000000000000000000" {
+	if IsNativeAsset(assetKey) {
 		return EthereumChainId
 	}
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,6 +39,22 @@ func TestValidation(t *testing.T) {
 	assert.NotNil(VerifyAddress(strings.ToUpper(xin)))
 	assert.NotNil(VerifyAddress(strings.ToUpper(usdt)))
 
+	norm, err := NormalizeAddress(xin)
+	assert.Nil(err)
+	assert.Equal(xinFormat, norm)
+	norm, err = NormalizeAddress("0x" + strings.ToUpper(usdt[2:]))
+	assert.Nil(err)
+	assert.Equal(usdtFormat, norm)
+	norm, err = NormalizeAddress(usdtFormat)
+	assert.Nil(err)
+	assert.Equal(usdtFormat, norm)
+	_, err = NormalizeAddress("0xdAC17F958D2ee523a2206206994597C13D831EC7")
+	assert.NotNil(err)
+	_, err = NormalizeAddress(usdt[2:])
+	assert.NotNil(err)
+	_, err = NormalizeAddress(strings.ToUpper(usdt))
+	assert.NotNil(err)
+
 	assert.Nil(VerifyTransactionHash(tx))
 	assert.NotNil(VerifyTransactionHash(xin))
 	assert.NotNil(VerifyTransactionHash(tx[2:]))
@@ -47,4 +64,17 @@ func TestValidation(t *testing.T) {
 	assert.Equal(crypto.NewHash([]byte("4d8c508b-91c5-375b-92b0-ee702ed2dac5")), GenerateAssetId(usdt))
 	assert.Equal(crypto.NewHash([]byte("43d61dcd-e413-450d-80b8-101d5e903357")), GenerateAssetId("0x0000000000000000000000000000000000000000"))
 	assert.Equal(crypto.NewHash([]byte("43d61dcd-e413-450d-80b8-101d5e903357")), EthereumChainId)
+
+	d := domains.Get(EthereumChainId)
+	assert.NotNil(d)
+	assert.Equal(EthereumChainId, d.ChainId())
+	assert.Equal(EthereumChainId, d.FeeAssetId())
+	assert.Equal(GenerateAssetId(usdt), d.GenerateAssetId(usdt))
+	assert.Nil(d.VerifyTransactionHash(tx))
+	assert.Nil(d.VerifyTag("", "", true))
+	assert.NotNil(d.VerifyTag("", "1234567", true))
+	assert.Nil(d.VerifyTag("", "", false))
+	assert.Nil(d.VerifyTag("", "1234567", false))
+	assert.Nil(d.VerifyAmount(usdt, "1.23456700"))
+	assert.NotNil(d.VerifyAmount(usdt, "1.23456789"))
 }
@@ -0,0 +1,41 @@
+package domains
+
+import (
+	"sync"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+type Domain interface {
+	ChainId() crypto.Hash
+	VerifyAssetKey(assetKey string) error
+	VerifyAddress(address string, fork bool) error
+	VerifyTag(address, tag string, fork bool) error
+	VerifyTransactionHash(hash string) error
+	VerifyAmount(assetKey string, amount string) error
+	GenerateAssetId(assetKey string) crypto.Hash
+	FeeAssetId() crypto.Hash
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[crypto.Hash]Domain)
+)
+
+func Register(d Domain) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	id := d.ChainId()
+	if registry[id] != nil {
+		panic("domain already registered " + id.String())
+	}
+	registry[id] = d
+}
+
+func Get(chainId crypto.Hash) Domain {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	return registry[chainId]
+}
@@ -2,9 +2,8 @@ package kernel
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
+	"github.com/MixinNetwork/mixin/logger"
 )
 
 func (node *Node) Loop() error {
@@ -18,7 +17,12 @@ func (node *Node) Loop() error {
 			panic(fmt.Errorf("ListenNeighbors %s", err.Error()))
 		}
 	}()
-	go node.LoadCacheToQueue()
+	go func() {
+		err := node.LoadCacheToQueue()
+		if err != nil {
+			logger.Printf("LoadCacheToQueue ERROR %s\n", err)
+		}
+	}()
 	go node.MintLoop()
 	node.ElectionLoop()
 	return nil
@@ -37,11 +41,3 @@ func (node *Node) Teardown() {
 	node.persistStore.Close()
 	node.cacheStore.Reset()
 }
-
-func TestMockReset() {
-	clock.Reset()
-}
-
-func TestMockDiff(at time.Duration) {
-	clock.MockDiff(at)
-}
@@ -3,6 +3,7 @@ package kernel
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/MixinNetwork/mixin/common"
@@ -53,17 +54,27 @@ type Chain struct {
 	CosiVerifiers   map[crypto.Hash]*CosiVerifier
 	CachePool       *util.RingBuffer
 	CacheIndex      uint64
+	retryAction     *CosiAction
 	FinalPool       [FinalPoolSlotsLimit]*ChainRound
 	FinalIndex      int
 	FinalCount      int
 
 	persistStore     storage.Store
+	clock            Clock
 	finalActionsRing *util.RingBuffer
+	pollSupervisor   *chainSupervisor
+	finalSupervisor  *chainSupervisor
 	plc              chan struct{}
 	clc              chan struct{}
-	running          bool
+	teardown         sync.Once
+	running          int32
+	quarantined      int32
 }
 
+// BuildChain loads the chain state from the storage, but doesn't start it,
+// the chain must be started by start after it's put in the chains map and
+// the map lock released, because resuming the cosi sessions may read other
+// chains.
 func (node *Node) BuildChain(chainId crypto.Hash) *Chain {
 	chain := &Chain{
 		node:    node,
@@ -75,28 +86,53 @@ func (node *Node) BuildChain(chainId crypto.Hash) *Chain {
 		CosiVerifiers:    make(map[crypto.Hash]*CosiVerifier),
 		CachePool:        util.NewRingBuffer(CachePoolSnapshotsLimit),
 		persistStore:     node.persistStore,
+		clock:            node.clock,
 		finalActionsRing: util.NewRingBuffer(FinalPoolSlotsLimit),
+		pollSupervisor:   newChainSupervisor(ChainLoopPollSnapshots, node.clock),
+		finalSupervisor:  newChainSupervisor(ChainLoopConsumeFinalities, node.clock),
 		plc:              make(chan struct{}),
 		clc:              make(chan struct{}),
-		running:          true,
+		running:          1,
 	}
 
 	err := chain.loadState(node.networkId, node.AllNodesSorted)
 	if err != nil {
 		panic(err)
 	}
+	return chain
+}
+
+// start resumes the cosi sessions and runs the loops of the chain. If the
+// sessions fail to load, the chain is left stopped, so it's safe to be torn
+// down and started again.
+func (chain *Chain) start() error {
+	err := chain.loadCosiSessions()
+	if err != nil {
+		atomic.StoreInt32(&chain.running, 0)
+		close(chain.plc)
+		close(chain.clc)
+		return err
+	}
 
 	go chain.QueuePollSnapshots()
 	go chain.ConsumeFinalActions()
-	return chain
+	return nil
+}
+
+func (chain *Chain) isRunning() bool {
+	return atomic.LoadInt32(&chain.running) == 1
 }
 
+// Teardown stops the loops of the chain and waits until they exit, it's safe
+// to be called more than once, the later calls wait for the first one.
 func (chain *Chain) Teardown() {
-	chain.running = false
-	chain.CachePool.Dispose()
-	chain.finalActionsRing.Dispose()
-	<-chain.clc
-	<-chain.plc
+	chain.teardown.Do(func() {
+		atomic.StoreInt32(&chain.running, 0)
+		chain.CachePool.Dispose()
+		chain.finalActionsRing.Dispose()
+		<-chain.clc
+		<-chain.plc
+	})
 }
 
 func (chain *Chain) loadState(networkId crypto.Hash, allNodes []*CNode) error {
@@ -149,73 +185,107 @@ func (chain *Chain) loadState(networkId crypto.Hash, allNodes []*CNode) error {
 func (chain *Chain) QueuePollSnapshots() {
 	defer close(chain.plc)
 
-	for chain.running {
-		final, cache, stale := 0, 0, false
-		for i := 0; i < 2; i++ {
-			index := (chain.FinalIndex + i) % FinalPoolSlotsLimit
-			round := chain.FinalPool[index]
-			if round == nil {
-				logger.Debugf("QueuePollSnapshots final round empty %s %d %d\n", chain.ChainId, chain.FinalIndex, index)
-				continue
-			}
-			cr := chain.State.CacheRound
-			if cr != nil && (round.Number < cr.Number || round.Number > cr.Number+1) {
-				logger.Debugf("QueuePollSnapshots final round number bad %s %d %d %d\n", chain.ChainId, chain.FinalIndex, cr.Number, round.Number)
+	for chain.isRunning() {
+		chain.probeQuarantine()
+		if chain.isQuarantined() || !chain.pollSupervisor.ready() {
+			chain.clock.Sleep(100 * time.Millisecond)
+			continue
+		}
+		final, cache, stale, err := chain.pollSnapshots()
+		if err != nil {
+			logger.Printf("QueuePollSnapshots(%s) ERROR %s\n", chain.ChainId, err)
+			chain.supervise(chain.pollSupervisor, err)
+			continue
+		}
+		chain.pollSupervisor.recover()
+		if stale || final == 0 && cache == 0 {
+			chain.clock.Sleep(100 * time.Millisecond)
+		} else {
+			chain.clock.Sleep(1 * time.Millisecond)
+		}
+	}
+}
+
+// pollSnapshots handles the pending final and cache pool actions once, a
+// cache action failed is kept to retry, and the final pool snapshot is not
+// marked finalized so it will be retried naturally.
+func (chain *Chain) pollSnapshots() (int, int, bool, error) {
+	err := chain.node.pullMempoolBatch(chain)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	final, cache, stale := 0, 0, false
+	for i := 0; i < 2; i++ {
+		index := (chain.FinalIndex + i) % FinalPoolSlotsLimit
+		round := chain.FinalPool[index]
+		if round == nil {
+			logger.Debugf("QueuePollSnapshots final round empty %s %d %d\n", chain.ChainId, chain.FinalIndex, index)
+			continue
+		}
+		cr := chain.State.CacheRound
+		if cr != nil && (round.Number < cr.Number || round.Number > cr.Number+1) {
+			logger.Debugf("QueuePollSnapshots final round number bad %s %d %d %d\n", chain.ChainId, chain.FinalIndex, cr.Number, round.Number)
+			continue
+		}
+		if round.Timestamp > chain.node.GraphTimestamp+uint64(config.KernelNodeAcceptPeriodMaximum) {
+			stale = true
+		}
+		logger.Debugf("QueuePollSnapshots final round good %s %d %d %d\n", chain.ChainId, chain.FinalIndex, round.Number, round.Size)
+		for j := 0; j < round.Size; j++ {
+			ps := round.Snapshots[j]
+			logger.Debugf("QueuePollSnapshots final snapshot %s %d %s %t %d\n", chain.ChainId, chain.FinalIndex, ps.Snapshot.Hash, ps.finalized, len(ps.peers))
+			if ps.finalized {
 				continue
 			}
-			if round.Timestamp > chain.node.GraphTimestamp+uint64(config.KernelNodeAcceptPeriodMaximum) {
-				stale = true
-			}
-			logger.Debugf("QueuePollSnapshots final round good %s %d %d %d\n", chain.ChainId, chain.FinalIndex, round.Number, round.Size)
-			for j := 0; j < round.Size; j++ {
-				ps := round.Snapshots[j]
-				logger.Debugf("QueuePollSnapshots final snapshot %s %d %s %t %d\n", chain.ChainId, chain.FinalIndex, ps.Snapshot.Hash, ps.finalized, len(ps.peers))
-				if ps.finalized {
+			for _, pid := range ps.peers {
+				finalized, err := chain.cosiHook(&CosiAction{
+					PeerId:   pid,
+					Action:   CosiActionFinalization,
+					Snapshot: ps.Snapshot,
+				})
+				if isDroppableChainError(err) {
+					logger.Printf("QueuePollSnapshots(%s) DROP %s %s %s\n", chain.ChainId, pid, ps.Snapshot.Hash, err)
 					continue
+				} else if err != nil {
+					return final, cache, stale, err
 				}
-				for _, pid := range ps.peers {
-					finalized, err := chain.cosiHook(&CosiAction{
-						PeerId:   pid,
-						Action:   CosiActionFinalization,
-						Snapshot: ps.Snapshot,
-					})
-					if err != nil {
-						panic(err)
-					}
-					final++
-					ps.finalized = finalized
-					if ps.finalized {
-						break
-					}
-				}
-				if i != 0 {
+				final++
+				ps.finalized = finalized
+				if ps.finalized {
 					break
 				}
 			}
+			if i != 0 {
+				break
+			}
 		}
-		for i := 0; i < CachePoolSnapshotsLimit; i++ {
+	}
+	for i := 0; i < CachePoolSnapshotsLimit; i++ {
+		m := chain.retryAction
+		if m == nil {
 			item, err := chain.CachePool.Poll(false)
 			if err != nil || item == nil {
 				break
 			}
-			m := item.(*CosiAction)
-			s := m.Snapshot
-			cr := chain.State.CacheRound
-			if s != nil && cr != nil && s.RoundNumber > cr.Number+1 {
-				continue
-			}
-			_, err = chain.cosiHook(m)
-			if err != nil {
-				panic(err)
-			}
-			cache++
+			m = item.(*CosiAction)
 		}
-		if stale || final == 0 && cache == 0 {
-			time.Sleep(100 * time.Millisecond)
-		} else {
-			time.Sleep(1 * time.Millisecond)
+		chain.retryAction = nil
+		s := m.Snapshot
+		cr := chain.State.CacheRound
+		if s != nil && cr != nil && s.RoundNumber > cr.Number+1 {
+			continue
+		}
+		_, err := chain.cosiHook(m)
+		if isDroppableChainError(err) {
+			logger.Printf("QueuePollSnapshots(%s) DROP %s %d %s\n", chain.ChainId, m.PeerId, m.Action, err)
+			continue
+		} else if err != nil {
+			chain.retryAction = m
+			return final, cache, stale, err
 		}
+		cache++
 	}
+	return final, cache, stale, nil
 }
 
 func (chain *Chain) StepForward() {
@@ -226,23 +296,34 @@ func (chain *Chain) StepForward() {
 func (chain *Chain) ConsumeFinalActions() {
 	defer close(chain.clc)
 
-	for chain.running {
+	for chain.isRunning() {
 		item, err := chain.finalActionsRing.Poll(false)
 		if err != nil {
 			logger.Verbosef("ConsumeFinalActions(%s) DONE %s\n", chain.ChainId, err)
 			return
 		} else if item == nil {
-			time.Sleep(10 * time.Millisecond)
+			chain.clock.Sleep(10 * time.Millisecond)
 			continue
 		}
 		ps := item.(*CosiAction)
 		logger.Debugf("ConsumeFinalActions(%s) %s\n", chain.ChainId, ps.Snapshot.Hash)
-		for chain.running {
+		for chain.isRunning() && !chain.isQuarantined() {
+			if !chain.finalSupervisor.ready() {
+				chain.clock.Sleep(100 * time.Millisecond)
+				continue
+			}
 			retry, err := chain.appendFinalSnapshot(ps.PeerId, ps.Snapshot)
-			if err != nil {
-				panic(err)
-			} else if retry {
-				time.Sleep(1 * time.Second)
+			if isDroppableChainError(err) {
+				logger.Printf("ConsumeFinalActions(%s) DROP %s %s %s\n", chain.ChainId, ps.PeerId, ps.Snapshot.Hash, err)
+				break
+			} else if err != nil {
+				logger.Printf("ConsumeFinalActions(%s) ERROR %s\n", chain.ChainId, err)
+				chain.supervise(chain.finalSupervisor, err)
+				continue
+			}
+			chain.finalSupervisor.recover()
+			if retry {
+				chain.clock.Sleep(1 * time.Second)
 			} else {
 				break
 			}
@@ -318,6 +399,9 @@ func (chain *Chain) AppendFinalSnapshot(peerId crypto.Hash, s *common.Snapshot)
 	if cr := chain.State.CacheRound; cr != nil && cr.Number > s.RoundNumber {
 		return nil
 	}
+	if chain.isQuarantined() || !chain.isRunning() {
+		return nil
+	}
 	ps := &CosiAction{PeerId: peerId, Snapshot: s}
 	success, _ := chain.finalActionsRing.Offer(ps)
 	if !success {
@@ -367,6 +451,9 @@ func (chain *Chain) AppendCosiAction(m *CosiAction) error {
 		panic("should never be here")
 	}
 
+	if chain.isQuarantined() || !chain.isRunning() {
+		return nil
+	}
 	if s := m.Snapshot; s != nil {
 		if s.NodeId != chain.ChainId {
 			panic("should never be here")
@@ -403,14 +490,100 @@ func (node *Node) GetOrCreateChain(id crypto.Hash) *Chain {
 	}
 
 	node.chains.Lock()
-	defer node.chains.Unlock()
-
 	chain = node.chains.m[id]
 	if chain != nil {
+		node.chains.Unlock()
 		return chain
 	}
-	node.chains.m[id] = node.BuildChain(id)
-	return node.chains.m[id]
+	chain = node.BuildChain(id)
+	node.chains.m[id] = chain
+	node.chains.Unlock()
+
+	err := chain.start()
+	if err != nil {
+		logger.Printf("GetOrCreateChain(%s) start ERROR %s\n", id, err)
+	}
+	return chain
+}
+
+// StopChain stops the loops of the chain, but keeps it in the chains map so
+// its state is still readable by other chains, and all new actions to it are
+// dropped until it's started again. Stopping a stopped chain does nothing.
+func (node *Node) StopChain(id crypto.Hash) error {
+	chain := node.getChain(id)
+	if chain == nil {
+		return fmt.Errorf("chain not found %s", id)
+	}
+	chain.Teardown()
+	logger.Printf("StopChain(%s)\n", id)
+	return nil
+}
+
+// StartChain starts a stopped chain with the state reloaded from the storage,
+// it does nothing if the chain is running.
+func (node *Node) StartChain(id crypto.Hash) error {
+	chain := node.getChain(id)
+	if chain == nil {
+		return fmt.Errorf("chain not found %s", id)
+	}
+	if chain.isRunning() {
+		return nil
+	}
+	chain.Teardown()
+	replaced, err := node.replaceChain(chain)
+	if err != nil {
+		return err
+	}
+	if replaced {
+		logger.Printf("StartChain(%s)\n", id)
+	}
+	return nil
+}
+
+// RestartChain stops the chain if still running, then replaces it with a new
+// chain which reloads the state from the storage. All pending actions and the
+// quarantine state are discarded, they will be synced from peers again.
+func (node *Node) RestartChain(id crypto.Hash) error {
+	chain := node.getChain(id)
+	if chain == nil {
+		return fmt.Errorf("chain not found %s", id)
+	}
+	chain.Teardown()
+	replaced, err := node.replaceChain(chain)
+	if err != nil {
+		return err
+	}
+	if replaced {
+		logger.Printf("RestartChain(%s)\n", id)
+	}
+	return nil
+}
+
+// replaceChain builds the new chain out of the chains map lock, because it
+// reads the storage, then puts it in the map only if the old chain is still
+// there, so the concurrent starts or restarts won't replace it twice. It
+// returns false if the old chain has been replaced by others, and the start
+// error of the new chain, which is left stopped then.
+func (node *Node) replaceChain(old *Chain) (bool, error) {
+	chain := node.BuildChain(old.ChainId)
+
+	node.chains.Lock()
+	if node.chains.m[old.ChainId] != old {
+		node.chains.Unlock()
+		return false, nil
+	}
+	node.chains.m[old.ChainId] = chain
+	node.chains.Unlock()
+
+	return true, chain.start()
+}
+
+func (node *Node) ChainHealth(id crypto.Hash) (*ChainHealth, error) {
+	chain := node.getChain(id)
+	if chain == nil {
+		return nil, fmt.Errorf("chain not found %s", id)
+	}
+	return chain.Health(), nil
 }
 
 func (node *Node) getChain(id crypto.Hash) *Chain {
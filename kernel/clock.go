@@ -0,0 +1,27 @@
+package kernel
+
+import (
+	"time"
+
+	"github.com/MixinNetwork/mixin/kernel/internal/clock"
+)
+
+type Clock = clock.Clock
+
+type MockClock = clock.Mock
+
+func NewRealClock() Clock {
+	return clock.NewReal()
+}
+
+func NewMockClock(base Clock) MockClock {
+	return clock.NewMock(base)
+}
+
+func NewSkewedClock(base Clock, skew time.Duration) Clock {
+	return clock.NewSkewed(base, skew)
+}
+
+func NewSourceClock(now func() time.Time) Clock {
+	return clock.NewSource(now)
+}
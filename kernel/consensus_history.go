@@ -0,0 +1,178 @@
+package kernel
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const (
+	ConsensusEventPledge = "PLEDGE"
+	ConsensusEventCancel = "CANCEL"
+	ConsensusEventAccept = "ACCEPT"
+	ConsensusEventResign = "RESIGN"
+	ConsensusEventRemove = "REMOVE"
+)
+
+// ConsensusEvent is a membership change of the consensus nodes. The threshold
+// before is computed at the event timestamp, while the threshold and keys
+// after are computed when the change becomes effective, i.e. the node accept
+// period later, as if no other change happened in between.
+type ConsensusEvent struct {
+	Type            string
+	NodeId          crypto.Hash
+	Signer          common.Address
+	Payee           common.Address
+	Transaction     crypto.Hash
+	Snapshot        crypto.Hash
+	Timestamp       uint64
+	ThresholdBefore int
+	ThresholdAfter  int
+	Keys            []crypto.PublicKey
+}
+
+// ConsensusHistory returns all the membership changes ordered by timestamp.
+// Only the latest state of each node is stored, so the history is rebuilt
+// by walking back the node transactions from the latest state to the pledge.
+func (node *Node) ConsensusHistory() ([]*ConsensusEvent, error) {
+	var events []*ConsensusEvent
+	for _, cn := range node.AllNodesSorted {
+		nes, err := node.readNodeEvents(cn)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, nes...)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Timestamp < events[j].Timestamp {
+			return true
+		}
+		if events[i].Timestamp > events[j].Timestamp {
+			return false
+		}
+		a, b := events[i].Transaction, events[j].Transaction
+		return a.String() < b.String()
+	})
+	node.replayConsensusEvents(events)
+	return events, nil
+}
+
+func (node *Node) readNodeEvents(cn *CNode) ([]*ConsensusEvent, error) {
+	var events []*ConsensusEvent
+	for hash := cn.Transaction; ; {
+		tx, snap, err := node.persistStore.ReadTransaction(hash)
+		if err != nil {
+			return nil, err
+		}
+		if tx == nil {
+			return nil, fmt.Errorf("node %s transaction %s not found", cn.IdForNetwork, hash)
+		}
+		sh, err := crypto.HashFromString(snap)
+		if err != nil {
+			return nil, err
+		}
+		s, err := node.persistStore.ReadSnapshot(sh)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, fmt.Errorf("node %s snapshot %s not found", cn.IdForNetwork, snap)
+		}
+
+		e := &ConsensusEvent{
+			NodeId:      cn.IdForNetwork,
+			Signer:      cn.Signer,
+			Payee:       cn.Payee,
+			Transaction: hash,
+			Snapshot:    sh,
+			Timestamp:   s.Timestamp,
+		}
+		e.Type = consensusEventType(tx)
+		if e.Type == "" {
+			return nil, fmt.Errorf("node %s invalid transaction %s type %d", cn.IdForNetwork, hash, tx.TransactionType())
+		}
+		events = append(events, e)
+
+		if e.Type == ConsensusEventPledge || len(tx.Inputs) != 1 || tx.Inputs[0].Genesis != nil {
+			return events, nil
+		}
+		hash = tx.Inputs[0].Hash
+	}
+}
+
+// consensusEventType checks the output types, the same as the node states are
+// written, because the genesis transactions have no valid transaction type.
+func consensusEventType(tx *common.VersionedTransaction) string {
+	for _, out := range tx.Outputs {
+		switch out.Type {
+		case common.OutputTypeNodePledge:
+			return ConsensusEventPledge
+		case common.OutputTypeNodeCancel:
+			return ConsensusEventCancel
+		case common.OutputTypeNodeAccept:
+			return ConsensusEventAccept
+		case common.OutputTypeNodeResign:
+			return ConsensusEventResign
+		case common.OutputTypeNodeRemove:
+			return ConsensusEventRemove
+		}
+	}
+	return ""
+}
+
+func (node *Node) replayConsensusEvents(events []*ConsensusEvent) {
+	nodes := make(map[crypto.Hash]*CNode)
+	sorted := func() []*CNode {
+		list := make([]*CNode, 0, len(nodes))
+		for _, cn := range nodes {
+			c := *cn
+			list = append(list, &c)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Timestamp < list[j].Timestamp {
+				return true
+			}
+			if list[i].Timestamp > list[j].Timestamp {
+				return false
+			}
+			return list[i].IdForNetwork.String() < list[j].IdForNetwork.String()
+		})
+		return list
+	}
+
+	for _, e := range events {
+		e.ThresholdBefore = node.consensusThreshold(sorted(), e.Timestamp)
+
+		cn := nodes[e.NodeId]
+		if cn == nil {
+			cn = &CNode{
+				IdForNetwork: e.NodeId,
+				Signer:       e.Signer,
+				Payee:        e.Payee,
+			}
+			nodes[e.NodeId] = cn
+		}
+		cn.Transaction = e.Transaction
+		cn.Timestamp = e.Timestamp
+		switch e.Type {
+		case ConsensusEventPledge:
+			cn.State = common.NodeStatePledging
+		case ConsensusEventCancel:
+			cn.State = common.NodeStateCancelled
+		case ConsensusEventAccept:
+			cn.State = common.NodeStateAccepted
+		case ConsensusEventResign:
+			cn.State = common.NodeStateResigning
+		case ConsensusEventRemove:
+			cn.State = common.NodeStateRemoved
+		}
+
+		after := sorted()
+		effective := e.Timestamp + uint64(config.KernelNodeAcceptPeriodMinimum) + 1
+		e.ThresholdAfter = node.consensusThreshold(after, effective)
+		e.Keys = node.consensusKeys(after, effective)
+	}
+}
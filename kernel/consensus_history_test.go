@@ -0,0 +1,71 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsensusHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-consensus-history-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	events, err := node.ConsensusHistory()
+	assert.Nil(err)
+	assert.Len(events, len(node.genesisNodes))
+	for _, e := range events {
+		assert.Equal(ConsensusEventAccept, e.Type)
+		assert.Equal(node.Epoch, e.Timestamp)
+		assert.True(node.genesisNodesMap[e.NodeId])
+	}
+	last := events[len(events)-1]
+	assert.Len(last.Keys, len(node.genesisNodes))
+	assert.Equal(node.ConsensusThreshold(node.Epoch+1), last.ThresholdAfter)
+	assert.Equal(1000, events[0].ThresholdBefore)
+
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	signer := common.NewAddressFromSeed(seed)
+	id := signer.Hash().ForNetwork(node.networkId)
+	period := uint64(config.KernelNodeAcceptPeriodMinimum)
+	pledge := node.Epoch + period
+	accept := pledge + period
+	remove := accept + period*2
+	history := append(events, []*ConsensusEvent{
+		{Type: ConsensusEventPledge, NodeId: id, Signer: signer, Transaction: crypto.NewHash([]byte("pledge")), Timestamp: pledge},
+		{Type: ConsensusEventAccept, NodeId: id, Signer: signer, Transaction: crypto.NewHash([]byte("accept")), Timestamp: accept},
+		{Type: ConsensusEventRemove, NodeId: id, Signer: signer, Transaction: crypto.NewHash([]byte("remove")), Timestamp: remove},
+	}...)
+	node.replayConsensusEvents(history)
+
+	count := len(node.genesisNodes)
+	e := history[count]
+	assert.Equal(count*2/3+1, e.ThresholdBefore)
+	assert.Equal((count+1)*2/3+1, e.ThresholdAfter)
+	assert.Len(e.Keys, count)
+	e = history[count+1]
+	assert.Equal((count+1)*2/3+1, e.ThresholdBefore)
+	assert.Equal((count+1)*2/3+1, e.ThresholdAfter)
+	assert.Len(e.Keys, count+1)
+	e = history[count+2]
+	assert.Equal((count+1)*2/3+1, e.ThresholdBefore)
+	assert.Equal(count*2/3+1, e.ThresholdAfter)
+	assert.Len(e.Keys, count)
+	for _, k := range e.Keys {
+		assert.NotEqual(signer.PublicSpendKey.Key(), k.Key())
+	}
+}
@@ -1,14 +1,12 @@
 package kernel
 
 import (
-	"crypto/rand"
 	"fmt"
 	"time"
 
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
 	"github.com/MixinNetwork/mixin/logger"
 )
 
@@ -45,12 +43,14 @@ type CosiAggregator struct {
 }
 
 type CosiVerifier struct {
-	Snapshot *common.Snapshot
-	random   crypto.PrivateKey
+	Snapshot  *common.Snapshot
+	random    crypto.PrivateKey
+	counter   uint64
+	challenge *crypto.Hash
 }
 
 func (chain *Chain) cosiHook(m *CosiAction) (bool, error) {
-	if !chain.running {
+	if !chain.isRunning() {
 		return false, nil
 	}
 	err := chain.cosiHandleAction(m)
@@ -60,6 +60,12 @@ func (chain *Chain) cosiHook(m *CosiAction) (bool, error) {
 	if m.Action != CosiActionFinalization {
 		return false, nil
 	}
+	if m.finalized {
+		err := chain.removeCosiSession(m.Snapshot.Hash)
+		if err != nil {
+			return false, err
+		}
+	}
 	if m.finalized || !m.WantTx || m.PeerId == chain.node.IdForNetwork {
 		return m.finalized, nil
 	}
@@ -89,14 +95,21 @@ func (chain *Chain) cosiHandleAction(m *CosiAction) error {
 
 func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 	logger.Verbosef("CosiLoop cosiHandleAction cosiSendAnnouncement %v\n", m.Snapshot)
-	s := m.Snapshot
-	if s.Version != common.SnapshotVersion || s.Signature != nil || s.Timestamp != 0 {
+	if m.Snapshot.Version != common.SnapshotVersion || m.Snapshot.Signature != nil || m.Snapshot.Timestamp != 0 {
 		return nil
 	}
-	if !chain.node.CheckCatchUpWithPeers() && !chain.node.checkInitialAcceptSnapshotWeak(m.Snapshot) {
+	// the action keeps the empty snapshot, so it's announced again with a new
+	// timestamp when the action is retried after an error
+	snap := *m.Snapshot
+	s := &snap
+	if !chain.node.CheckCatchUpWithPeers() && !chain.node.checkInitialAcceptSnapshotWeak(s) {
 		logger.Verbosef("CosiLoop cosiHandleAction cosiSendAnnouncement CheckCatchUpWithPeers\n")
 		return nil
 	}
+	if cs := chain.node.ClockSkew(); cs.Skewed {
+		logger.Verbosef("CosiLoop cosiHandleAction cosiSendAnnouncement ClockSkew %s %d\n", cs.Offset, cs.Peers)
+		return chain.node.requeueSnapshotTransaction(s)
+	}
 
 	tx, finalized, err := chain.node.checkCacheSnapshotTransaction(s)
 	if err != nil || finalized || tx == nil {
@@ -114,14 +127,21 @@ func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 	}
 
 	if chain.node.checkInitialAcceptSnapshot(s, tx) {
-		s.Timestamp = uint64(clock.Now().UnixNano())
+		s.Timestamp = uint64(chain.clock.Now().UnixNano())
 		s.Hash = s.PayloadHash()
-		v := &CosiVerifier{Snapshot: s, random: crypto.NewPrivateKey(rand.Reader)}
+		v, err := chain.newCosiVerifier(s)
+		if err != nil {
+			return err
+		}
 		R := crypto.Commitment(v.random.Public().Key())
 		chain.CosiVerifiers[s.Hash] = v
 		agg.Commitments[len(chain.node.SortedConsensusNodes)] = &R
 		agg.responsed[chain.node.IdForNetwork] = true
 		chain.CosiAggregators[s.Hash] = agg
+		err = chain.checkpointCosiSession(v)
+		if err != nil {
+			return err
+		}
 		for peerId := range chain.node.ConsensusNodes {
 			err := chain.node.Peer.SendSnapshotAnnouncementMessage(peerId, s, R)
 			if err != nil {
@@ -142,11 +162,11 @@ func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 		return chain.clearAndQueueSnapshotOrPanic(s)
 	}
 	for {
-		s.Timestamp = uint64(clock.Now().UnixNano())
+		s.Timestamp = uint64(chain.clock.Now().UnixNano())
 		if s.Timestamp > cache.Timestamp {
 			break
 		}
-		time.Sleep(100 * time.Millisecond)
+		chain.clock.Sleep(100 * time.Millisecond)
 	}
 
 	if len(cache.Snapshots) == 0 {
@@ -171,7 +191,7 @@ func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 			}
 			err = chain.persistStore.UpdateEmptyHeadRound(cache.NodeId, cache.Number, cache.References)
 			if err != nil {
-				panic(err)
+				return err
 			}
 			chain.assignNewGraphRound(final, cache)
 			return chain.clearAndQueueSnapshotOrPanic(s)
@@ -195,11 +215,11 @@ func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 				External: best.Hash,
 			},
 		}
-		chain.assignNewGraphRound(final, cache)
 		err := chain.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, final.Start)
 		if err != nil {
-			panic(err)
+			return err
 		}
+		chain.assignNewGraphRound(final, cache)
 	}
 	cache.Timestamp = s.Timestamp
 
@@ -210,14 +230,21 @@ func (chain *Chain) cosiSendAnnouncement(m *CosiAction) error {
 	s.RoundNumber = cache.Number
 	s.References = cache.References
 	s.Hash = s.PayloadHash()
-	v := &CosiVerifier{Snapshot: s, random: crypto.NewPrivateKey(rand.Reader)}
+	v, err := chain.newCosiVerifier(s)
+	if err != nil {
+		return err
+	}
 	R := crypto.Commitment(v.random.Public().Key())
 	chain.CosiVerifiers[s.Hash] = v
 	agg.Commitments[chain.node.ConsensusIndex] = &R
 	agg.responsed[chain.node.IdForNetwork] = true
 	chain.CosiAggregators[s.Hash] = agg
+	err = chain.checkpointCosiSession(v)
+	if err != nil {
+		return err
+	}
 	for peerId := range chain.node.ConsensusNodes {
-		err := chain.node.Peer.SendSnapshotAnnouncementMessage(peerId, m.Snapshot, R)
+		err := chain.node.Peer.SendSnapshotAnnouncementMessage(peerId, s, R)
 		if err != nil {
 			logger.Verbosef("CosiLoop cosiHandleAction cosiSendAnnouncement SendSnapshotAnnouncementMessage(%s, %s) ERROR %s\n", peerId, s.Hash, err.Error())
 		}
@@ -244,7 +271,7 @@ func (chain *Chain) cosiHandleAnnouncement(m *CosiAction) error {
 		return nil
 	}
 	threshold := config.SnapshotRoundGap * config.SnapshotReferenceThreshold
-	if s.Timestamp > uint64(clock.Now().UnixNano())+threshold {
+	if s.Timestamp > uint64(chain.clock.Now().UnixNano())+threshold {
 		return nil
 	}
 	if s.Timestamp+threshold*2 < chain.node.GraphTimestamp {
@@ -256,10 +283,17 @@ func (chain *Chain) cosiHandleAnnouncement(m *CosiAction) error {
 		return nil
 	}
 
-	v := &CosiVerifier{Snapshot: s, random: crypto.NewPrivateKey(rand.Reader)}
 	if chain.node.checkInitialAcceptSnapshotWeak(s) {
+		v, err := chain.newCosiVerifier(s)
+		if err != nil {
+			return err
+		}
 		chain.CosiVerifiers[s.Hash] = v
-		err := chain.node.Peer.SendSnapshotCommitmentMessage(s.NodeId, s.Hash, v.random.Public().Key(), tx == nil)
+		err = chain.checkpointCosiSession(v)
+		if err != nil {
+			return err
+		}
+		err = chain.node.Peer.SendSnapshotCommitmentMessage(s.NodeId, s.Hash, v.random.Public().Key(), tx == nil)
 		if err != nil {
 			logger.Verbosef("CosiLoop cosiHandleAction cosiHandleAnnouncement SendSnapshotCommitmentMessage(%s, %s) ERROR %s\n", s.NodeId, s.Hash, err.Error())
 		}
@@ -293,7 +327,7 @@ func (chain *Chain) cosiHandleAnnouncement(m *CosiAction) error {
 		}
 		err = chain.persistStore.UpdateEmptyHeadRound(cache.NodeId, cache.Number, cache.References)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		chain.assignNewGraphRound(final, cache)
 		return chain.queueActionOrPanic(m)
@@ -316,7 +350,7 @@ func (chain *Chain) cosiHandleAnnouncement(m *CosiAction) error {
 		}
 		err = chain.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, final.Start)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
 
@@ -325,7 +359,15 @@ func (chain *Chain) cosiHandleAnnouncement(m *CosiAction) error {
 		return nil
 	}
 
+	v, err := chain.newCosiVerifier(s)
+	if err != nil {
+		return err
+	}
 	chain.CosiVerifiers[s.Hash] = v
+	err = chain.checkpointCosiSession(v)
+	if err != nil {
+		return err
+	}
 	err = chain.node.Peer.SendSnapshotCommitmentMessage(s.NodeId, s.Hash, v.random.Public().Key(), tx == nil)
 	if err != nil {
 		logger.Verbosef("CosiLoop cosiHandleAction cosiHandleAnnouncement SendSnapshotCommitmentMessage(%s, %s) ERROR %s\n", s.NodeId, s.Hash, err.Error())
@@ -344,6 +386,10 @@ func (chain *Chain) cosiHandleCommitment(m *CosiAction) error {
 	if ann == nil || ann.Snapshot.Hash != m.SnapshotHash {
 		return nil
 	}
+	v := chain.CosiVerifiers[m.SnapshotHash]
+	if v == nil {
+		return nil
+	}
 	if ann.committed[m.PeerId] {
 		return nil
 	}
@@ -367,6 +413,10 @@ func (chain *Chain) cosiHandleCommitment(m *CosiAction) error {
 			break
 		}
 	}
+	err := chain.checkpointCosiSession(v)
+	if err != nil {
+		return err
+	}
 	if len(ann.Commitments) < base {
 		return nil
 	}
@@ -381,7 +431,6 @@ func (chain *Chain) cosiHandleCommitment(m *CosiAction) error {
 		return err
 	}
 	ann.Snapshot.Signature = cosi
-	v := chain.CosiVerifiers[m.SnapshotHash]
 	priv := chain.node.Signer.PrivateSpendKey
 	publics := chain.node.ConsensusKeys(ann.Snapshot.Timestamp)
 	if chain.node.checkInitialAcceptSnapshot(ann.Snapshot, tx) {
@@ -391,6 +440,9 @@ func (chain *Chain) cosiHandleCommitment(m *CosiAction) error {
 	if err != nil {
 		return err
 	}
+	if valid, err := chain.checkpointCosiChallenge(v, challenge); err != nil || !valid {
+		return err
+	}
 
 	signature, err := priv.SignWithChallenge(v.random, m.SnapshotHash[:], challenge)
 	if err != nil {
@@ -402,6 +454,10 @@ func (chain *Chain) cosiHandleCommitment(m *CosiAction) error {
 	} else {
 		cosi.AggregateSignature(chain.node.ConsensusIndex, signature)
 	}
+	err = chain.checkpointCosiSession(v)
+	if err != nil {
+		return err
+	}
 	for id := range chain.node.ConsensusNodes {
 		if wantTx, found := ann.WantTxs[id]; !found {
 			continue
@@ -433,6 +489,9 @@ func (chain *Chain) cosiHandleChallenge(m *CosiAction) error {
 	}
 
 	if m.Transaction != nil {
+		if m.Transaction.ValidateStamp(chain.node.stampDifficulty(v.Snapshot)) != nil {
+			return nil
+		}
 		err := chain.node.CachePutTransaction(m.PeerId, m.Transaction)
 		if err != nil {
 			return err
@@ -441,7 +500,7 @@ func (chain *Chain) cosiHandleChallenge(m *CosiAction) error {
 
 	s := v.Snapshot
 	threshold := config.SnapshotRoundGap * config.SnapshotReferenceThreshold
-	if s.Timestamp > uint64(clock.Now().UnixNano())+threshold {
+	if s.Timestamp > uint64(chain.clock.Now().UnixNano())+threshold {
 		return nil
 	}
 	if s.Timestamp+threshold*2 < chain.node.GraphTimestamp {
@@ -475,6 +534,9 @@ func (chain *Chain) cosiHandleChallenge(m *CosiAction) error {
 			return nil
 		}
 	}
+	if valid, err := chain.checkpointCosiChallenge(v, challenge); err != nil || !valid {
+		return err
+	}
 	sig, err := chain.node.Signer.PrivateSpendKey.SignWithChallenge(v.random, m.SnapshotHash[:], challenge)
 	if err != nil {
 		return err
@@ -497,14 +559,16 @@ func (chain *Chain) cosiHandleResponse(m *CosiAction) error {
 	if agg == nil || agg.Snapshot.Hash != m.SnapshotHash {
 		return nil
 	}
-	if agg.responsed[m.PeerId] {
+	// the last response is handled again if the snapshot failed to be written,
+	// all other responses handled are ignored
+	if agg.responsed[m.PeerId] && len(agg.responsed) < len(agg.Commitments) {
 		return nil
 	}
 	if !chain.node.CheckCatchUpWithPeers() && !chain.node.checkInitialAcceptSnapshotWeak(agg.Snapshot) {
 		logger.Verbosef("CosiLoop cosiHandleAction cosiHandleResponse CheckCatchUpWithPeers\n")
 		return nil
 	}
-	if len(agg.responsed) >= len(agg.Commitments) {
+	if !agg.responsed[m.PeerId] && len(agg.responsed) >= len(agg.Commitments) {
 		return nil
 	}
 	base := chain.node.ConsensusThreshold(agg.Snapshot.Timestamp)
@@ -518,17 +582,23 @@ func (chain *Chain) cosiHandleResponse(m *CosiAction) error {
 		return nil
 	}
 
-	for i, id := range chain.node.SortedConsensusNodes {
-		if id == m.PeerId {
-			commitment := agg.Commitments[i]
-			sig := agg.Snapshot.Signature.LoadResponseSignature(commitment, m.Response)
-			if err := agg.Snapshot.Signature.AggregateSignature(i, sig); err != nil {
-				return err
+	if !agg.responsed[m.PeerId] {
+		for i, id := range chain.node.SortedConsensusNodes {
+			if id == m.PeerId {
+				commitment := agg.Commitments[i]
+				sig := agg.Snapshot.Signature.LoadResponseSignature(commitment, m.Response)
+				if err := agg.Snapshot.Signature.AggregateSignature(i, sig); err != nil {
+					return err
+				}
+				break
 			}
-			break
+		}
+		agg.responsed[m.PeerId] = true
+		err = chain.checkpointCosiSession(chain.CosiVerifiers[m.SnapshotHash])
+		if err != nil {
+			return err
 		}
 	}
-	agg.responsed[m.PeerId] = true
 	if len(agg.responsed) != len(agg.Commitments) {
 		return nil
 	}
@@ -571,7 +641,14 @@ func (chain *Chain) cosiHandleResponse(m *CosiAction) error {
 		return chain.clearAndQueueSnapshotOrPanic(s)
 	}
 
-	chain.node.TopoWrite(s)
+	if _, err := chain.node.TopoWrite(s); err != nil {
+		logger.Printf("CosiLoop cosiHandleAction cosiHandleResponse TopoWrite(%s) ERROR %s\n", s.Hash, err.Error())
+		return err
+	}
+	err = chain.removeCosiSession(s.Hash)
+	if err != nil {
+		return err
+	}
 	if err := cache.ValidateSnapshot(s, true); err != nil {
 		panic("should never be here")
 	}
@@ -625,7 +702,7 @@ func (chain *Chain) cosiHandleFinalization(m *CosiAction) error {
 		}
 		err = chain.persistStore.UpdateEmptyHeadRound(cache.NodeId, cache.Number, cache.References)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		chain.assignNewGraphRound(final, cache)
 		return nil
@@ -647,7 +724,7 @@ func (chain *Chain) cosiHandleFinalization(m *CosiAction) error {
 		}
 		err := chain.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, final.Start)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
 
@@ -656,7 +733,9 @@ func (chain *Chain) cosiHandleFinalization(m *CosiAction) error {
 		logger.Verbosef("ERROR cosiHandleFinalization ValidateSnapshot %s %v %s\n", m.PeerId, s, err.Error())
 		return nil
 	}
-	chain.node.TopoWrite(s)
+	if _, err := chain.node.TopoWrite(s); err != nil {
+		return err
+	}
 	if err := cache.ValidateSnapshot(s, true); err != nil {
 		panic("should never be here")
 	}
@@ -705,7 +784,7 @@ func (chain *Chain) handleFinalization(m *CosiAction) error {
 		return nil
 	}
 	if s.RoundNumber == 0 && tx.TransactionType() != common.TransactionTypeNodeAccept {
-		return fmt.Errorf("invalid initial transaction type %d", tx.TransactionType())
+		return common.NewValidationError(common.ErrorCodeType, "invalid initial transaction type %d", tx.TransactionType())
 	}
 
 	m.Transaction = tx
@@ -727,6 +806,7 @@ func (node *Node) CosiQueueExternalAnnouncement(peerId crypto.Hash, s *common.Sn
 	if s.Signature != nil || s.Timestamp == 0 || commitment == nil {
 		return nil
 	}
+	node.sampleClockSkew(peerId, s.Timestamp)
 	s.Hash = s.PayloadHash()
 	s.Commitment = commitment
 
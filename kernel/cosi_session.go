@@ -0,0 +1,203 @@
+package kernel
+
+import (
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/logger"
+)
+
+// cosiSession is the checkpoint of a cosi verifier, and the aggregator too
+// if the node leads the snapshot. The nonce is not stored, it's derived from
+// the signer key, snapshot hash and the session counter again on resume.
+type cosiSession struct {
+	Snapshot      *common.Snapshot
+	Hash          crypto.Hash
+	Commitment    *crypto.Commitment
+	Counter       uint64
+	Challenge     *crypto.Hash
+	Leader        bool
+	Commitments   map[int]*crypto.Commitment
+	WantTxs       map[crypto.Hash]bool
+	Committed     []crypto.Hash
+	Responsed     []crypto.Hash
+	SignatureMask uint64
+}
+
+// newCosiVerifier derives the commitment nonce deterministically from the
+// signer key, the snapshot payload hash and a new session counter, so the
+// nonce never relies on the random source, and the persisted counter makes
+// sure a restarted session of the same snapshot never reuses a nonce.
+func (chain *Chain) newCosiVerifier(s *common.Snapshot) (*CosiVerifier, error) {
+	counter, err := chain.persistStore.NextCosiNonce(chain.ChainId, s.Hash)
+	if err != nil {
+		return nil, err
+	}
+	random := chain.cosiNonce(s.Hash, counter)
+	return &CosiVerifier{Snapshot: s, random: random, counter: counter}, nil
+}
+
+func (chain *Chain) cosiNonce(hash crypto.Hash, counter uint64) crypto.PrivateKey {
+	priv := chain.node.Signer.PrivateSpendKey
+	return crypto.DeterministicNonce(priv, hash[:], counter)
+}
+
+func (chain *Chain) checkpointCosiSession(v *CosiVerifier) error {
+	s := v.Snapshot
+	session := &cosiSession{
+		Snapshot:   s,
+		Hash:       s.Hash,
+		Commitment: s.Commitment,
+		Counter:    v.counter,
+		Challenge:  v.challenge,
+	}
+	if agg := chain.CosiAggregators[s.Hash]; agg != nil {
+		session.Leader = true
+		session.Commitments = agg.Commitments
+		session.WantTxs = agg.WantTxs
+		for id := range agg.committed {
+			session.Committed = append(session.Committed, id)
+		}
+		for id := range agg.responsed {
+			session.Responsed = append(session.Responsed, id)
+		}
+		if sig := agg.Snapshot.Signature; sig != nil {
+			session.SignatureMask = sig.SignatureMask
+		}
+	}
+	data := common.MsgpackMarshalPanic(session)
+	return chain.persistStore.CachePutCosiSession(chain.ChainId, s.Hash, data)
+}
+
+// checkpointCosiChallenge must be called before signing the challenge with
+// the verifier nonce, it refuses a different challenge for the same nonce,
+// and the usage is persisted so it's refused even after the session is gone.
+func (chain *Chain) checkpointCosiChallenge(v *CosiVerifier, challenge [32]byte) (bool, error) {
+	h := crypto.Hash(challenge)
+	valid, err := chain.persistStore.CheckCosiNonce(chain.ChainId, v.Snapshot.Hash, v.counter, h)
+	if err != nil {
+		return false, err
+	}
+	if !valid {
+		logger.Printf("COSI NONCE REUSE REFUSED %s %d %s\n", v.Snapshot.Hash, v.counter, h)
+		return false, nil
+	}
+	if v.challenge != nil {
+		return true, nil
+	}
+	v.challenge = &h
+	return true, chain.checkpointCosiSession(v)
+}
+
+func (chain *Chain) removeCosiSession(hash crypto.Hash) error {
+	return chain.persistStore.CacheRemoveCosiSession(chain.ChainId, hash)
+}
+
+// loadCosiSessions resumes the cosi sessions after restart, a session is
+// aborted if its snapshot is finalized or fails the validation, or if the
+// node leads it but the snapshot doesn't fit the cache round anymore. Only
+// the storage errors are returned.
+func (chain *Chain) loadCosiSessions() error {
+	var sessions []*cosiSession
+	err := chain.persistStore.CacheListCosiSessions(chain.ChainId, func(hash crypto.Hash, data []byte) error {
+		var session cosiSession
+		err := common.MsgpackUnmarshal(data, &session)
+		if err != nil || session.Snapshot == nil || session.Hash != hash {
+			logger.Printf("loadCosiSessions(%s) malformed session %s %v\n", chain.ChainId, hash, err)
+			return chain.removeCosiSession(hash)
+		}
+		sessions = append(sessions, &session)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		resumed, err := chain.resumeCosiSession(session)
+		if err != nil {
+			return err
+		}
+		logger.Printf("loadCosiSessions(%s) %s %t %t\n", chain.ChainId, session.Hash, session.Leader, resumed)
+		if !resumed {
+			err = chain.removeCosiSession(session.Hash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (chain *Chain) resumeCosiSession(session *cosiSession) (bool, error) {
+	s := session.Snapshot
+	s.Hash = s.PayloadHash()
+	s.Commitment = session.Commitment
+	if s.Hash != session.Hash || s.NodeId != chain.ChainId {
+		return false, nil
+	}
+	tx, finalized, err := chain.persistStore.ReadTransaction(s.Transaction)
+	if err != nil || len(finalized) > 0 {
+		return false, err
+	}
+	if tx == nil {
+		tx, err = chain.persistStore.CacheGetTransaction(s.Transaction)
+		if err != nil {
+			return false, err
+		}
+	}
+	if tx != nil {
+		_, _, err = chain.node.checkCacheSnapshotTransaction(s)
+	}
+	if err != nil {
+		logger.Printf("resumeCosiSession(%s) INVALID %s %s\n", chain.ChainId, s.Hash, err.Error())
+		return false, nil
+	}
+
+	random := chain.cosiNonce(s.Hash, session.Counter)
+	v := &CosiVerifier{Snapshot: s, random: random, counter: session.Counter, challenge: session.Challenge}
+	if !session.Leader {
+		chain.CosiVerifiers[s.Hash] = v
+		return true, nil
+	}
+
+	if tx == nil || chain.ChainId != chain.node.IdForNetwork {
+		return false, nil
+	}
+	initial := chain.State.FinalRound == nil && tx.TransactionType() == common.TransactionTypeNodeAccept
+	if !initial || !chain.node.checkInitialAcceptSnapshotWeak(s) {
+		cache := chain.State.CacheRound
+		if cache == nil || s.RoundNumber != cache.Number || !s.References.Equal(cache.References) {
+			return false, nil
+		}
+		if err := cache.ValidateSnapshot(s, false); err != nil {
+			return false, nil
+		}
+	}
+	agg := &CosiAggregator{
+		Snapshot:    s,
+		Transaction: tx,
+		WantTxs:     session.WantTxs,
+		Commitments: session.Commitments,
+		Responses:   make(map[int]*crypto.Response),
+		committed:   make(map[crypto.Hash]bool),
+		responsed:   make(map[crypto.Hash]bool),
+	}
+	if agg.WantTxs == nil {
+		agg.WantTxs = make(map[crypto.Hash]bool)
+	}
+	if agg.Commitments == nil {
+		agg.Commitments = make(map[int]*crypto.Commitment)
+	}
+	for _, id := range session.Committed {
+		agg.committed[id] = true
+	}
+	for _, id := range session.Responsed {
+		agg.responsed[id] = true
+	}
+	if s.Signature != nil {
+		s.Signature.SignatureMask = session.SignatureMask
+	}
+	chain.CosiVerifiers[s.Hash] = v
+	chain.CosiAggregators[s.Hash] = agg
+	return true, nil
+}
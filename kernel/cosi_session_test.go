@@ -0,0 +1,162 @@
+package kernel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosiSession(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-cosi-session-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	chain := node.GetOrCreateChain(node.genesisNodes[0])
+	s := &common.Snapshot{
+		Version:     common.SnapshotVersion,
+		NodeId:      chain.ChainId,
+		Transaction: crypto.NewHash([]byte("cosi-session-test")),
+		RoundNumber: 1,
+		Timestamp:   node.Epoch + 1,
+	}
+	s.Hash = s.PayloadHash()
+	v, err := chain.newCosiVerifier(s)
+	assert.Nil(err)
+	assert.Equal(uint64(0), v.counter)
+	assert.Equal(chain.cosiNonce(s.Hash, 0).Key(), v.random.Key())
+	chain.CosiVerifiers[s.Hash] = v
+	err = chain.checkpointCosiSession(v)
+	assert.Nil(err)
+
+	var stored [][]byte
+	err = node.persistStore.CacheListCosiSessions(chain.ChainId, func(hash crypto.Hash, data []byte) error {
+		assert.Equal(s.Hash, hash)
+		stored = append(stored, data)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Len(stored, 1)
+	var session cosiSession
+	err = common.MsgpackUnmarshal(stored[0], &session)
+	assert.Nil(err)
+	assert.Equal(uint64(0), session.Counter)
+
+	c1 := crypto.NewHash([]byte("challenge-1"))
+	c2 := crypto.NewHash([]byte("challenge-2"))
+	valid, err := chain.checkpointCosiChallenge(v, c1)
+	assert.Nil(err)
+	assert.True(valid)
+	valid, err = chain.checkpointCosiChallenge(v, c1)
+	assert.Nil(err)
+	assert.True(valid)
+	valid, err = chain.checkpointCosiChallenge(v, c2)
+	assert.Nil(err)
+	assert.False(valid)
+
+	delete(chain.CosiVerifiers, s.Hash)
+	err = chain.loadCosiSessions()
+	assert.Nil(err)
+	r := chain.CosiVerifiers[s.Hash]
+	assert.NotNil(r)
+	assert.Equal(v.random.Key(), r.random.Key())
+	assert.Equal(c1, *r.challenge)
+	assert.Equal(s.Hash, r.Snapshot.Hash)
+	valid, err = chain.checkpointCosiChallenge(r, c2)
+	assert.Nil(err)
+	assert.False(valid)
+
+	err = chain.removeCosiSession(s.Hash)
+	assert.Nil(err)
+	delete(chain.CosiVerifiers, s.Hash)
+	err = chain.loadCosiSessions()
+	assert.Nil(err)
+	assert.Nil(chain.CosiVerifiers[s.Hash])
+
+	replayed := &CosiVerifier{Snapshot: s, random: chain.cosiNonce(s.Hash, 0)}
+	valid, err = chain.checkpointCosiChallenge(replayed, c2)
+	assert.Nil(err)
+	assert.False(valid)
+	valid, err = chain.checkpointCosiChallenge(replayed, c1)
+	assert.Nil(err)
+	assert.True(valid)
+
+	n, err := chain.newCosiVerifier(s)
+	assert.Nil(err)
+	assert.Equal(uint64(1), n.counter)
+	assert.NotEqual(v.random.Key(), n.random.Key())
+	valid, err = chain.checkpointCosiChallenge(n, c2)
+	assert.Nil(err)
+	assert.True(valid)
+}
+
+func TestCosiSessionLeaderResume(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-cosi-session-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	chain := node.GetOrCreateChain(node.IdForNetwork)
+	tx := common.NewTransaction(common.XINAssetId)
+	tx.AddInput(crypto.NewHash([]byte("cosi-session-leader-input")), 0)
+	tx.AddScriptOutput([]common.Address{node.Signer}, common.NewThresholdScript(1), common.NewInteger(1), make([]byte, 64))
+	ver := tx.AsLatestVersion()
+	err = node.persistStore.CachePutTransaction(ver)
+	assert.Nil(err)
+
+	var hashes []crypto.Hash
+	for _, th := range []crypto.Hash{ver.PayloadHash(), crypto.NewHash([]byte("cosi-session-leader-missing"))} {
+		s := &common.Snapshot{
+			Version:     common.SnapshotVersion,
+			NodeId:      chain.ChainId,
+			Transaction: th,
+			Timestamp:   node.Epoch + 1,
+		}
+		s.Hash = s.PayloadHash()
+		session := &cosiSession{
+			Snapshot: s,
+			Hash:     s.Hash,
+			Leader:   true,
+		}
+		err = node.persistStore.CachePutCosiSession(chain.ChainId, s.Hash, common.MsgpackMarshalPanic(session))
+		assert.Nil(err)
+		hashes = append(hashes, s.Hash)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- node.RestartChain(node.IdForNetwork)
+	}()
+	select {
+	case err = <-done:
+		assert.Nil(err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("RestartChain with leader sessions deadlocked")
+	}
+
+	chain = node.GetOrCreateChain(node.IdForNetwork)
+	for _, h := range hashes {
+		assert.Nil(chain.CosiVerifiers[h])
+		assert.Nil(chain.CosiAggregators[h])
+	}
+	var stored int
+	err = node.persistStore.CacheListCosiSessions(chain.ChainId, func(hash crypto.Hash, data []byte) error {
+		stored++
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(0, stored)
+}
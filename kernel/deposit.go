@@ -0,0 +1,30 @@
+package kernel
+
+import (
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/domains"
+)
+
+// validateDepositSnapshot checks the deposit asset key against the kernel
+// domain assets, and the amount against the domain asset decimals only after
+// the domains fork, so the old deposits stay valid.
+func (node *Node) validateDepositSnapshot(s *common.Snapshot, tx *common.VersionedTransaction) error {
+	deposit := tx.Inputs[0].Deposit
+	timestamp := node.snapshotTimestamp(s)
+	err := node.validateDomainAsset(deposit.Chain, deposit.AssetKey, timestamp)
+	if err != nil {
+		return err
+	}
+	if !node.domainsForked(timestamp) {
+		return nil
+	}
+	d := domains.Get(deposit.Chain)
+	if d == nil {
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid deposit chain %s", deposit.Chain)
+	}
+	err = d.VerifyAmount(deposit.AssetKey, deposit.Amount.String())
+	if err != nil {
+		return common.NewValidationError(common.ErrorCodeAmount, "invalid deposit amount %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package kernel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains/bitcoin"
+	"github.com/MixinNetwork/mixin/domains/ethereum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepositSnapshotDecimals(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-deposit-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	usdt := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	tx := common.NewTransaction(ethereum.GenerateAssetId(usdt))
+	tx.AddDepositInput(&common.DepositData{
+		Chain:           ethereum.EthereumChainId,
+		AssetKey:        usdt,
+		TransactionHash: "0xc7c1132b58e1f64c263957d7857fe5ec5294fce95d30dcd64efef71da1000000",
+		Amount:          common.NewIntegerFromString("1.23456789"),
+	})
+	ver := tx.AsLatestVersion()
+	s := &common.Snapshot{NodeId: node.IdForNetwork, Timestamp: MainnetDomainsForkTimestamp - 1}
+
+	assert.Equal(config.MainnetId, node.networkId.String())
+	assert.Nil(node.validateKernelSnapshot(s, ver, false))
+	s.Timestamp = MainnetDomainsForkTimestamp
+	err = node.validateKernelSnapshot(s, ver, false)
+	assert.NotNil(err)
+	assert.Equal(common.ErrorCodeAmount, common.ValidationErrorCode(err))
+
+	tx.Inputs[0].Deposit.Amount = common.NewIntegerFromString("1.23456700")
+	assert.Nil(node.validateKernelSnapshot(s, tx.AsLatestVersion(), false))
+
+	tx.Inputs[0].Deposit.Amount = common.NewIntegerFromString("1.23456789")
+	node.networkId = crypto.NewHash([]byte("testnet"))
+	s.Timestamp = MainnetDomainsForkTimestamp - 1
+	err = node.validateKernelSnapshot(s, tx.AsLatestVersion(), false)
+	assert.NotNil(err)
+	assert.Equal(common.ErrorCodeAmount, common.ValidationErrorCode(err))
+}
+
+func TestDepositSnapshotColoredAsset(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-deposit-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	colored := "31d2ea9c-95eb-3355-b65b-ba096853bc18"
+	fork := MainnetDomainsForkTimestamp + 1
+	BitcoinColoredAssets[colored] = fork
+	defer delete(BitcoinColoredAssets, colored)
+
+	tx := common.NewTransaction(crypto.NewHash([]byte(colored)))
+	tx.AddDepositInput(&common.DepositData{
+		Chain:           bitcoin.BitcoinChainId,
+		AssetKey:        colored,
+		TransactionHash: "c5945a8571fc84cd6850b26b5771d76311ed56957a04e993927de07b83f07c91",
+		Amount:          common.NewIntegerFromString("1"),
+	})
+	ver := tx.AsLatestVersion()
+	s := &common.Snapshot{NodeId: node.IdForNetwork, Timestamp: fork - 1}
+
+	assert.Equal(config.MainnetId, node.networkId.String())
+	err = node.validateDepositSnapshot(s, ver)
+	assert.NotNil(err)
+	assert.Equal(common.ErrorCodeAsset, common.ValidationErrorCode(err))
+	s.Timestamp = 0
+	assert.NotNil(node.validateDepositSnapshot(s, ver))
+	s.Timestamp = fork
+	assert.Nil(node.validateDepositSnapshot(s, ver))
+
+	tx.Inputs[0].Deposit.AssetKey = bitcoin.BitcoinOmniUSDTAssetKey
+	s.Timestamp = 1
+	assert.Nil(node.validateDepositSnapshot(s, tx.AsLatestVersion()))
+
+	tx.Inputs[0].Deposit.AssetKey = colored
+	node.networkId = crypto.NewHash([]byte("testnet"))
+	assert.Nil(node.validateDepositSnapshot(s, tx.AsLatestVersion()))
+}
@@ -0,0 +1,28 @@
+package kernel
+
+import (
+	"github.com/MixinNetwork/mixin/common"
+)
+
+func (node *Node) validateDomainSnapshot(s *common.Snapshot, tx *common.VersionedTransaction) error {
+	publics := node.ConsensusKeys(s.Timestamp)
+	threshold := node.ConsensusThreshold(s.Timestamp)
+
+	msg := tx.PayloadMarshal()
+	signers := make(map[int]bool)
+	for _, sig := range tx.ConsensusSignatures() {
+		for i, pub := range publics {
+			if signers[i] {
+				continue
+			}
+			if pub.Verify(msg, &sig) {
+				signers[i] = true
+				break
+			}
+		}
+	}
+	if len(signers) < threshold {
+		return common.NewValidationError(common.ErrorCodeSignature, "invalid consensus signatures count %d/%d", len(signers), threshold)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainSnapshotConsensusSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	epoch := uint64(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	node := &Node{genesisNodesMap: make(map[crypto.Hash]bool)}
+	signers := make([]common.Address, 0)
+	for i := 0; i < 5; i++ {
+		seed := make([]byte, 64)
+		rand.Read(seed)
+		signer := common.NewAddressFromSeed(seed)
+		signers = append(signers, signer)
+		cn := &CNode{
+			IdForNetwork: signer.Hash(),
+			Signer:       signer,
+			State:        common.NodeStateAccepted,
+			Timestamp:    epoch,
+		}
+		if i < 4 {
+			node.genesisNodesMap[cn.IdForNetwork] = true
+			node.genesisNodes = append(node.genesisNodes, cn.IdForNetwork)
+		} else {
+			cn.Timestamp = epoch + uint64(24*time.Hour)
+		}
+		node.AllNodesSorted = append(node.AllNodesSorted, cn)
+	}
+	accepted := node.AllNodesSorted[4].Timestamp
+
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	domain := common.NewAddressFromSeed(seed)
+	tx := common.NewTransaction(common.XINAssetId)
+	tx.AddInput(crypto.Hash{}, 0)
+	tx.AddOutputWithType(common.OutputTypeDomainAccept, nil, common.Script{}, common.NewIntegerFromString(config.DomainPledgeAmount), []byte{})
+	domainSpend := domain.PublicSpendKey.Key()
+	tx.Extra = domainSpend[:]
+	ver := tx.AsLatestVersion()
+	ver.Signatures = [][]crypto.Signature{{}}
+
+	s := &common.Snapshot{Timestamp: accepted + uint64(time.Second)}
+	for _, i := range []int{4, 0, 1, 0} {
+		err := ver.SignConsensus(signers[i].PrivateSpendKey)
+		assert.Nil(err)
+	}
+	err := node.validateDomainSnapshot(s, ver)
+	assert.NotNil(err)
+	assert.Equal(common.ErrorCodeSignature, common.ValidationErrorCode(err))
+	assert.Contains(err.Error(), "invalid consensus signatures count 2/3")
+
+	err = ver.SignConsensus(signers[2].PrivateSpendKey)
+	assert.Nil(err)
+	err = node.validateDomainSnapshot(s, ver)
+	assert.Nil(err)
+
+	s = &common.Snapshot{Timestamp: accepted + uint64(config.KernelNodeAcceptPeriodMinimum) + uint64(time.Second)}
+	err = node.validateDomainSnapshot(s, ver)
+	assert.Nil(err)
+	ver.Signatures[1] = ver.Signatures[1][1:]
+	err = node.validateDomainSnapshot(s, ver)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid consensus signatures count 3/4")
+}
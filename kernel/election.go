@@ -10,7 +10,6 @@ import (
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
 	"github.com/MixinNetwork/mixin/logger"
 )
 
@@ -21,16 +20,15 @@ const (
 func (node *Node) ElectionLoop() {
 	defer close(node.elc)
 
-	ticker := time.NewTicker(time.Duration(node.custom.Node.KernelOprationPeriod) * time.Second)
-	defer ticker.Stop()
+	period := time.Duration(node.custom.Node.KernelOprationPeriod) * time.Second
 
 	chain := node.GetOrCreateChain(node.IdForNetwork)
 	for chain.State.CacheRound == nil {
 		select {
 		case <-node.done:
 			return
-		case <-ticker.C:
-			now := uint64(clock.Now().UnixNano())
+		case <-node.clock.After(period):
+			now := uint64(node.clock.Now().UnixNano())
 			if now < node.Epoch {
 				logger.Printf("LOCAL TIME INVALID %d %d\n", now, node.Epoch)
 				continue
@@ -51,7 +49,7 @@ func (node *Node) ElectionLoop() {
 		select {
 		case <-node.done:
 			return
-		case <-ticker.C:
+		case <-node.clock.After(period):
 			// disable send remove transaction
 
 			// candi, err := node.checkRemovePossibility(node.IdForNetwork, node.GraphTimestamp)
@@ -249,18 +247,21 @@ func (node *Node) finalizeNodeAcceptSnapshot(s *common.Snapshot) error {
 	if err := cache.ValidateSnapshot(s, true); err != nil {
 		panic("should never be here")
 	}
-	err := node.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, cache.Timestamp)
+	final := cache.asFinal()
+	external, err := node.getInitialExternalReference(s)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	node.TopoWrite(s)
-
-	final := cache.asFinal()
-	external, err := node.getInitialExternalReference(s)
+	err = node.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, cache.Timestamp)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	_, err = node.TopoWrite(s)
+	if err != nil {
+		return err
 	}
+
 	cache = &CacheRound{
 		NodeId:    s.NodeId,
 		Number:    1,
@@ -272,7 +273,7 @@ func (node *Node) finalizeNodeAcceptSnapshot(s *common.Snapshot) error {
 	}
 	err = node.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, cache.Timestamp)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	chain := node.GetOrCreateChain(s.NodeId)
@@ -306,18 +307,18 @@ func (node *Node) getInitialExternalReference(s *common.Snapshot) (*FinalRound,
 func (node *Node) validateNodePledgeSnapshot(s *common.Snapshot, tx *common.VersionedTransaction) error {
 	timestamp := s.Timestamp
 	if s.Timestamp == 0 && s.NodeId == node.IdForNetwork {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
 
 	if timestamp < node.Epoch {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.Epoch, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.Epoch, timestamp)
 	}
 	since := timestamp - node.Epoch
 	days := int(since / 3600000000000 / 24)
 	elp := time.Duration((days%MintYearBatches)*24) * time.Hour
 	eta := time.Duration((MintYearBatches-days%MintYearBatches)*24) * time.Hour
 	if eta < config.KernelNodeAcceptPeriodMaximum*2 || elp < config.KernelNodeAcceptPeriodMinimum*2 {
-		return fmt.Errorf("invalid pledge timestamp %d %d", eta, elp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid pledge timestamp %d %d", eta, elp)
 	}
 
 	var signerSpend crypto.Key
@@ -327,37 +328,37 @@ func (node *Node) validateNodePledgeSnapshot(s *common.Snapshot, tx *common.Vers
 			cn.Timestamp = node.Epoch
 		}
 		if timestamp < cn.Timestamp {
-			return fmt.Errorf("invalid snapshot timestamp %d %d", cn.Timestamp, timestamp)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", cn.Timestamp, timestamp)
 		}
 		elapse := time.Duration(timestamp - cn.Timestamp)
 		if elapse < config.KernelNodePledgePeriodMinimum {
-			return fmt.Errorf("invalid pledge period %d %d", config.KernelNodePledgePeriodMinimum, elapse)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "invalid pledge period %d %d", config.KernelNodePledgePeriodMinimum, elapse)
 		}
 		if cn.State != common.NodeStateAccepted && cn.State != common.NodeStateCancelled && cn.State != common.NodeStateRemoved {
-			return fmt.Errorf("invalid node pending state %s %s", cn.Signer, cn.State)
+			return common.NewValidationError(common.ErrorCodeState, "invalid node pending state %s %s", cn.Signer, cn.State)
 		}
 		if cn.Signer.PublicSpendKey.String() == signerSpend.String() {
-			return fmt.Errorf("invalid node signer key %s %s", hex.EncodeToString(tx.Extra), cn.Signer)
+			return common.NewValidationError(common.ErrorCodeInput, "invalid node signer key %s %s", hex.EncodeToString(tx.Extra), cn.Signer)
 		}
 		if cn.Payee.PublicSpendKey.String() == signerSpend.String() {
-			return fmt.Errorf("invalid node signer key %s %s", hex.EncodeToString(tx.Extra), cn.Payee)
+			return common.NewValidationError(common.ErrorCodeInput, "invalid node signer key %s %s", hex.EncodeToString(tx.Extra), cn.Payee)
 		}
 	}
 
 	if cn := node.ConsensusPledging; cn != nil {
-		return fmt.Errorf("invalid node state %s %s", cn.Signer, cn.State)
+		return common.NewValidationError(common.ErrorCodeState, "invalid node state %s %s", cn.Signer, cn.State)
 	}
 	if tx.Asset != common.XINAssetId {
-		return fmt.Errorf("invalid node asset %s", tx.Asset.String())
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid node asset %s", tx.Asset.String())
 	}
 	if len(tx.Outputs) != 1 {
-		return fmt.Errorf("invalid outputs count %d for pledge transaction", len(tx.Outputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid outputs count %d for pledge transaction", len(tx.Outputs))
 	}
 	if len(tx.Extra) != 2*crypto.KeySize {
-		return fmt.Errorf("invalid extra length %d for pledge transaction", len(tx.Extra))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid extra length %d for pledge transaction", len(tx.Extra))
 	}
 	if tx.Outputs[0].Amount.Cmp(pledgeAmount(time.Duration(since))) != 0 {
-		return fmt.Errorf("invalid pledge amount %s", tx.Outputs[0].Amount.String())
+		return common.NewValidationError(common.ErrorCodeAmount, "invalid pledge amount %s", tx.Outputs[0].Amount.String())
 	}
 
 	// FIXME the node operation lock threshold should be optimized on pledging period
@@ -366,29 +367,29 @@ func (node *Node) validateNodePledgeSnapshot(s *common.Snapshot, tx *common.Vers
 
 func (node *Node) validateNodeCancelSnapshot(s *common.Snapshot, tx *common.VersionedTransaction, finalized bool) error {
 	if tx.Asset != common.XINAssetId {
-		return fmt.Errorf("invalid node asset %s", tx.Asset.String())
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid node asset %s", tx.Asset.String())
 	}
 	if len(tx.Outputs) != 2 {
-		return fmt.Errorf("invalid outputs count %d for cancel transaction", len(tx.Outputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid outputs count %d for cancel transaction", len(tx.Outputs))
 	}
 	if len(tx.Inputs) != 1 {
-		return fmt.Errorf("invalid inputs count %d for cancel transaction", len(tx.Inputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid inputs count %d for cancel transaction", len(tx.Inputs))
 	}
 	if len(tx.Extra) != crypto.KeySize*3 {
-		return fmt.Errorf("invalid extra %s for cancel transaction", hex.EncodeToString(tx.Extra))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid extra %s for cancel transaction", hex.EncodeToString(tx.Extra))
 	}
 	cancel, script := tx.Outputs[0], tx.Outputs[1]
 	if cancel.Type != common.OutputTypeNodeCancel || script.Type != common.OutputTypeScript {
-		return fmt.Errorf("invalid outputs type %d %d for cancel transaction", cancel.Type, script.Type)
+		return common.NewValidationError(common.ErrorCodeOutput, "invalid outputs type %d %d for cancel transaction", cancel.Type, script.Type)
 	}
 	if len(script.Keys) != 1 {
-		return fmt.Errorf("invalid script output keys %d for cancel transaction", len(script.Keys))
+		return common.NewValidationError(common.ErrorCodeOutput, "invalid script output keys %d for cancel transaction", len(script.Keys))
 	}
 	if node.ConsensusPledging == nil {
-		return fmt.Errorf("invalid consensus status")
+		return common.NewValidationError(common.ErrorCodeState, "invalid consensus status")
 	}
 	if node.ConsensusPledging.Transaction != tx.Inputs[0].Hash {
-		return fmt.Errorf("invalid plede utxo source %s %s", node.ConsensusPledging.Transaction, tx.Inputs[0].Hash)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid plede utxo source %s %s", node.ConsensusPledging.Transaction, tx.Inputs[0].Hash)
 	}
 
 	pledge, _, err := node.persistStore.ReadTransaction(tx.Inputs[0].Hash)
@@ -396,24 +397,24 @@ func (node *Node) validateNodeCancelSnapshot(s *common.Snapshot, tx *common.Vers
 		return err
 	}
 	if len(pledge.Outputs) != 1 {
-		return fmt.Errorf("invalid pledge utxo count %d", len(pledge.Outputs))
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge utxo count %d", len(pledge.Outputs))
 	}
 	if pledge.Outputs[0].Type != common.OutputTypeNodePledge {
-		return fmt.Errorf("invalid pledge utxo type %d", pledge.Outputs[0].Type)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge utxo type %d", pledge.Outputs[0].Type)
 	}
 	if cancel.Amount.Cmp(pledge.Outputs[0].Amount.Div(100)) != 0 {
-		return fmt.Errorf("invalid script output amount %s for cancel transaction", cancel.Amount)
+		return common.NewValidationError(common.ErrorCodeAmount, "invalid script output amount %s for cancel transaction", cancel.Amount)
 	}
 	pit, _, err := node.persistStore.ReadTransaction(pledge.Inputs[0].Hash)
 	if err != nil {
 		return err
 	}
 	if pit == nil {
-		return fmt.Errorf("invalid pledge input source %s:%d", pledge.Inputs[0].Hash, pledge.Inputs[0].Index)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge input source %s:%d", pledge.Inputs[0].Hash, pledge.Inputs[0].Index)
 	}
 	pi := pit.Outputs[pledge.Inputs[0].Index]
 	if len(pi.Keys) != 1 {
-		return fmt.Errorf("invalid pledge input source keys %d", len(pi.Keys))
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge input source keys %d", len(pi.Keys))
 	}
 	var a crypto.Key
 	copy(a[:], tx.Extra[crypto.KeySize*2:])
@@ -440,42 +441,42 @@ func (node *Node) validateNodeCancelSnapshot(s *common.Snapshot, tx *common.Vers
 	pledgeSpend := crypto.ViewGhostOutputKey(piMask, piKey, view, uint64(pledge.Inputs[0].Index))
 	targetSpend := crypto.ViewGhostOutputKey(sMask, sKey, view, 1)
 	if bytes.Compare(pledge.Extra, tx.Extra[:crypto.KeySize*2]) != 0 {
-		return fmt.Errorf("invalid pledge and accpet key %s %s", hex.EncodeToString(pledge.Extra), hex.EncodeToString(tx.Extra))
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge and accpet key %s %s", hex.EncodeToString(pledge.Extra), hex.EncodeToString(tx.Extra))
 	}
 	pledgeSpendKey := pledgeSpend.Key()
 	targetSpendKey := targetSpend.Key()
 	if bytes.Compare(pledgeSpendKey[:], targetSpendKey[:]) != 0 {
-		return fmt.Errorf("invalid pledge and cancel target %s %s", pledgeSpend, targetSpend)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge and cancel target %s %s", pledgeSpend, targetSpend)
 	}
 
 	timestamp := s.Timestamp
 	if s.Timestamp == 0 && s.NodeId == node.IdForNetwork {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
 	if timestamp < node.Epoch {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.Epoch, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.Epoch, timestamp)
 	}
 
 	since := timestamp - node.Epoch
 	hours := int(since / 3600000000000)
 	if hours%24 < config.KernelNodeAcceptTimeBegin || hours%24 > config.KernelNodeAcceptTimeEnd {
-		return fmt.Errorf("invalid node cancel hour %d", hours%24)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid node cancel hour %d", hours%24)
 	}
 
 	threshold := config.SnapshotRoundGap * config.SnapshotReferenceThreshold
 	if !finalized && timestamp+threshold*2 < node.GraphTimestamp {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.GraphTimestamp, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.GraphTimestamp, timestamp)
 	}
 
 	if timestamp < node.ConsensusPledging.Timestamp {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.ConsensusPledging.Timestamp, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.ConsensusPledging.Timestamp, timestamp)
 	}
 	elapse := time.Duration(timestamp - node.ConsensusPledging.Timestamp)
 	if elapse < config.KernelNodeAcceptPeriodMinimum {
-		return fmt.Errorf("invalid cancel period %d %d", config.KernelNodeAcceptPeriodMinimum, elapse)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid cancel period %d %d", config.KernelNodeAcceptPeriodMinimum, elapse)
 	}
 	if elapse > config.KernelNodeAcceptPeriodMaximum {
-		return fmt.Errorf("invalid cancel period %d %d", config.KernelNodeAcceptPeriodMaximum, elapse)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid cancel period %d %d", config.KernelNodeAcceptPeriodMaximum, elapse)
 	}
 
 	// FIXME the node operation lock threshold should be optimized on pledging period
@@ -485,7 +486,7 @@ func (node *Node) validateNodeCancelSnapshot(s *common.Snapshot, tx *common.Vers
 func (node *Node) validateNodeRemoveSnapshot(s *common.Snapshot, tx *common.VersionedTransaction) error {
 	timestamp := s.Timestamp
 	if s.Timestamp == 0 && s.NodeId == node.IdForNetwork {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
 	candi, err := node.checkRemovePossibility(s.NodeId, timestamp)
 	if err != nil {
@@ -496,29 +497,29 @@ func (node *Node) validateNodeRemoveSnapshot(s *common.Snapshot, tx *common.Vers
 		return err
 	}
 	if cantx.PayloadHash() != tx.PayloadHash() {
-		return fmt.Errorf("invalid node remove transaction %s %s", cantx.PayloadHash(), tx.PayloadHash())
+		return common.NewValidationError(common.ErrorCodeInput, "invalid node remove transaction %s %s", cantx.PayloadHash(), tx.PayloadHash())
 	}
 	return nil
 }
 
 func (node *Node) validateNodeAcceptSnapshot(s *common.Snapshot, tx *common.VersionedTransaction, finalized bool) error {
 	if tx.Asset != common.XINAssetId {
-		return fmt.Errorf("invalid node asset %s", tx.Asset.String())
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid node asset %s", tx.Asset.String())
 	}
 	if len(tx.Outputs) != 1 {
-		return fmt.Errorf("invalid outputs count %d for accept transaction", len(tx.Outputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid outputs count %d for accept transaction", len(tx.Outputs))
 	}
 	if len(tx.Inputs) != 1 {
-		return fmt.Errorf("invalid inputs count %d for accept transaction", len(tx.Inputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid inputs count %d for accept transaction", len(tx.Inputs))
 	}
 	if node.ConsensusPledging == nil {
-		return fmt.Errorf("invalid consensus status")
+		return common.NewValidationError(common.ErrorCodeState, "invalid consensus status")
 	}
 	if id := node.ConsensusPledging.IdForNetwork; id != s.NodeId {
-		return fmt.Errorf("invalid pledging node %s %s", id, s.NodeId)
+		return common.NewValidationError(common.ErrorCodeState, "invalid pledging node %s %s", id, s.NodeId)
 	}
 	if node.ConsensusPledging.Transaction != tx.Inputs[0].Hash {
-		return fmt.Errorf("invalid plede utxo source %s %s", node.ConsensusPledging.Transaction, tx.Inputs[0].Hash)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid plede utxo source %s %s", node.ConsensusPledging.Transaction, tx.Inputs[0].Hash)
 	}
 
 	pledge, _, err := node.persistStore.ReadTransaction(tx.Inputs[0].Hash)
@@ -526,57 +527,57 @@ func (node *Node) validateNodeAcceptSnapshot(s *common.Snapshot, tx *common.Vers
 		return err
 	}
 	if len(pledge.Outputs) != 1 {
-		return fmt.Errorf("invalid pledge utxo count %d", len(pledge.Outputs))
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge utxo count %d", len(pledge.Outputs))
 	}
 	if pledge.Outputs[0].Type != common.OutputTypeNodePledge {
-		return fmt.Errorf("invalid pledge utxo type %d", pledge.Outputs[0].Type)
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge utxo type %d", pledge.Outputs[0].Type)
 	}
 	if bytes.Compare(pledge.Extra, tx.Extra) != 0 {
-		return fmt.Errorf("invalid pledge and accpet key %s %s", hex.EncodeToString(pledge.Extra), hex.EncodeToString(tx.Extra))
+		return common.NewValidationError(common.ErrorCodeInput, "invalid pledge and accpet key %s %s", hex.EncodeToString(pledge.Extra), hex.EncodeToString(tx.Extra))
 	}
 
 	timestamp := s.Timestamp
 	if s.RoundNumber != 0 {
-		return fmt.Errorf("invalid snapshot round %d", s.RoundNumber)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot round %d", s.RoundNumber)
 	}
 	if s.Timestamp == 0 && s.NodeId == node.IdForNetwork {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
 	if timestamp < node.Epoch {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.Epoch, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.Epoch, timestamp)
 	}
 	chain := node.GetOrCreateChain(s.NodeId)
 	if r := chain.State.CacheRound; r != nil {
-		return fmt.Errorf("invalid graph round %s %d", s.NodeId, r.Number)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid graph round %s %d", s.NodeId, r.Number)
 	}
 	if r := chain.State.FinalRound; r != nil {
-		return fmt.Errorf("invalid graph round %s %d", s.NodeId, r.Number)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid graph round %s %d", s.NodeId, r.Number)
 	}
 
 	since := timestamp - node.Epoch
 	hours := int(since / 3600000000000)
 	if hours%24 < config.KernelNodeAcceptTimeBegin || hours%24 > config.KernelNodeAcceptTimeEnd {
-		return fmt.Errorf("invalid node accept hour %d", hours%24)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid node accept hour %d", hours%24)
 	}
 
 	threshold := config.SnapshotRoundGap * config.SnapshotReferenceThreshold
 	if !finalized && timestamp+threshold*2 < node.GraphTimestamp {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.GraphTimestamp, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.GraphTimestamp, timestamp)
 	}
 
 	if timestamp < node.ConsensusPledging.Timestamp {
-		return fmt.Errorf("invalid snapshot timestamp %d %d", node.ConsensusPledging.Timestamp, timestamp)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid snapshot timestamp %d %d", node.ConsensusPledging.Timestamp, timestamp)
 	}
 	elapse := time.Duration(timestamp - node.ConsensusPledging.Timestamp)
 	if elapse < config.KernelNodeAcceptPeriodMinimum {
 		if s.PayloadHash().String() == MainnetAcceptPeriodForkSnapshotHash {
 			logger.Printf("FORK invalid accept period %d %d\n", config.KernelNodeAcceptPeriodMinimum, elapse)
 		} else {
-			return fmt.Errorf("invalid accept period %d %d", config.KernelNodeAcceptPeriodMinimum, elapse)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "invalid accept period %d %d", config.KernelNodeAcceptPeriodMinimum, elapse)
 		}
 	}
 	if elapse > config.KernelNodeAcceptPeriodMaximum {
-		return fmt.Errorf("invalid accept period %d %d", config.KernelNodeAcceptPeriodMaximum, elapse)
+		return common.NewValidationError(common.ErrorCodeSnapshot, "invalid accept period %d %d", config.KernelNodeAcceptPeriodMaximum, elapse)
 	}
 
 	return nil
@@ -98,6 +98,10 @@ ring-final-size = 16384
 listener = "mixin-node.example.com:7239"`)
 
 func setupTestNode(assert *assert.Assertions, dir string) *Node {
+	return setupTestNodeWithClock(assert, dir, NewRealClock())
+}
+
+func setupTestNodeWithClock(assert *assert.Assertions, dir string, clock Clock) *Node {
 	err := ioutil.WriteFile(dir+"/config.toml", configData, 0644)
 	assert.Nil(err)
 
@@ -117,7 +121,7 @@ func setupTestNode(assert *assert.Assertions, dir string) *Node {
 	store, err := storage.NewBadgerStore(custom, dir)
 	assert.Nil(err)
 	assert.NotNil(store)
-	node, err := SetupNode(custom, store, cache, ":7239", dir)
+	node, err := SetupNode(custom, store, cache, clock, ":7239", dir)
 	assert.Nil(err)
 	return node
 }
@@ -91,7 +91,7 @@ func (chain *Chain) tryToStartNewRound(s *common.Snapshot) (bool, error) {
 	}
 	err = chain.persistStore.StartNewRound(cache.NodeId, cache.Number, cache.References, final.Start)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
 	chain.assignNewGraphRound(final, cache)
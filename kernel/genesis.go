@@ -9,10 +9,14 @@ import (
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains/bitcoin"
+	_ "github.com/MixinNetwork/mixin/domains/eos"
+	_ "github.com/MixinNetwork/mixin/domains/ethereum"
 )
 
 const (
-	MinimumNodeCount = 7
+	MinimumNodeCount       = 7
+	MaximumStampDifficulty = 32
 )
 
 type Genesis struct {
@@ -26,9 +30,24 @@ type Genesis struct {
 		Signer  common.Address `json:"signer"`
 		Balance common.Integer `json:"balance"`
 	} `json:"domains"`
+	AntiSpam *struct {
+		Difficulty int `json:"difficulty"`
+	} `json:"anti-spam,omitempty"`
 }
 
 func (node *Node) LoadGenesis(configDir string) error {
+	network, err := readGenesisNetwork(configDir + "/genesis.json")
+	if err != nil {
+		return err
+	}
+	if n := node.custom.Node.Network; n != "" && n != network {
+		return fmt.Errorf("invalid genesis network %s %s", network, n)
+	}
+	err = common.SetNetworkId(network)
+	if err != nil {
+		return err
+	}
+
 	gns, err := readGenesis(configDir + "/genesis.json")
 	if err != nil {
 		return err
@@ -40,6 +59,14 @@ func (node *Node) LoadGenesis(configDir string) error {
 	}
 	node.Epoch = uint64(time.Unix(gns.Epoch, 0).UnixNano())
 	node.networkId = crypto.NewHash(data)
+	node.genesisStamp = -1
+	if gns.AntiSpam != nil {
+		node.genesisStamp = gns.AntiSpam.Difficulty
+	}
+	err = setupDomains(node.networkId)
+	if err != nil {
+		return err
+	}
 	node.IdForNetwork = node.Signer.Hash().ForNetwork(node.networkId)
 	for _, in := range gns.Nodes {
 		id := in.Signer.Hash().ForNetwork(node.networkId)
@@ -60,6 +87,17 @@ func (node *Node) LoadGenesis(configDir string) error {
 	return node.persistStore.LoadGenesis(rounds, snapshots, transactions)
 }
 
+// setupDomains configures the domain rules from the kernel constants, so all
+// nodes of the network validate the domain transactions with the same rules,
+// and the existing networks adopt them without a new genesis.
+func setupDomains(networkId crypto.Hash) error {
+	var coloredAssets []string
+	for k := range BitcoinColoredAssets {
+		coloredAssets = append(coloredAssets, k)
+	}
+	return bitcoin.Configure(networkId.String() == config.MainnetId, coloredAssets)
+}
+
 func buildGenesisSnapshots(networkId crypto.Hash, epoch uint64, gns *Genesis) ([]*common.Round, []*common.SnapshotWithTopologicalOrder, []*common.VersionedTransaction, error) {
 	var snapshots []*common.SnapshotWithTopologicalOrder
 	var transactions []*common.VersionedTransaction
@@ -158,7 +196,7 @@ func buildDomainSnapshot(networkId crypto.Hash, epoch uint64, domain common.Addr
 	}
 	tx := common.NewTransaction(common.XINAssetId)
 	tx.Inputs = []*common.Input{{Genesis: networkId[:]}}
-	tx.AddOutputWithType(common.OutputTypeDomainAccept, accounts, script, common.NewInteger(50000), seed)
+	tx.AddOutputWithType(common.OutputTypeDomainAccept, accounts, script, common.NewIntegerFromString(config.DomainPledgeAmount), seed)
 	domainPubKey := domain.PublicSpendKey.Key()
 	tx.Extra = make([]byte, len(domainPubKey))
 	copy(tx.Extra, domainPubKey[:])
@@ -184,6 +222,29 @@ func buildDomainSnapshot(networkId crypto.Hash, epoch uint64, domain common.Addr
 	}, signed
 }
 
+// readGenesisNetwork returns the address prefix used by the genesis nodes,
+// which must be activated before the genesis addresses could be decoded.
+func readGenesisNetwork(path string) (string, error) {
+	f, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var gns struct {
+		Nodes []struct {
+			Signer string `json:"signer"`
+		} `json:"nodes"`
+	}
+	err = json.Unmarshal(f, &gns)
+	if err != nil {
+		return "", err
+	}
+	if len(gns.Nodes) == 0 {
+		return "", fmt.Errorf("invalid genesis inputs number %d/%d", len(gns.Nodes), MinimumNodeCount)
+	}
+	return common.AddressNetworkId(gns.Nodes[0].Signer)
+}
+
 func readGenesis(path string) (*Genesis, error) {
 	f, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -228,8 +289,11 @@ func readGenesis(path string) (*Genesis, error) {
 	if domain.Signer.String() != gns.Nodes[0].Signer.String() {
 		return nil, fmt.Errorf("invalid genesis domain input account %s %s", domain.Signer.String(), gns.Nodes[0].Signer.String())
 	}
-	if domain.Balance.Cmp(common.NewInteger(50000)) != 0 {
+	if domain.Balance.Cmp(common.NewIntegerFromString(config.DomainPledgeAmount)) != 0 {
 		return nil, fmt.Errorf("invalid genesis domain input amount %s", domain.Balance.String())
 	}
+	if gns.AntiSpam != nil && (gns.AntiSpam.Difficulty < 0 || gns.AntiSpam.Difficulty > MaximumStampDifficulty) {
+		return nil, fmt.Errorf("invalid genesis anti spam difficulty %d", gns.AntiSpam.Difficulty)
+	}
 	return &gns, nil
 }
@@ -237,6 +237,10 @@ func (chain *Chain) clearAndQueueSnapshotOrPanic(s *common.Snapshot) error {
 	delete(chain.CosiVerifiers, s.Hash)
 	delete(chain.CosiAggregators, s.Hash)
 	delete(chain.CosiAggregators, s.Transaction)
+	err := chain.removeCosiSession(s.Hash)
+	if err != nil {
+		return err
+	}
 	return chain.AppendSelfEmpty(&common.Snapshot{
 		Version:     common.SnapshotVersion,
 		NodeId:      s.NodeId,
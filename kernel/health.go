@@ -0,0 +1,197 @@
+package kernel
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/logger"
+)
+
+const (
+	ChainStateRunning     = "running"
+	ChainStateRetrying    = "retrying"
+	ChainStateQuarantined = "quarantined"
+	ChainStateStopped     = "stopped"
+
+	ChainLoopPollSnapshots     = "QueuePollSnapshots"
+	ChainLoopConsumeFinalities = "ConsumeFinalActions"
+
+	ChainRetryLimit          = 10
+	ChainRetryBackoffMinimum = 100 * time.Millisecond
+	ChainRetryBackoffMaximum = 30 * time.Second
+	ChainQuarantineProbe     = 10 * time.Minute
+)
+
+type ChainLoopHealth struct {
+	Loop      string `json:"loop"`
+	State     string `json:"state"`
+	Errors    uint64 `json:"errors"`
+	Retries   int    `json:"retries"`
+	LastError string `json:"error,omitempty"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+type ChainHealth struct {
+	ChainId crypto.Hash        `json:"node"`
+	State   string             `json:"state"`
+	Loops   []*ChainLoopHealth `json:"loops"`
+}
+
+// chainSupervisor tracks the errors of one chain loop. A retryable error
+// makes the loop wait with an exponential backoff, and too many consecutive
+// retries quarantine the whole chain, while the other chains keep running.
+// A quarantined loop is probed with one more retry after a long while, and
+// quarantined again if it still fails. The validation errors are never
+// supervised, the actions are dropped.
+type chainSupervisor struct {
+	sync.Mutex
+	clock   Clock
+	health  ChainLoopHealth
+	retryAt time.Time
+}
+
+func newChainSupervisor(loop string, clock Clock) *chainSupervisor {
+	return &chainSupervisor{clock: clock, health: ChainLoopHealth{Loop: loop, State: ChainStateRunning}}
+}
+
+// supervise records the loop error, and returns false if the chain should be
+// quarantined because the error is retried too many times.
+func (s *chainSupervisor) supervise(chainId crypto.Hash, err error) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.clock.Now()
+	h := &s.health
+	h.Errors += 1
+	h.LastError = err.Error()
+	h.Timestamp = uint64(now.UnixNano())
+	if h.Retries >= ChainRetryLimit {
+		h.State = ChainStateQuarantined
+		s.retryAt = now.Add(ChainQuarantineProbe)
+		logger.Printf("CHAIN QUARANTINED %s %s %d %s\n", chainId, h.Loop, h.Retries, h.LastError)
+		return false
+	}
+
+	backoff := ChainRetryBackoffMinimum << uint(h.Retries)
+	if backoff > ChainRetryBackoffMaximum {
+		backoff = ChainRetryBackoffMaximum
+	}
+	h.Retries += 1
+	h.State = ChainStateRetrying
+	s.retryAt = now.Add(backoff)
+	logger.Printf("CHAIN RETRY %s %s %d %s %s\n", chainId, h.Loop, h.Retries, backoff, h.LastError)
+	return true
+}
+
+func (s *chainSupervisor) recover() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.health.State == ChainStateRetrying {
+		s.health.State = ChainStateRunning
+		s.health.Retries = 0
+	}
+}
+
+// probe lifts the quarantine when it's due, and keeps the retries at the
+// limit, so the loop is quarantined again by another error.
+func (s *chainSupervisor) probe() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.health.State != ChainStateQuarantined {
+		return true
+	}
+	if s.clock.Now().Before(s.retryAt) {
+		return false
+	}
+	s.health.State = ChainStateRetrying
+	return true
+}
+
+func (s *chainSupervisor) ready() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.health.State == ChainStateQuarantined {
+		return false
+	}
+	return !s.clock.Now().Before(s.retryAt)
+}
+
+func (s *chainSupervisor) snapshot() *ChainLoopHealth {
+	s.Lock()
+	defer s.Unlock()
+
+	h := s.health
+	return &h
+}
+
+// the validation errors are deterministic on the same action supplied by the
+// peer, so it's useless to retry them, and the action is dropped without the
+// chain quarantined, all other errors e.g. storage errors are retried
+func isDroppableChainError(err error) bool {
+	return err != nil && common.ValidationErrorCode(err) != common.ErrorCodeUnknown
+}
+
+func (chain *Chain) supervise(s *chainSupervisor, err error) {
+	if s.supervise(chain.ChainId, err) {
+		return
+	}
+	atomic.StoreInt32(&chain.quarantined, 1)
+}
+
+// probeQuarantine lifts the chain quarantine when all its loops are probed,
+// the actions dropped meanwhile will be synced from peers again.
+func (chain *Chain) probeQuarantine() {
+	if !chain.isQuarantined() {
+		return
+	}
+	poll := chain.pollSupervisor.probe()
+	final := chain.finalSupervisor.probe()
+	if !poll || !final {
+		return
+	}
+	logger.Printf("CHAIN PROBE %s\n", chain.ChainId)
+	atomic.StoreInt32(&chain.quarantined, 0)
+}
+
+func (chain *Chain) isQuarantined() bool {
+	return atomic.LoadInt32(&chain.quarantined) == 1
+}
+
+func (chain *Chain) Health() *ChainHealth {
+	h := &ChainHealth{
+		ChainId: chain.ChainId,
+		State:   ChainStateRunning,
+		Loops:   []*ChainLoopHealth{chain.pollSupervisor.snapshot(), chain.finalSupervisor.snapshot()},
+	}
+	for _, l := range h.Loops {
+		if l.State == ChainStateRetrying {
+			h.State = ChainStateRetrying
+		}
+	}
+	if chain.isQuarantined() {
+		h.State = ChainStateQuarantined
+	}
+	if !chain.isRunning() {
+		h.State = ChainStateStopped
+	}
+	return h
+}
+
+func (node *Node) ChainsHealth() []*ChainHealth {
+	node.chains.RLock()
+	defer node.chains.RUnlock()
+
+	hs := make([]*ChainHealth, 0)
+	for _, chain := range node.chains.m {
+		hs = append(hs, chain.Health())
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i].ChainId.String() < hs[j].ChainId.String() })
+	return hs
+}
@@ -0,0 +1,121 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainSupervisor(t *testing.T) {
+	assert := assert.New(t)
+
+	id := crypto.NewHash([]byte("chain"))
+	s := newChainSupervisor(ChainLoopPollSnapshots, NewRealClock())
+	assert.True(s.ready())
+
+	assert.True(s.supervise(id, errors.New("storage unavailable")))
+	h := s.snapshot()
+	assert.Equal(ChainStateRetrying, h.State)
+	assert.Equal(uint64(1), h.Errors)
+	assert.Equal(1, h.Retries)
+	assert.Equal("storage unavailable", h.LastError)
+	assert.False(s.ready())
+	time.Sleep(ChainRetryBackoffMinimum + 10*time.Millisecond)
+	assert.True(s.ready())
+
+	s.recover()
+	h = s.snapshot()
+	assert.Equal(ChainStateRunning, h.State)
+	assert.Equal(uint64(1), h.Errors)
+	assert.Equal(0, h.Retries)
+
+	for i := 0; i < ChainRetryLimit; i++ {
+		assert.True(s.supervise(id, errors.New("storage unavailable")))
+	}
+	assert.False(s.supervise(id, errors.New("storage unavailable")))
+	assert.Equal(ChainStateQuarantined, s.snapshot().State)
+	s.recover()
+	assert.Equal(ChainStateQuarantined, s.snapshot().State)
+	assert.False(s.ready())
+
+	clock := NewMockClock(NewRealClock())
+	s.clock = clock
+	assert.False(s.probe())
+	clock.MockDiff(ChainQuarantineProbe)
+	assert.True(s.probe())
+	assert.Equal(ChainStateRetrying, s.snapshot().State)
+	assert.True(s.ready())
+	assert.False(s.supervise(id, errors.New("storage unavailable")))
+	assert.Equal(ChainStateQuarantined, s.snapshot().State)
+	assert.False(s.probe())
+	clock.MockDiff(ChainQuarantineProbe)
+	assert.True(s.probe())
+	s.recover()
+	assert.Equal(ChainStateRunning, s.snapshot().State)
+	assert.Equal(0, s.snapshot().Retries)
+
+	err := common.NewValidationError(common.ErrorCodeType, "invalid initial transaction type %d", 1)
+	assert.True(isDroppableChainError(err))
+	assert.False(isDroppableChainError(errors.New("storage unavailable")))
+	assert.False(isDroppableChainError(nil))
+}
+
+func TestChainControl(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-chain-control-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	node := setupTestNode(assert, root)
+	assert.NotNil(node)
+
+	id := node.IdForNetwork
+	chain := node.GetOrCreateChain(id)
+	assert.Nil(node.StartChain(id))
+	assert.Equal(chain, node.getChain(id))
+
+	assert.Nil(node.StopChain(id))
+	assert.Nil(node.StopChain(id))
+	assert.Equal(chain, node.getChain(id))
+	assert.Equal(ChainStateStopped, chain.Health().State)
+
+	assert.Nil(node.StartChain(id))
+	started := node.getChain(id)
+	assert.NotEqual(chain, started)
+	assert.Equal(ChainStateRunning, started.Health().State)
+	assert.Nil(node.StartChain(id))
+	assert.Equal(started, node.getChain(id))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(node.RestartChain(id))
+		}()
+	}
+	wg.Wait()
+	restarted := node.getChain(id)
+	assert.NotEqual(started, restarted)
+	assert.Equal(ChainStateRunning, restarted.Health().State)
+	assert.False(started.isRunning())
+
+	assert.Nil(node.StopChain(id))
+	assert.Nil(node.RestartChain(id))
+	assert.Equal(ChainStateRunning, node.getChain(id).Health().State)
+	node.getChain(id).Teardown()
+
+	_, err = node.ChainHealth(crypto.NewHash([]byte("chain-control-missing")))
+	assert.NotNil(err)
+	assert.NotNil(node.StartChain(crypto.NewHash([]byte("chain-control-missing"))))
+}
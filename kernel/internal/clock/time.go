@@ -1,56 +1,122 @@
 package clock
 
 import (
-	"fmt"
-	"strings"
 	"sync"
 	"time"
-
-	"github.com/MixinNetwork/mixin/config"
 )
 
-// FIXME GLOBAL VARAIBLES
+// Clock is the time source of a node, each node has its own clock, so tests
+// could run nodes with different clocks in the same process. All the waits of
+// a node go through its clock too, so a virtual clock controls when the node
+// loops wake up.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
 
-var (
-	mutex    *sync.RWMutex
-	mockDiff time.Duration
-)
+// Mock is a clock could be shifted at runtime in tests.
+type Mock interface {
+	Clock
+	MockDiff(at time.Duration)
+	Reset()
+}
+
+type realClock struct{}
 
-func init() {
-	mutex = new(sync.RWMutex)
-	mockDiff = 0
+type mockClock struct {
+	sync.RWMutex
+	base Clock
+	diff time.Duration
 }
 
-func Reset() {
-	if !inTest() {
-		panic(fmt.Errorf("clock reset not allowed in build version %s", config.BuildVersion))
-	}
+type skewedClock struct {
+	base Clock
+	skew time.Duration
+}
+
+type sourceClock func() time.Time
 
-	mutex.Lock()
-	defer mutex.Unlock()
-	mockDiff = 0
+func NewReal() Clock {
+	return realClock{}
 }
 
-func MockDiff(at time.Duration) {
-	if !inTest() {
-		panic(fmt.Errorf("clock mock not allowed in build version %s", config.BuildVersion))
-	}
+// NewMock returns a clock shifted from the base clock, the shift could be
+// changed at any time with MockDiff, and cleared with Reset.
+func NewMock(base Clock) Mock {
+	return &mockClock{base: base}
+}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-	mockDiff += at
+// NewSkewed returns a clock with a fixed skew from the base clock, to mimic
+// a node with a drifted system clock.
+func NewSkewed(base Clock, skew time.Duration) Clock {
+	return &skewedClock{base: base, skew: skew}
 }
 
-func Now() time.Time {
-	if !inTest() {
-		return time.Now()
-	}
+// NewSource returns a clock reads time from the source, the waits still use
+// the wall clock.
+func NewSource(now func() time.Time) Clock {
+	return sourceClock(now)
+}
+
+func (c realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (c realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c *mockClock) Reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.diff = 0
+}
+
+func (c *mockClock) MockDiff(at time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.diff += at
+}
+
+func (c *mockClock) Now() time.Time {
+	c.RLock()
+	defer c.RUnlock()
+	return c.base.Now().Add(c.diff)
+}
+
+func (c *mockClock) Sleep(d time.Duration) {
+	c.base.Sleep(d)
+}
+
+func (c *mockClock) After(d time.Duration) <-chan time.Time {
+	return c.base.After(d)
+}
+
+func (c *skewedClock) Now() time.Time {
+	return c.base.Now().Add(c.skew)
+}
+
+func (c *skewedClock) Sleep(d time.Duration) {
+	c.base.Sleep(d)
+}
+
+func (c *skewedClock) After(d time.Duration) <-chan time.Time {
+	return c.base.After(d)
+}
+
+func (c sourceClock) Now() time.Time {
+	return c()
+}
 
-	mutex.RLock()
-	defer mutex.RUnlock()
-	return time.Now().Add(mockDiff)
+func (c sourceClock) Sleep(d time.Duration) {
+	time.Sleep(d)
 }
 
-func inTest() bool {
-	return strings.Contains(config.BuildVersion, "BUILD_VERSION")
+func (c sourceClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
 }
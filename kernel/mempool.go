@@ -0,0 +1,279 @@
+package kernel
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/logger"
+)
+
+const (
+	MempoolSizeLimit = CachePoolSnapshotsLimit * 16
+	MempoolBatchSize = 64
+
+	mempoolPriorityKernel = 0
+	mempoolPriorityUser   = 1
+)
+
+type mempoolEntry struct {
+	tx       *common.VersionedTransaction
+	hash     crypto.Hash
+	priority int
+	work     int
+	arrival  uint64
+	spends   []string
+	index    int
+}
+
+type mempoolHeap []*mempoolEntry
+
+type Mempool struct {
+	sync.Mutex
+	entries  map[crypto.Hash]*mempoolEntry
+	spends   map[string]crypto.Hash
+	queue    mempoolHeap
+	limit    int
+	sequence uint64
+	rejected uint64
+	replaced uint64
+}
+
+type MempoolStats struct {
+	Size     int
+	Rejected uint64
+	Replaced uint64
+}
+
+func NewMempool(limit int) *Mempool {
+	return &Mempool{
+		entries: make(map[crypto.Hash]*mempoolEntry),
+		spends:  make(map[string]crypto.Hash),
+		limit:   limit,
+	}
+}
+
+// Add keeps the first seen transaction for any spent input, and when the pool
+// is full it evicts the lowest priority transaction only if the new one ranks
+// higher, otherwise the new transaction is rejected.
+func (pool *Mempool) Add(tx *common.VersionedTransaction) error {
+	pool.Lock()
+	defer pool.Unlock()
+
+	hash := tx.PayloadHash()
+	if pool.entries[hash] != nil {
+		return nil
+	}
+
+	entry := &mempoolEntry{
+		tx:       tx,
+		hash:     hash,
+		priority: mempoolPriority(tx),
+		work:     common.StampWork(hash),
+		arrival:  pool.sequence,
+		spends:   mempoolSpends(tx),
+	}
+	for _, k := range entry.spends {
+		if old, found := pool.spends[k]; found {
+			pool.rejected += 1
+			return common.NewValidationError(common.ErrorCodeDoubleSpend, "mempool double spend %s by %s %s", k, old, hash)
+		}
+	}
+
+	if len(pool.queue) >= pool.limit {
+		lowest := pool.lowest()
+		if lowest == nil || !entry.before(lowest) {
+			pool.rejected += 1
+			return common.NewValidationError(common.ErrorCodeCapacity, "mempool full %d %s", len(pool.queue), hash)
+		}
+		pool.remove(lowest)
+		pool.replaced += 1
+	}
+
+	pool.sequence += 1
+	pool.entries[hash] = entry
+	for _, k := range entry.spends {
+		pool.spends[k] = hash
+	}
+	heap.Push(&pool.queue, entry)
+	return nil
+}
+
+func (pool *Mempool) Remove(hash crypto.Hash) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if entry := pool.entries[hash]; entry != nil {
+		pool.remove(entry)
+	}
+}
+
+// PopBatch removes and returns at most limit transactions in priority order.
+func (pool *Mempool) PopBatch(limit int) []*common.VersionedTransaction {
+	pool.Lock()
+	defer pool.Unlock()
+
+	var txs []*common.VersionedTransaction
+	for len(txs) < limit && len(pool.queue) > 0 {
+		entry := pool.queue[0]
+		pool.remove(entry)
+		txs = append(txs, entry.tx)
+	}
+	return txs
+}
+
+func (pool *Mempool) Stats() MempoolStats {
+	pool.Lock()
+	defer pool.Unlock()
+
+	return MempoolStats{
+		Size:     len(pool.queue),
+		Rejected: pool.rejected,
+		Replaced: pool.replaced,
+	}
+}
+
+func (pool *Mempool) remove(entry *mempoolEntry) {
+	heap.Remove(&pool.queue, entry.index)
+	delete(pool.entries, entry.hash)
+	for _, k := range entry.spends {
+		if pool.spends[k] == entry.hash {
+			delete(pool.spends, k)
+		}
+	}
+}
+
+func (pool *Mempool) lowest() *mempoolEntry {
+	var lowest *mempoolEntry
+	for _, e := range pool.queue {
+		if lowest == nil || lowest.before(e) {
+			lowest = e
+		}
+	}
+	return lowest
+}
+
+func (e *mempoolEntry) before(o *mempoolEntry) bool {
+	if e.priority != o.priority {
+		return e.priority < o.priority
+	}
+	if e.work != o.work {
+		return e.work > o.work
+	}
+	return e.arrival < o.arrival
+}
+
+func (h mempoolHeap) Len() int { return len(h) }
+
+func (h mempoolHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+
+func (h mempoolHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *mempoolHeap) Push(x interface{}) {
+	e := x.(*mempoolEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *mempoolHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+func mempoolPriority(tx *common.VersionedTransaction) int {
+	switch tx.TransactionType() {
+	case common.TransactionTypeMint,
+		common.TransactionTypeNodePledge,
+		common.TransactionTypeNodeCancel,
+		common.TransactionTypeNodeAccept,
+		common.TransactionTypeNodeResign,
+		common.TransactionTypeNodeRemove,
+		common.TransactionTypeDomainAccept,
+		common.TransactionTypeDomainRemove:
+		return mempoolPriorityKernel
+	}
+	return mempoolPriorityUser
+}
+
+func mempoolSpends(tx *common.VersionedTransaction) []string {
+	var spends []string
+	for _, in := range tx.Inputs {
+		switch {
+		case in.Deposit != nil:
+			spends = append(spends, "DEPOSIT:"+in.Deposit.UniqueKey().String())
+		case in.Mint != nil:
+			spends = append(spends, fmt.Sprintf("MINT:%s:%d", in.Mint.Group, in.Mint.Batch))
+		case len(in.Genesis) > 0:
+		default:
+			spends = append(spends, fmt.Sprintf("UTXO:%s:%d", in.Hash, in.Index))
+		}
+	}
+	return spends
+}
+
+func (node *Node) MempoolStats() MempoolStats {
+	return node.mempool.Stats()
+}
+
+// pullMempoolBatch appends the mempool transactions to the self chain, they
+// are kept in the mempool when the clock is skewed, because no snapshot is
+// announced until the clock recovers.
+func (node *Node) pullMempoolBatch(chain *Chain) error {
+	if chain.ChainId != node.IdForNetwork {
+		return nil
+	}
+	if node.ClockSkew().Skewed {
+		return nil
+	}
+	free := int(chain.CachePool.Cap() - chain.CachePool.Len())
+	if free > MempoolBatchSize {
+		free = MempoolBatchSize
+	}
+	txs := node.mempool.PopBatch(free)
+	for i, tx := range txs {
+		s := &common.Snapshot{
+			Version:     common.SnapshotVersion,
+			NodeId:      node.IdForNetwork,
+			Transaction: tx.PayloadHash(),
+		}
+		err := chain.AppendSelfEmpty(s)
+		if err != nil {
+			node.requeueMempool(txs[i:])
+			return err
+		}
+	}
+	return nil
+}
+
+// requeueMempool puts back the transactions popped but not appended to the
+// chain, they are still in the cache store if the mempool rejects them.
+func (node *Node) requeueMempool(txs []*common.VersionedTransaction) {
+	for _, tx := range txs {
+		err := node.mempool.Add(tx)
+		if err != nil {
+			logger.Printf("requeueMempool(%s) DROP %s\n", tx.PayloadHash(), err)
+		}
+	}
+}
+
+// requeueSnapshotTransaction puts the transaction of a snapshot not announced
+// back to the mempool, it's announced again when the mempool is pulled.
+func (node *Node) requeueSnapshotTransaction(s *common.Snapshot) error {
+	tx, err := node.persistStore.CacheGetTransaction(s.Transaction)
+	if err != nil || tx == nil {
+		return err
+	}
+	node.requeueMempool([]*common.VersionedTransaction{tx})
+	return nil
+}
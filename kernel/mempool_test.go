@@ -0,0 +1,81 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMempool(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewMempool(3)
+	user := mempoolTestTransaction(crypto.NewHash([]byte("user")), 0, false)
+	err := pool.Add(user)
+	assert.Nil(err)
+	err = pool.Add(user)
+	assert.Nil(err)
+	assert.Equal(1, pool.Stats().Size)
+
+	double := mempoolTestTransaction(crypto.NewHash([]byte("user")), 0, false)
+	double.Extra = []byte("double")
+	err = pool.Add(double)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "mempool double spend")
+	assert.Equal(uint64(1), pool.Stats().Rejected)
+
+	kernel := mempoolTestTransaction(crypto.NewHash([]byte("kernel")), 0, true)
+	err = pool.Add(kernel)
+	assert.Nil(err)
+	other := mempoolTestTransaction(crypto.NewHash([]byte("other")), 1, false)
+	err = pool.Add(other)
+	assert.Nil(err)
+	assert.Equal(3, pool.Stats().Size)
+
+	pledge := mempoolTestTransaction(crypto.NewHash([]byte("pledge")), 0, true)
+	err = pool.Add(pledge)
+	assert.Nil(err)
+	stats := pool.Stats()
+	assert.Equal(3, stats.Size)
+	assert.Equal(uint64(1), stats.Replaced)
+
+	extra := mempoolTestTransaction(crypto.NewHash([]byte("extra")), 0, false)
+	err = pool.Add(extra)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "mempool full")
+	assert.Equal(uint64(2), pool.Stats().Rejected)
+
+	batch := pool.PopBatch(2)
+	assert.Len(batch, 2)
+	assert.ElementsMatch([]crypto.Hash{kernel.PayloadHash(), pledge.PayloadHash()}, []crypto.Hash{batch[0].PayloadHash(), batch[1].PayloadHash()})
+	survivor := user
+	if common.StampWork(other.PayloadHash()) > common.StampWork(user.PayloadHash()) {
+		survivor = other
+	}
+	batch = pool.PopBatch(2)
+	assert.Len(batch, 1)
+	assert.Equal(survivor.PayloadHash(), batch[0].PayloadHash())
+	assert.Equal(0, pool.Stats().Size)
+
+	err = pool.Add(double)
+	assert.Nil(err)
+	pool.Remove(double.PayloadHash())
+	assert.Equal(0, pool.Stats().Size)
+	err = pool.Add(user)
+	assert.Nil(err)
+}
+
+func mempoolTestTransaction(hash crypto.Hash, index int, kernel bool) *common.VersionedTransaction {
+	tx := common.NewTransaction(common.XINAssetId)
+	tx.AddInput(hash, index)
+	if kernel {
+		tx.AddOutputWithType(common.OutputTypeNodePledge, nil, common.Script{}, common.NewInteger(10000), []byte{})
+	} else {
+		tx.AddOutputWithType(common.OutputTypeScript, nil, common.Script{}, common.NewInteger(1), []byte{})
+	}
+	return tx.AsLatestVersion()
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
 	"github.com/MixinNetwork/mixin/logger"
 )
 
@@ -37,14 +36,13 @@ func init() {
 func (node *Node) MintLoop() {
 	defer close(node.mlc)
 
-	ticker := time.NewTicker(time.Duration(node.custom.Node.KernelOprationPeriod) * time.Second)
-	defer ticker.Stop()
+	period := time.Duration(node.custom.Node.KernelOprationPeriod) * time.Second
 
 	for {
 		select {
 		case <-node.done:
 			return
-		case <-ticker.C:
+		case <-node.clock.After(period):
 			batch, amount := node.checkMintPossibility(node.GraphTimestamp, false)
 			if amount.Sign() <= 0 || batch <= 0 {
 				continue
@@ -95,7 +93,7 @@ func pledgeAmount(sinceEpoch time.Duration) common.Integer {
 }
 
 func (node *Node) tryToMintKernelNode(batch uint64, amount common.Integer) error {
-	nodes := node.sortMintNodes(uint64(time.Now().UnixNano()))
+	nodes := node.sortMintNodes(uint64(node.clock.Now().UnixNano()))
 	per := amount.Div(len(nodes))
 	diff := amount.Sub(per.Mul(len(nodes)))
 
@@ -142,15 +140,15 @@ func (node *Node) tryToMintKernelNode(batch uint64, amount common.Integer) error
 func (node *Node) validateMintSnapshot(snap *common.Snapshot, tx *common.VersionedTransaction) error {
 	timestamp := snap.Timestamp
 	if snap.Timestamp == 0 && snap.NodeId == node.IdForNetwork {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
 	batch, amount := node.checkMintPossibility(timestamp, true)
 	if amount.Sign() <= 0 || batch <= 0 {
-		return fmt.Errorf("no mint available %d %s", batch, amount.String())
+		return common.NewValidationError(common.ErrorCodeState, "no mint available %d %s", batch, amount.String())
 	}
 	mint := tx.Inputs[0].Mint
 	if mint.Batch != uint64(batch) || mint.Amount.Cmp(amount) != 0 {
-		return fmt.Errorf("invalid mint data %d %s", batch, amount.String())
+		return common.NewValidationError(common.ErrorCodeState, "invalid mint data %d %s", batch, amount.String())
 	}
 
 	nodes := node.sortMintNodes(timestamp)
@@ -159,27 +157,27 @@ func (node *Node) validateMintSnapshot(snap *common.Snapshot, tx *common.Version
 
 	if diff.Sign() > 0 {
 		if len(nodes)+1 != len(tx.Outputs) {
-			return fmt.Errorf("invalid mint outputs count with diff %d %d %s %s", len(nodes), len(tx.Outputs), per, diff)
+			return common.NewValidationError(common.ErrorCodeFormat, "invalid mint outputs count with diff %d %d %s %s", len(nodes), len(tx.Outputs), per, diff)
 		}
 		out := tx.Outputs[len(nodes)]
 		if diff.Cmp(out.Amount) != 0 {
-			return fmt.Errorf("invalid mint diff %s", diff.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff %s", diff.String())
 		}
 		if out.Type != common.OutputTypeScript {
-			return fmt.Errorf("invalid mint diff type %d", out.Type)
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff type %d", out.Type)
 		}
 		if out.Script.String() != common.NewThresholdScript(common.Operator64).String() {
-			return fmt.Errorf("invalid mint diff script %s", out.Script.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff script %s", out.Script.String())
 		}
 		if len(out.Keys) != 1 {
-			return fmt.Errorf("invalid mint diff keys %d", len(out.Keys))
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff keys %d", len(out.Keys))
 		}
 		addr := common.NewAddressFromSeed(make([]byte, 64))
 		in := fmt.Sprintf("MINTKERNELNODE%dDIFF", mint.Batch)
 		seed := crypto.NewHash([]byte(addr.String() + in))
 		r := crypto.PrivateKeyFromSeed(append(seed[:], seed[:]...))
 		if r.Public().Key() != out.Mask {
-			return fmt.Errorf("invalid mint diff mask %s %s", r.Public().String(), out.Mask.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff mask %s %s", r.Public().String(), out.Mask.String())
 		}
 		oMask, err := out.Mask.AsPublicKey()
 		if err != nil {
@@ -191,11 +189,11 @@ func (node *Node) validateMintSnapshot(snap *common.Snapshot, tx *common.Version
 		}
 		ghost := crypto.ViewGhostOutputKey(oMask, oKey, addr.PrivateViewKey, uint64(len(nodes)))
 		if ghost.Key() != addr.PublicSpendKey.Key() {
-			return fmt.Errorf("invalid mint diff signature %s %s", addr.PublicSpendKey.String(), ghost.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint diff signature %s %s", addr.PublicSpendKey.String(), ghost.String())
 		}
 		return nil
 	} else if len(nodes) != len(tx.Outputs) {
-		return fmt.Errorf("invalid mint outputs count %d %d", len(nodes), len(tx.Outputs))
+		return common.NewValidationError(common.ErrorCodeFormat, "invalid mint outputs count %d %d", len(nodes), len(tx.Outputs))
 	}
 
 	for i, out := range tx.Outputs {
@@ -203,23 +201,23 @@ func (node *Node) validateMintSnapshot(snap *common.Snapshot, tx *common.Version
 			break
 		}
 		if out.Type != common.OutputTypeScript {
-			return fmt.Errorf("invalid mint output type %d", out.Type)
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint output type %d", out.Type)
 		}
 		if per.Cmp(out.Amount) != 0 {
-			return fmt.Errorf("invalid mint output amount %s %s", per.String(), out.Amount.String())
+			return common.NewValidationError(common.ErrorCodeAmount, "invalid mint output amount %s %s", per.String(), out.Amount.String())
 		}
 		if out.Script.String() != common.NewThresholdScript(1).String() {
-			return fmt.Errorf("invalid mint output script %s", out.Script.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint output script %s", out.Script.String())
 		}
 		if len(out.Keys) != 1 {
-			return fmt.Errorf("invalid mint output keys %d", len(out.Keys))
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint output keys %d", len(out.Keys))
 		}
 		n := nodes[i]
 		in := fmt.Sprintf("MINTKERNELNODE%d", mint.Batch)
 		seed := crypto.NewHash([]byte(n.Signer.String() + in))
 		r := crypto.PrivateKeyFromSeed(append(seed[:], seed[:]...))
 		if r.Public().Key() != out.Mask {
-			return fmt.Errorf("invalid mint output mask %s %s", r.Public().String(), out.Mask.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint output mask %s %s", r.Public().String(), out.Mask.String())
 		}
 		oMask, err := out.Mask.AsPublicKey()
 		if err != nil {
@@ -231,7 +229,7 @@ func (node *Node) validateMintSnapshot(snap *common.Snapshot, tx *common.Version
 		}
 		ghost := crypto.ViewGhostOutputKey(oMask, oKey, n.Payee.PrivateViewKey, uint64(i))
 		if ghost.Key() != n.Payee.PublicSpendKey.Key() {
-			return fmt.Errorf("invalid mint output signature %s %s", n.Payee.PublicSpendKey.String(), ghost.String())
+			return common.NewValidationError(common.ErrorCodeOutput, "invalid mint output signature %s %s", n.Payee.PublicSpendKey.String(), ghost.String())
 		}
 	}
 
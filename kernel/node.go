@@ -12,7 +12,6 @@ import (
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
 	"github.com/MixinNetwork/mixin/logger"
 	"github.com/MixinNetwork/mixin/network"
 	"github.com/MixinNetwork/mixin/storage"
@@ -36,18 +35,23 @@ type Node struct {
 	ConsensusPledging    *CNode
 	GraphTimestamp       uint64
 
-	chains *chainsMap
+	chains  *chainsMap
+	mempool *Mempool
+	skews   *clockSkewMap
 
 	genesisNodesMap map[crypto.Hash]bool
 	genesisNodes    []crypto.Hash
 	Epoch           uint64
 	startAt         time.Time
 	networkId       crypto.Hash
+	genesisStamp    int
 	persistStore    storage.Store
 	cacheStore      *fastcache.Cache
 	custom          *config.Custom
 	configDir       string
 	addr            string
+	clock           Clock
+	transports      network.TransportFactory
 
 	done chan struct{}
 	elc  chan struct{}
@@ -63,17 +67,20 @@ type CNode struct {
 	State        string
 }
 
-func SetupNode(custom *config.Custom, persistStore storage.Store, cacheStore *fastcache.Cache, addr string, dir string) (*Node, error) {
+func SetupNode(custom *config.Custom, persistStore storage.Store, cacheStore *fastcache.Cache, clock Clock, addr string, dir string) (*Node, error) {
 	var node = &Node{
 		SyncPoints:      &syncMap{mutex: new(sync.RWMutex), m: make(map[crypto.Hash]*network.SyncPoint)},
 		ConsensusIndex:  -1,
 		chains:          &chainsMap{m: make(map[crypto.Hash]*Chain)},
+		mempool:         NewMempool(MempoolSizeLimit),
+		skews:           newClockSkewMap(),
 		genesisNodesMap: make(map[crypto.Hash]bool),
 		persistStore:    persistStore,
 		cacheStore:      cacheStore,
 		custom:          custom,
 		configDir:       dir,
 		addr:            addr,
+		clock:           clock,
 		startAt:         clock.Now(),
 		done:            make(chan struct{}),
 		elc:             make(chan struct{}),
@@ -103,6 +110,10 @@ func SetupNode(custom *config.Custom, persistStore storage.Store, cacheStore *fa
 		return nil, err
 	}
 
+	node.persistStore.SubscribeConflicts(func(c *common.UTXOConflict) {
+		logger.Verbosef("UTXO CONFLICT %s:%d LOCKER %s SPENDER %s PRUNED %t\n", c.Hash, c.Index, c.Locker, c.Spender, c.Pruned)
+	})
+
 	err = node.LoadAllChains(node.persistStore, node.networkId)
 	if err != nil {
 		return nil, err
@@ -133,11 +144,14 @@ func (node *Node) LoadNodeConfig() {
 
 func (node *Node) ConsensusKeys(timestamp uint64) []crypto.PublicKey {
 	if timestamp == 0 {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
+	return node.consensusKeys(node.AllNodesSorted, timestamp)
+}
 
+func (node *Node) consensusKeys(nodes []*CNode, timestamp uint64) []crypto.PublicKey {
 	var keys []crypto.PublicKey
-	for _, cn := range node.AllNodesSorted {
+	for _, cn := range nodes {
 		if cn.State != common.NodeStateAccepted {
 			continue
 		}
@@ -150,10 +164,14 @@ func (node *Node) ConsensusKeys(timestamp uint64) []crypto.PublicKey {
 
 func (node *Node) ConsensusThreshold(timestamp uint64) int {
 	if timestamp == 0 {
-		timestamp = uint64(clock.Now().UnixNano())
+		timestamp = uint64(node.clock.Now().UnixNano())
 	}
+	return node.consensusThreshold(node.AllNodesSorted, timestamp)
+}
+
+func (node *Node) consensusThreshold(nodes []*CNode, timestamp uint64) int {
 	consensusBase := 0
-	for _, cn := range node.AllNodesSorted {
+	for _, cn := range nodes {
 		threshold := config.SnapshotReferenceThreshold * config.SnapshotRoundGap
 		if threshold > uint64(3*time.Minute) {
 			panic("should never be here")
@@ -238,8 +256,18 @@ func (node *Node) ConsensusRemovedRecently(timestamp uint64) *CNode {
 	return nil
 }
 
+// SetTransportFactory replaces the default QUIC transports of the node peer,
+// it must be called before the node starts to ping neighbors.
+func (node *Node) SetTransportFactory(f network.TransportFactory) {
+	node.transports = f
+}
+
 func (node *Node) PingNeighborsFromConfig() error {
 	node.Peer = network.NewPeer(node, node.IdForNetwork, node.addr, node.custom.Network.GossipNeighbors)
+	node.Peer.SetClock(node.clock)
+	if node.transports != nil {
+		node.Peer.SetTransportFactory(node.transports)
+	}
 
 	f, err := ioutil.ReadFile(node.configDir + "/nodes.json")
 	if err != nil {
@@ -281,7 +309,7 @@ func (node *Node) NetworkId() crypto.Hash {
 }
 
 func (node *Node) Uptime() time.Duration {
-	return clock.Now().Sub(node.startAt)
+	return node.clock.Now().Sub(node.startAt)
 }
 
 func (node *Node) GetCacheStore() *fastcache.Cache {
@@ -332,7 +360,7 @@ func (node *Node) BuildGraphWithPoolInfo() []map[string]interface{} {
 
 func (node *Node) BuildAuthenticationMessage() []byte {
 	data := make([]byte, 8)
-	binary.BigEndian.PutUint64(data, uint64(clock.Now().Unix()))
+	binary.BigEndian.PutUint64(data, uint64(node.clock.Now().Unix()))
 	pubSpendKey := node.Signer.PublicSpendKey.Key()
 	data = append(data, pubSpendKey[:]...)
 	sig, err := node.Signer.PrivateSpendKey.Sign(data)
@@ -348,8 +376,8 @@ func (node *Node) Authenticate(msg []byte) (crypto.Hash, string, error) {
 		return crypto.Hash{}, "", fmt.Errorf("peer authentication message malformated %d", len(msg))
 	}
 	ts := binary.BigEndian.Uint64(msg[:8])
-	if clock.Now().Unix()-int64(ts) > 3 {
-		return crypto.Hash{}, "", fmt.Errorf("peer authentication message timeout %d %d", ts, clock.Now().Unix())
+	if node.clock.Now().Unix()-int64(ts) > 3 {
+		return crypto.Hash{}, "", fmt.Errorf("peer authentication message timeout %d %d", ts, node.clock.Now().Unix())
 	}
 
 	signerPubSpend, err := crypto.PublicKeyFromString(hex.EncodeToString(msg[8 : 8+crypto.KeySize]))
@@ -398,6 +426,11 @@ func (node *Node) SendTransactionToPeer(peerId, hash crypto.Hash) error {
 }
 
 func (node *Node) CachePutTransaction(peerId crypto.Hash, tx *common.VersionedTransaction) error {
+	err := tx.ValidateStamp(node.StampDifficulty())
+	if err != nil {
+		logger.Verbosef("CachePutTransaction REJECT %s %s %d %s\n", peerId, tx.PayloadHash(), common.ValidationErrorCode(err), err.Error())
+		return err
+	}
 	return node.persistStore.CachePutTransaction(tx)
 }
 
@@ -475,7 +508,7 @@ func (node *Node) CheckCatchUpWithPeers() bool {
 			logger.Verbosef("CheckCatchUpWithPeers local(%s) != remote(%s)\n", cf.Hash, remote.Hash)
 			return false
 		}
-		if now := uint64(clock.Now().UnixNano()); cf.Start+config.SnapshotRoundGap*100 > now {
+		if now := uint64(node.clock.Now().UnixNano()); cf.Start+config.SnapshotRoundGap*100 > now {
 			logger.Verbosef("CheckCatchUpWithPeers local start(%d)+%d > now(%d)\n", cf.Start, config.SnapshotRoundGap*100, now)
 			return false
 		}
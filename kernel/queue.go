@@ -2,35 +2,39 @@ package kernel
 
 import (
 	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/logger"
 )
 
 func (node *Node) QueueTransaction(tx *common.VersionedTransaction) (string, error) {
-	err := tx.Validate(node.persistStore)
+	err := tx.ValidateStamp(node.StampDifficulty())
 	if err != nil {
 		return "", err
 	}
-	err = node.persistStore.CachePutTransaction(tx)
+	err = tx.Validate(node.persistStore)
+	if err != nil {
+		return "", err
+	}
+	err = node.mempool.Add(tx)
 	if err != nil {
 		return "", err
 	}
-	chain := node.GetOrCreateChain(node.IdForNetwork)
-	s := &common.Snapshot{
-		Version:     common.SnapshotVersion,
-		NodeId:      node.IdForNetwork,
-		Transaction: tx.PayloadHash(),
+	err = node.persistStore.CachePutTransaction(tx)
+	if err != nil {
+		node.mempool.Remove(tx.PayloadHash())
+		return "", err
 	}
-	err = chain.AppendSelfEmpty(s)
-	return tx.PayloadHash().String(), err
+	return tx.PayloadHash().String(), nil
 }
 
+// LoadCacheToQueue puts the cached transactions back to the mempool, those
+// rejected by the mempool, e.g. double spends or the pool full, are skipped
+// and logged, the other transactions are still loaded.
 func (node *Node) LoadCacheToQueue() error {
-	chain := node.GetOrCreateChain(node.IdForNetwork)
 	return node.persistStore.CacheListTransactions(func(tx *common.VersionedTransaction) error {
-		s := &common.Snapshot{
-			Version:     common.SnapshotVersion,
-			NodeId:      node.IdForNetwork,
-			Transaction: tx.PayloadHash(),
+		err := node.mempool.Add(tx)
+		if err != nil {
+			logger.Printf("LoadCacheToQueue(%s) DROP %s\n", tx.PayloadHash(), err)
 		}
-		return chain.AppendSelfEmpty(s)
+		return nil
 	})
 }
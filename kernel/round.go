@@ -203,15 +203,15 @@ func (c *CacheRound) ValidateSnapshot(s *common.Snapshot, add bool) error {
 	}
 	for _, cs := range c.Snapshots {
 		if cs.Hash == s.Hash || cs.Timestamp == s.Timestamp {
-			return fmt.Errorf("ValidateSnapshot error duplication %s %d", s.Hash, s.Timestamp)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "ValidateSnapshot error duplication %s %d", s.Hash, s.Timestamp)
 		}
 	}
 	if start, end := c.Gap(); start <= end {
 		if s.Timestamp < start && s.Timestamp+config.SnapshotRoundGap <= end {
-			return fmt.Errorf("ValidateSnapshot error gap start %s %d %d %d", s.Hash, s.Timestamp, start, end)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "ValidateSnapshot error gap start %s %d %d %d", s.Hash, s.Timestamp, start, end)
 		}
 		if s.Timestamp > end && start+config.SnapshotRoundGap <= s.Timestamp {
-			return fmt.Errorf("ValidateSnapshot error gap end %s %d %d %d", s.Hash, s.Timestamp, start, end)
+			return common.NewValidationError(common.ErrorCodeSnapshot, "ValidateSnapshot error gap end %s %d %d %d", s.Hash, s.Timestamp, start, end)
 		}
 	}
 	if add {
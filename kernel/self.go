@@ -7,7 +7,6 @@ import (
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/crypto"
-	"github.com/MixinNetwork/mixin/kernel/internal/clock"
 	"github.com/MixinNetwork/mixin/logger"
 )
 
@@ -25,12 +24,15 @@ func (node *Node) checkCacheSnapshotTransaction(s *common.Snapshot) (*common.Ver
 		return nil, false, err
 	}
 
-	err = tx.Validate(node.persistStore)
-	if err != nil {
-		return nil, false, err
+	err = tx.ValidateStamp(node.stampDifficulty(s))
+	if err == nil {
+		err = tx.Validate(node.persistStore)
+	}
+	if err == nil {
+		err = node.validateKernelSnapshot(s, tx, false)
 	}
-	err = node.validateKernelSnapshot(s, tx, false)
 	if err != nil {
+		logger.Verbosef("checkCacheSnapshotTransaction REJECT %s %s %s %d %s\n", s.NodeId, s.Hash, s.Transaction, common.ValidationErrorCode(err), err.Error())
 		return nil, false, err
 	}
 
@@ -42,6 +44,15 @@ func (node *Node) checkCacheSnapshotTransaction(s *common.Snapshot) (*common.Ver
 	return tx, false, node.persistStore.WriteTransaction(tx)
 }
 
+// snapshotTimestamp returns the timestamp to validate the snapshot with, the
+// self snapshot not announced yet uses the clock.
+func (node *Node) snapshotTimestamp(s *common.Snapshot) uint64 {
+	if s.Timestamp == 0 && s.NodeId == node.IdForNetwork {
+		return uint64(node.clock.Now().UnixNano())
+	}
+	return s.Timestamp
+}
+
 func (node *Node) validateKernelSnapshot(s *common.Snapshot, tx *common.VersionedTransaction, finalized bool) error {
 	switch tx.TransactionType() {
 	case common.TransactionTypeMint:
@@ -74,9 +85,27 @@ func (node *Node) validateKernelSnapshot(s *common.Snapshot, tx *common.Versione
 			logger.Verbosef("validateNodeRemoveSnapshot ERROR %v %s %s\n", s, hex.EncodeToString(tx.PayloadMarshal()), err.Error())
 			return err
 		}
+	case common.TransactionTypeDeposit:
+		err := node.validateDepositSnapshot(s, tx)
+		if err != nil {
+			logger.Verbosef("validateDepositSnapshot ERROR %v %s %s\n", s, hex.EncodeToString(tx.PayloadMarshal()), err.Error())
+			return err
+		}
+	case common.TransactionTypeWithdrawalSubmit:
+		err := node.validateWithdrawalSubmitSnapshot(s, tx)
+		if err != nil {
+			logger.Verbosef("validateWithdrawalSubmitSnapshot ERROR %v %s %s\n", s, hex.EncodeToString(tx.PayloadMarshal()), err.Error())
+			return err
+		}
+	case common.TransactionTypeDomainAccept, common.TransactionTypeDomainRemove:
+		err := node.validateDomainSnapshot(s, tx)
+		if err != nil {
+			logger.Verbosef("validateDomainSnapshot ERROR %v %s %s\n", s, hex.EncodeToString(tx.PayloadMarshal()), err.Error())
+			return err
+		}
 	}
 	if s.NodeId != node.IdForNetwork && s.RoundNumber == 0 && tx.TransactionType() != common.TransactionTypeNodeAccept {
-		return fmt.Errorf("invalid initial transaction type %d", tx.TransactionType())
+		return common.NewValidationError(common.ErrorCodeType, "invalid initial transaction type %d", tx.TransactionType())
 	}
 	return nil
 }
@@ -118,11 +147,11 @@ func (chain *Chain) determinBestRound(roundTime uint64, hint crypto.Hash) (*Fina
 			}
 			return nil, fmt.Errorf("external hint round too early yet not genesis %d", r.Number)
 		}
-		if ts := rts + config.SnapshotRoundGap*rh; ts > uint64(clock.Now().UnixNano()) {
+		if ts := rts + config.SnapshotRoundGap*rh; ts > uint64(chain.clock.Now().UnixNano()) {
 			if id != hint {
 				continue
 			}
-			return nil, fmt.Errorf("external hint round timestamp too future %d %d", ts, clock.Now().UnixNano())
+			return nil, fmt.Errorf("external hint round timestamp too future %d %d", ts, chain.clock.Now().UnixNano())
 		}
 		if len(cr.Snapshots) == 0 && cr.Number == r.Number+1 && r.Number > 0 {
 			if id != hint {
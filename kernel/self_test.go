@@ -42,3 +42,51 @@ func TestDeterminBestRound(t *testing.T) {
 	assert.Nil(err)
 	assert.NotNil(best)
 }
+
+func TestDeterminBestRoundClockSkew(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-self-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	clock := NewMockClock(NewRealClock())
+	node := setupTestNodeWithClock(assert, root, clock)
+	assert.NotNil(node)
+
+	chain := node.GetOrCreateChain(node.genesisNodes[0])
+	hint := node.genesisNodes[1]
+	now := uint64(time.Now().UnixNano())
+	best, err := chain.determinBestRound(now, hint)
+	assert.Nil(err)
+	assert.NotNil(best)
+
+	epoch := time.Unix(0, int64(node.Epoch))
+	clock.MockDiff(epoch.Sub(time.Now()))
+	best, err = chain.determinBestRound(now, hint)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "external hint round timestamp too future")
+	assert.Nil(best)
+
+	clock.Reset()
+	best, err = chain.determinBestRound(now, hint)
+	assert.Nil(err)
+	assert.NotNil(best)
+}
+
+func TestClock(t *testing.T) {
+	assert := assert.New(t)
+
+	base := NewSourceClock(func() time.Time { return time.Unix(1551312000, 0) })
+	assert.Equal(int64(1551312000), base.Now().Unix())
+
+	skewed := NewSkewedClock(base, -time.Minute)
+	assert.Equal(int64(1551312000-60), skewed.Now().Unix())
+
+	mock := NewMockClock(skewed)
+	mock.MockDiff(time.Hour)
+	mock.MockDiff(time.Minute)
+	assert.Equal(int64(1551312000+3600), mock.Now().Unix())
+	mock.Reset()
+	assert.Equal(skewed.Now(), mock.Now())
+}
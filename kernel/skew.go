@@ -0,0 +1,113 @@
+package kernel
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/logger"
+)
+
+const (
+	ClockSkewThreshold        = time.Duration(config.SnapshotRoundGap)
+	ClockSkewPeersMinimum     = 3
+	ClockSkewSamplesPerPeer   = 16
+	ClockSkewSampleExpiration = 10 * time.Minute
+)
+
+type ClockSkew struct {
+	Offset time.Duration
+	Peers  int
+	Skewed bool
+}
+
+type clockSkewPeer struct {
+	offsets []time.Duration
+	at      time.Time
+}
+
+// clockSkewMap estimates the local clock offset to the peers, from the
+// nanosecond timestamps of the snapshot announcements, which are produced
+// with the peer local clock right before sending. The authentication messages
+// are not sampled, their timestamps are truncated to seconds.
+type clockSkewMap struct {
+	sync.Mutex
+	peers  map[crypto.Hash]*clockSkewPeer
+	skewed bool
+}
+
+func newClockSkewMap() *clockSkewMap {
+	return &clockSkewMap{peers: make(map[crypto.Hash]*clockSkewPeer)}
+}
+
+func (m *clockSkewMap) sample(peerId crypto.Hash, remote, local time.Time) {
+	m.Lock()
+	defer m.Unlock()
+
+	p := m.peers[peerId]
+	if p == nil {
+		p = &clockSkewPeer{}
+		m.peers[peerId] = p
+	}
+	p.offsets = append(p.offsets, remote.Sub(local))
+	if len(p.offsets) > ClockSkewSamplesPerPeer {
+		p.offsets = p.offsets[len(p.offsets)-ClockSkewSamplesPerPeer:]
+	}
+	p.at = local
+}
+
+// estimate returns the median of all recent peer offsets, and each peer
+// offset is the median of its samples, so a few skewed or malicious peers
+// won't affect the estimation.
+func (m *clockSkewMap) estimate(now time.Time) (time.Duration, int) {
+	m.Lock()
+	defer m.Unlock()
+
+	offsets := make([]time.Duration, 0)
+	for id, p := range m.peers {
+		if now.Sub(p.at) > ClockSkewSampleExpiration {
+			delete(m.peers, id)
+			continue
+		}
+		offsets = append(offsets, medianDuration(p.offsets))
+	}
+	return medianDuration(offsets), len(offsets)
+}
+
+func (m *clockSkewMap) check(now time.Time) *ClockSkew {
+	offset, peers := m.estimate(now)
+	cs := &ClockSkew{Offset: offset, Peers: peers}
+	if peers >= ClockSkewPeersMinimum && (offset > ClockSkewThreshold || -offset > ClockSkewThreshold) {
+		cs.Skewed = true
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if cs.Skewed != m.skewed {
+		logger.Printf("CLOCK SKEW %t %s %d\n", cs.Skewed, offset, peers)
+	}
+	m.skewed = cs.Skewed
+	return cs
+}
+
+func medianDuration(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func (node *Node) sampleClockSkew(peerId crypto.Hash, timestamp uint64) {
+	node.skews.sample(peerId, time.Unix(0, int64(timestamp)), node.clock.Now())
+}
+
+// ClockSkew estimates the local clock offset to the peers, a positive offset
+// means the local clock is behind the peers. The node refuses to lead cosi
+// rounds when skewed, because the rounds would be rejected by the peers.
+func (node *Node) ClockSkew() *ClockSkew {
+	return node.skews.check(node.clock.Now())
+}
@@ -0,0 +1,63 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockSkew(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1551312000, 0)
+	m := newClockSkewMap()
+	cs := m.check(now)
+	assert.Equal(time.Duration(0), cs.Offset)
+	assert.Equal(0, cs.Peers)
+	assert.False(cs.Skewed)
+
+	for i := 0; i < 4; i++ {
+		id := crypto.NewHash([]byte{byte(i)})
+		for j := 0; j < 32; j++ {
+			m.sample(id, now.Add(10*time.Second+time.Duration(j)*time.Millisecond), now)
+		}
+		assert.Len(m.peers[id].offsets, ClockSkewSamplesPerPeer)
+	}
+	cs = m.check(now)
+	assert.Equal(4, cs.Peers)
+	assert.Equal(10*time.Second+24*time.Millisecond, cs.Offset)
+	assert.True(cs.Skewed)
+
+	bad := crypto.NewHash([]byte("bad"))
+	m.sample(bad, now.Add(-time.Hour), now)
+	cs = m.check(now)
+	assert.Equal(5, cs.Peers)
+	assert.True(cs.Offset > ClockSkewThreshold)
+	assert.True(cs.Skewed)
+
+	for i := 0; i < 4; i++ {
+		id := crypto.NewHash([]byte{byte(i)})
+		m.sample(id, now.Add(time.Minute), now.Add(time.Minute))
+		m.sample(id, now.Add(time.Minute), now.Add(time.Minute))
+		m.sample(id, now.Add(time.Minute), now.Add(time.Minute))
+		m.peers[id].offsets = m.peers[id].offsets[ClockSkewSamplesPerPeer-3:]
+	}
+	cs = m.check(now.Add(time.Minute))
+	assert.Equal(5, cs.Peers)
+	assert.Equal(time.Duration(0), cs.Offset)
+	assert.False(cs.Skewed)
+
+	cs = m.check(now.Add(ClockSkewSampleExpiration + 2*time.Minute))
+	assert.Equal(0, cs.Peers)
+	assert.False(cs.Skewed)
+
+	m.sample(bad, now.Add(-time.Hour), now)
+	cs = m.check(now)
+	assert.Equal(1, cs.Peers)
+	assert.Equal(-time.Hour, cs.Offset)
+	assert.False(cs.Skewed)
+}
@@ -0,0 +1,37 @@
+package kernel
+
+import (
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+)
+
+// The networks with the anti spam difficulty in the genesis require the stamp
+// of that difficulty since the genesis. All other networks, including the
+// mainnet, require the stamp of StampForkDifficulty after StampForkTimestamp,
+// so they don't need a new genesis for it.
+const (
+	StampForkTimestamp  = uint64(1798761600 * time.Second)
+	StampForkDifficulty = 16
+)
+
+// stampDifficulty returns the anti spam difficulty of the script transactions
+// in the snapshot.
+func (node *Node) stampDifficulty(s *common.Snapshot) int {
+	return node.stampDifficultyAt(node.snapshotTimestamp(s))
+}
+
+func (node *Node) stampDifficultyAt(timestamp uint64) int {
+	if node.genesisStamp >= 0 {
+		return node.genesisStamp
+	}
+	if timestamp < StampForkTimestamp {
+		return 0
+	}
+	return StampForkDifficulty
+}
+
+// StampDifficulty returns the anti spam difficulty required by now.
+func (node *Node) StampDifficulty() int {
+	return node.stampDifficultyAt(uint64(node.clock.Now().UnixNano()))
+}
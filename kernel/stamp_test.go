@@ -0,0 +1,40 @@
+// +build ed25519 !custom_alg
+
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStampDifficulty(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := NewMockClock(NewRealClock())
+	node := &Node{
+		IdForNetwork: crypto.NewHash([]byte("stamp-node")),
+		clock:        clock,
+		genesisStamp: -1,
+	}
+	assert.Equal(0, node.stampDifficultyAt(StampForkTimestamp-1))
+	assert.Equal(StampForkDifficulty, node.stampDifficultyAt(StampForkTimestamp))
+
+	s := &common.Snapshot{NodeId: crypto.NewHash([]byte("stamp-peer")), Timestamp: StampForkTimestamp}
+	assert.Equal(StampForkDifficulty, node.stampDifficulty(s))
+	s.Timestamp = 0
+	assert.Equal(0, node.stampDifficulty(s))
+	s.NodeId = node.IdForNetwork
+	clock.MockDiff(time.Duration(StampForkTimestamp - uint64(clock.Now().UnixNano())))
+	assert.Equal(StampForkDifficulty, node.stampDifficulty(s))
+	assert.Equal(StampForkDifficulty, node.StampDifficulty())
+
+	node.genesisStamp = 0
+	assert.Equal(0, node.stampDifficultyAt(StampForkTimestamp))
+	node.genesisStamp = 8
+	assert.Equal(8, node.stampDifficultyAt(0))
+	assert.Equal(8, node.StampDifficulty())
+}
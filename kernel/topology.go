@@ -23,20 +23,20 @@ func (node *Node) SPS() float64 {
 	return node.TopoCounter.sps
 }
 
-func (node *Node) TopoWrite(s *common.Snapshot) *common.SnapshotWithTopologicalOrder {
+func (node *Node) TopoWrite(s *common.Snapshot) (*common.SnapshotWithTopologicalOrder, error) {
 	node.TopoCounter.Lock()
 	defer node.TopoCounter.Unlock()
 
-	node.TopoCounter.seq += 1
 	topo := &common.SnapshotWithTopologicalOrder{
 		Snapshot:         *s,
-		TopologicalOrder: node.TopoCounter.seq,
+		TopologicalOrder: node.TopoCounter.seq + 1,
 	}
 	err := node.persistStore.WriteSnapshot(topo)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return topo
+	node.TopoCounter.seq += 1
+	return topo, nil
 }
 
 func (topo *TopologicalSequence) TopoStats() {
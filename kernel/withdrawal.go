@@ -0,0 +1,69 @@
+package kernel
+
+import (
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains"
+	"github.com/MixinNetwork/mixin/domains/bitcoin"
+)
+
+// MainnetDomainsForkTimestamp is when the new deposit amount, withdrawal
+// address and tag rules of the domains take effect on mainnet, e.g. the token
+// decimals, the bitcoin taproot addresses and the empty tags, other networks
+// use the new rules since the genesis.
+const MainnetDomainsForkTimestamp = uint64(1796083200 * time.Second)
+
+// BitcoinColoredAssets are the bitcoin colored asset keys besides the omni
+// usdt, and the timestamps when they are accepted on mainnet, other networks
+// accept them since the genesis.
+var BitcoinColoredAssets = map[string]uint64{}
+
+func (node *Node) domainsForked(timestamp uint64) bool {
+	if node.networkId.String() == config.MainnetId {
+		return timestamp >= MainnetDomainsForkTimestamp
+	}
+	return true
+}
+
+func (node *Node) validateDomainAsset(chain crypto.Hash, assetKey string, timestamp uint64) error {
+	if chain != bitcoin.BitcoinChainId || node.networkId.String() != config.MainnetId {
+		return nil
+	}
+	if fork, found := BitcoinColoredAssets[assetKey]; found && timestamp < fork {
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid bitcoin asset key %s before %d", assetKey, fork)
+	}
+	return nil
+}
+
+func (node *Node) validateWithdrawalSubmitSnapshot(s *common.Snapshot, tx *common.VersionedTransaction) error {
+	submit := tx.Outputs[0].Withdrawal
+	d := domains.Get(submit.Chain)
+	if d == nil {
+		return common.NewValidationError(common.ErrorCodeAsset, "invalid withdrawal chain %s", submit.Chain)
+	}
+	timestamp := node.snapshotTimestamp(s)
+	err := node.validateDomainAsset(submit.Chain, submit.AssetKey, timestamp)
+	if err != nil {
+		return err
+	}
+	fork := node.domainsForked(timestamp)
+	err = d.VerifyAddress(submit.Address, fork)
+	if err != nil {
+		return common.NewValidationError(common.ErrorCodeOutput, "invalid withdrawal address %s", err.Error())
+	}
+	err = d.VerifyTag(submit.Address, submit.Tag, fork)
+	if err != nil {
+		return common.NewValidationError(common.ErrorCodeOutput, "invalid withdrawal tag %s", err.Error())
+	}
+	if !fork {
+		return nil
+	}
+	err = d.VerifyAmount(submit.AssetKey, tx.Outputs[0].Amount.String())
+	if err != nil {
+		return common.NewValidationError(common.ErrorCodeAmount, "invalid withdrawal amount %s", err.Error())
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"runtime"
 	"time"
 
+	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/kernel"
 	"github.com/MixinNetwork/mixin/logger"
@@ -39,6 +41,16 @@ func main() {
 			Value: false,
 			Usage: "print the runtime",
 		},
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: "the address network prefix, e.g. XIN for mainnet and XTN for testnet, the kernel always uses the prefix of the genesis",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		if n := c.String("network"); n != "" {
+			return common.SetNetworkId(n)
+		}
+		return nil
 	}
 	app.EnableBashCompletion = true
 	app.Commands = []*cli.Command{
@@ -144,6 +156,26 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "deriveaddress",
+			Usage:  "Derive a child address from a seed or an extended key",
+			Action: deriveAddressCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "seed",
+					Usage: "the 64 bytes master seed `HEX`",
+				},
+				&cli.StringFlag{
+					Name:  "key",
+					Usage: "the extended key, a public or view extended key derives only non-hardened children",
+				},
+				&cli.StringFlag{
+					Name:  "path",
+					Value: "m",
+					Usage: "the derivation path, e.g. m/0'/1, where ' or h marks a hardened index",
+				},
+			},
+		},
 		{
 			Name:   "decryptghostkey",
 			Usage:  "Decrypt a ghost key with the private view key",
@@ -210,6 +242,46 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "repairgraph",
+			Usage:  "Repair the topology, snapshot, unique and link entries of the graph data storage, the kernel must not be running",
+			Action: repairGraphEntries,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "only report the issues without applying the fixes",
+				},
+			},
+		},
+		{
+			Name:   "scanoutputs",
+			Usage:  "Scan the graph data storage for outputs owned by the keys, the kernel must not be running",
+			Action: scanOutputsCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "view",
+					Usage: "the private view key",
+				},
+				&cli.StringFlag{
+					Name:  "spend",
+					Usage: "the private spend key, to derive the ghost private keys",
+				},
+				&cli.StringFlag{
+					Name:  "address",
+					Usage: "the address to scan watch-only without the private spend key",
+				},
+				&cli.Uint64Flag{
+					Name:  "offset",
+					Value: 0,
+					Usage: "the topology offset to start scanning",
+				},
+				&cli.Uint64Flag{
+					Name:  "count",
+					Value: math.MaxUint64 >> 1,
+					Usage: "the maximum snapshots count to scan",
+				},
+			},
+		},
 		{
 			Name:   "signrawtransaction",
 			Usage:  "Sign a JSON encoded transaction",
@@ -227,6 +299,66 @@ func main() {
 					Name:  "seed",
 					Usage: "the mask seed to hide the recipient public key",
 				},
+				&cli.IntFlag{
+					Name:  "stamp",
+					Usage: "the anti spam difficulty of the network, shown in getinfo",
+				},
+			},
+		},
+		{
+			Name:   "createpartialtransaction",
+			Usage:  "Create a partially signed transaction from a JSON encoded transaction, for offline signers",
+			Action: createPartialTransactionCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "raw",
+					Usage: "the JSON encoded raw transaction, inputs without keys, mask and script are read from the node",
+				},
+				&cli.StringFlag{
+					Name:  "seed",
+					Usage: "the mask seed to hide the recipient public key",
+				},
+				&cli.IntFlag{
+					Name:  "stamp",
+					Usage: "the anti spam difficulty of the network, shown in getinfo",
+				},
+			},
+		},
+		{
+			Name:   "signpartialtransaction",
+			Usage:  "Add signatures to a hex encoded partially signed transaction without network access",
+			Action: signPartialTransactionCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "partial",
+					Usage: "the hex encoded partially signed transaction",
+				},
+				&cli.StringSliceFlag{
+					Name:  "key",
+					Usage: "the private key to sign the partial transaction, could be repeated",
+				},
+			},
+		},
+		{
+			Name:   "combinepartialtransactions",
+			Usage:  "Combine the signatures of hex encoded partially signed transactions",
+			Action: combinePartialTransactionsCmd,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "partial",
+					Usage: "the hex encoded partially signed transaction, could be repeated",
+				},
+			},
+		},
+		{
+			Name:   "finalizepartialtransaction",
+			Usage:  "Finalize a hex encoded partially signed transaction as a signed raw transaction",
+			Action: finalizePartialTransactionCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "partial",
+					Usage: "the hex encoded partially signed transaction",
+				},
 			},
 		},
 		{
@@ -240,6 +372,17 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "validaterawtransaction",
+			Usage:  "Validate a hex encoded signed raw transaction without broadcasting",
+			Action: validateTransactionCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "raw",
+					Usage: "the hex encoded signed raw transaction",
+				},
+			},
+		},
 		{
 			Name:   "decoderawtransaction",
 			Usage:  "Decode a raw transaction as JSON",
@@ -444,6 +587,42 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:   "getconflicts",
+			Usage:  "Get the lock conflicts of a transaction, or of a UTXO if index present",
+			Action: getConflictsCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "hash",
+					Aliases: []string{"x"},
+					Usage:   "the transaction hash",
+				},
+				&cli.Uint64Flag{
+					Name:    "index",
+					Aliases: []string{"i"},
+					Usage:   "the output index",
+				},
+			},
+		},
+		{
+			Name:   "listconflicts",
+			Usage:  "List the lock conflicts recorded since the timestamp",
+			Action: listConflictsCmd,
+			Flags: []cli.Flag{
+				&cli.Uint64Flag{
+					Name:    "since",
+					Aliases: []string{"s"},
+					Value:   0,
+					Usage:   "the timestamp to begin with",
+				},
+				&cli.Uint64Flag{
+					Name:    "count",
+					Aliases: []string{"c"},
+					Value:   10,
+					Usage:   "the up limit of the returned conflicts",
+				},
+			},
+		},
 		{
 			Name:   "listmintdistributions",
 			Usage:  "List mint distributions",
@@ -484,11 +663,47 @@ func main() {
 			Usage:  "List all nodes ever existed",
 			Action: listAllNodesCmd,
 		},
+		{
+			Name:   "listconsensushistory",
+			Usage:  "List all consensus membership changes with the thresholds and keys",
+			Action: listConsensusHistoryCmd,
+		},
+		{
+			Name:   "listdomains",
+			Usage:  "List all domains ever accepted",
+			Action: listDomainsCmd,
+		},
+		{
+			Name:   "listdomaincustodies",
+			Usage:  "List the custody balances of all domains",
+			Action: listDomainCustodiesCmd,
+		},
 		{
 			Name:   "getinfo",
 			Usage:  "Get info from the node",
 			Action: getInfoCmd,
 		},
+		{
+			Name:   "getchainhealth",
+			Usage:  "Get the health of all chain loops in the node",
+			Action: getChainHealthCmd,
+		},
+		{
+			Name:   "controlchain",
+			Usage:  "Stop, start or restart a chain in the node, requires the admin RPC enabled",
+			Action: controlChainCmd,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "id",
+					Usage: "the chain node id",
+				},
+				&cli.StringFlag{
+					Name:  "action",
+					Value: "status",
+					Usage: "the chain action, stop, start, restart or status",
+				},
+			},
+		},
 		{
 			Name:   "dumpgraphhead",
 			Usage:  "Dump the graph head",
@@ -538,7 +753,7 @@ func cloneCmd(c *cli.Context) error {
 	}
 	defer source.Close()
 
-	node, err := kernel.SetupNode(custom, store, cache, ":12345", c.String("dir"))
+	node, err := kernel.SetupNode(custom, store, cache, kernel.NewRealClock(), ":12345", c.String("dir"))
 	if err != nil {
 		return err
 	}
@@ -580,11 +795,10 @@ func kernelCmd(c *cli.Context) error {
 	defer store.Close()
 
 	addr := fmt.Sprintf(":%d", c.Int("port"))
-	node, err := kernel.SetupNode(custom, store, cache, addr, c.String("dir"))
+	node, err := kernel.SetupNode(custom, store, cache, kernel.NewRealClock(), addr, c.String("dir"))
 	if err != nil {
 		return err
 	}
-
 	go func() {
 		server := rpc.NewServer(custom, store, node, c.Int("port")+1000)
 		err := server.ListenAndServe()
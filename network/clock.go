@@ -0,0 +1,25 @@
+package network
+
+import "time"
+
+// Clock is the time source of a peer, the kernel node shares its own clock
+// with the peer, so all the peer loops wait on the same clock as the node.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (c realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (c realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -119,7 +119,7 @@ func (me *Peer) SendTransactionMessage(idForNetwork crypto.Hash, ver *common.Ver
 func (me *Peer) ConfirmSnapshotForPeer(idForNetwork, snap crypto.Hash) {
 	key := append(idForNetwork[:], snap[:]...)
 	key = append(key, 'S', 'C', 'O')
-	me.snapshotsCaches.store(key, time.Now())
+	me.snapshotsCaches.store(key, me.clock.Now())
 }
 
 func buildAuthenticationMessage(data []byte) []byte {
@@ -0,0 +1,246 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+const (
+	MemoryClientBufferSize = 1024
+)
+
+// MemoryRouter decides when and whether a message sent in the memory network
+// is delivered, it may also change the message data before delivery.
+type MemoryRouter interface {
+	Route(msg *MemoryMessage)
+}
+
+type MemoryMessage struct {
+	From string
+	To   string
+	Data []byte
+
+	target *memoryClient
+}
+
+// MemoryNetwork is an in-process network to run many peers in one process,
+// all messages sent by its transports are handed to the router, so the router
+// has full control of the message ordering and failures. The receive and dial
+// timeouts are measured with the clock of the network.
+type MemoryNetwork struct {
+	sync.RWMutex
+	router    MemoryRouter
+	clock     Clock
+	listeners map[string]*memoryTransport
+}
+
+type memoryFactory struct {
+	network *MemoryNetwork
+	local   string
+}
+
+type memoryTransport struct {
+	network *MemoryNetwork
+	local   string
+	addr    string
+	accept  chan *memoryClient
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type memoryClient struct {
+	network *MemoryNetwork
+	local   string
+	remote  string
+	peer    *memoryClient
+	receive chan []byte
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type memoryAddr string
+
+func NewMemoryNetwork(router MemoryRouter, clock Clock) *MemoryNetwork {
+	return &MemoryNetwork{
+		router:    router,
+		clock:     clock,
+		listeners: make(map[string]*memoryTransport),
+	}
+}
+
+// Factory returns the transport factory for the peer listening on local,
+// the local address is used as the sender of all its messages.
+func (n *MemoryNetwork) Factory(local string) TransportFactory {
+	return &memoryFactory{network: n, local: local}
+}
+
+func (f *memoryFactory) NewServer(addr string) (Transport, error) {
+	return f.network.newTransport(f.local, addr), nil
+}
+
+func (f *memoryFactory) NewClient(addr string) (Transport, error) {
+	return f.network.newTransport(f.local, addr), nil
+}
+
+func (n *MemoryNetwork) newTransport(local, addr string) *memoryTransport {
+	return &memoryTransport{
+		network: n,
+		local:   local,
+		addr:    addr,
+		accept:  make(chan *memoryClient, MaxIncomingStreams),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (t *memoryTransport) Listen() error {
+	t.network.Lock()
+	defer t.network.Unlock()
+
+	if t.network.listeners[t.addr] != nil {
+		return fmt.Errorf("memory listen address already in use %s", t.addr)
+	}
+	t.network.listeners[t.addr] = t
+	return nil
+}
+
+func (t *memoryTransport) Dial(ctx context.Context) (Client, error) {
+	t.network.RLock()
+	l := t.network.listeners[t.addr]
+	t.network.RUnlock()
+	if l == nil {
+		select {
+		case <-t.network.clock.After(HandshakeTimeout):
+			return nil, fmt.Errorf("memory dial connection timeout %s", t.addr)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	client := t.network.newClient(t.local, t.addr)
+	server := t.network.newClient(t.addr, t.local)
+	client.peer, server.peer = server, client
+	select {
+	case l.accept <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, fmt.Errorf("memory dial connection refused %s", t.addr)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *memoryTransport) Accept(ctx context.Context) (Client, error) {
+	select {
+	case c := <-t.accept:
+		return c, nil
+	case <-t.closed:
+		return nil, fmt.Errorf("memory transport closed %s", t.addr)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *memoryTransport) Close() error {
+	t.once.Do(func() {
+		t.network.Lock()
+		if t.network.listeners[t.addr] == t {
+			delete(t.network.listeners, t.addr)
+		}
+		t.network.Unlock()
+		close(t.closed)
+	})
+	return nil
+}
+
+func (n *MemoryNetwork) newClient(local, remote string) *memoryClient {
+	return &memoryClient{
+		network: n,
+		local:   local,
+		remote:  remote,
+		receive: make(chan []byte, MemoryClientBufferSize),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *memoryClient) RemoteAddr() net.Addr {
+	return memoryAddr(c.remote)
+}
+
+func (c *memoryClient) Receive() ([]byte, error) {
+	select {
+	case data := <-c.receive:
+		return data, nil
+	default:
+	}
+
+	select {
+	case data := <-c.receive:
+		return data, nil
+	case <-c.closed:
+		return nil, fmt.Errorf("memory client closed %s", c.remote)
+	case <-c.network.clock.After(ReadDeadline):
+		return nil, fmt.Errorf("memory client receive timeout %s", c.remote)
+	}
+}
+
+func (c *memoryClient) Send(data []byte) error {
+	select {
+	case <-c.closed:
+		return fmt.Errorf("memory client closed %s", c.remote)
+	default:
+	}
+	if len(data) > TransportMessageMaxSize {
+		return fmt.Errorf("memory send invalid message size %d", len(data))
+	}
+	msg := &MemoryMessage{
+		From:   c.local,
+		To:     c.remote,
+		Data:   append([]byte{}, data...),
+		target: c.peer,
+	}
+	c.network.router.Route(msg)
+	return nil
+}
+
+// Close closes both ends of the connection, the remote end is still able
+// to receive all the messages delivered before closing.
+func (c *memoryClient) Close() error {
+	c.close()
+	if c.peer != nil {
+		c.peer.close()
+	}
+	return nil
+}
+
+func (c *memoryClient) close() {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+}
+
+// Deliver puts the message to the receiving connection, it fails if the
+// connection is closed or its buffer is full, like a congested network.
+func (m *MemoryMessage) Deliver() error {
+	c := m.target
+	select {
+	case <-c.closed:
+		return fmt.Errorf("memory client closed %s", c.remote)
+	default:
+	}
+	select {
+	case c.receive <- m.Data:
+		return nil
+	default:
+		return fmt.Errorf("memory client buffer full %s", c.remote)
+	}
+}
+
+func (a memoryAddr) Network() string {
+	return "memory"
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
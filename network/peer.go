@@ -28,6 +28,8 @@ type Peer struct {
 	pingFilter      *neighborMap
 	handle          SyncHandle
 	transport       Transport
+	transports      TransportFactory
+	clock           Clock
 	gossipNeighbors bool
 	highRing        *util.RingBuffer
 	normalRing      *util.RingBuffer
@@ -73,7 +75,7 @@ func (me *Peer) PingNeighbor(addr string) error {
 
 func (me *Peer) pingPeerStream(addr string) error {
 	logger.Verbosef("PING OPEN PEER STREAM %s\n", addr)
-	transport, err := NewQuicClient(addr)
+	transport, err := me.transports.NewClient(addr)
 	if err != nil {
 		return err
 	}
@@ -89,7 +91,7 @@ func (me *Peer) pingPeerStream(addr string) error {
 		return err
 	}
 	logger.Verbosef("PING AUTH PEER STREAM %s\n", addr)
-	time.Sleep(time.Duration(config.SnapshotRoundGap))
+	me.clock.Sleep(time.Duration(config.SnapshotRoundGap))
 	return nil
 }
 
@@ -134,16 +136,31 @@ func NewPeer(handle SyncHandle, idForNetwork crypto.Hash, addr string, gossipNei
 		normalRing:      util.NewRingBuffer(1024),
 		syncRing:        util.NewRingBuffer(1024),
 		handle:          handle,
+		transports:      quicTransportFactory{},
+		clock:           realClock{},
 		ops:             make(chan struct{}),
 		stn:             make(chan struct{}),
 	}
 	peer.ctx = context.Background() // FIXME use real context
 	if handle != nil {
-		peer.snapshotsCaches = &confirmMap{cache: handle.GetCacheStore()}
+		peer.snapshotsCaches = &confirmMap{cache: handle.GetCacheStore(), clock: peer.clock}
 	}
 	return peer
 }
 
+func (me *Peer) SetTransportFactory(f TransportFactory) {
+	me.transports = f
+}
+
+// SetClock replaces the wall clock of the peer, it must be called before
+// the peer starts any loop.
+func (me *Peer) SetClock(c Clock) {
+	me.clock = c
+	if me.snapshotsCaches != nil {
+		me.snapshotsCaches.clock = c
+	}
+}
+
 func (me *Peer) Teardown() {
 	me.closing = true
 	me.transport.Close()
@@ -164,7 +181,7 @@ func (me *Peer) Teardown() {
 }
 
 func (me *Peer) ListenNeighbors() error {
-	transport, err := NewQuicServer(me.Address)
+	transport, err := me.transports.NewServer(me.Address)
 	if err != nil {
 		return err
 	}
@@ -176,15 +193,12 @@ func (me *Peer) ListenNeighbors() error {
 	}
 
 	go func() {
-		ticker := time.NewTicker(time.Duration(config.SnapshotRoundGap))
-		defer ticker.Stop()
-
 		for !me.closing {
 			me.gossipRound.Clear()
-			rand.Seed(time.Now().UnixNano())
+			r := rand.New(rand.NewSource(me.clock.Now().UnixNano()))
 			neighbors := me.neighbors.Slice()
 			for i := range neighbors {
-				j := rand.Intn(i + 1)
+				j := r.Intn(i + 1)
 				neighbors[i], neighbors[j] = neighbors[j], neighbors[i]
 			}
 			if len(neighbors) > config.GossipSize {
@@ -194,7 +208,7 @@ func (me *Peer) ListenNeighbors() error {
 				me.gossipRound.Set(p.IdForNetwork, p)
 			}
 
-			<-ticker.C
+			me.clock.Sleep(time.Duration(config.SnapshotRoundGap))
 		}
 	}()
 
@@ -228,13 +242,13 @@ func (me *Peer) openPeerStreamLoop(p *Peer) {
 			logger.Verbosef("neighbor open stream %s cost time %v error %s\n", p.Address, time.Since(beg), err.Error())
 		}
 		resend = msg
-		time.Sleep(1 * time.Second)
+		me.clock.Sleep(1 * time.Second)
 	}
 }
 
 func (me *Peer) openPeerStream(p *Peer, resend *ChanMsg) (*ChanMsg, error) {
 	logger.Verbosef("OPEN PEER STREAM %s\n", p.Address)
-	transport, err := NewQuicClient(p.Address)
+	transport, err := me.transports.NewClient(p.Address)
 	if err != nil {
 		return nil, err
 	}
@@ -257,27 +271,28 @@ func (me *Peer) openPeerStream(p *Peer, resend *ChanMsg) (*ChanMsg, error) {
 		if err != nil {
 			return resend, err
 		}
-		me.snapshotsCaches.store(resend.key, time.Now())
+		me.snapshotsCaches.store(resend.key, me.clock.Now())
 	}
 	logger.Verbosef("LOOP PEER STREAM %s\n", p.Address)
 
-	graphTicker := time.NewTicker(time.Duration(config.SnapshotRoundGap / 2))
-	defer graphTicker.Stop()
-
-	gossipNeighborsTicker := time.NewTicker(time.Duration(config.SnapshotRoundGap * 100))
-	defer gossipNeighborsTicker.Stop()
+	graphPeriod := time.Duration(config.SnapshotRoundGap / 2)
+	graphAt := me.clock.Now().Add(graphPeriod)
+	gossipNeighborsPeriod := time.Duration(config.SnapshotRoundGap * 100)
+	gossipNeighborsAt := me.clock.Now().Add(gossipNeighborsPeriod)
 
 	for !me.closing && !p.closing {
 		gd, hd, nd := false, false, false
 
-		select {
-		case <-graphTicker.C:
+		switch now := me.clock.Now(); {
+		case !now.Before(graphAt):
+			graphAt = now.Add(graphPeriod)
 			msg := buildGraphMessage(me.handle.BuildGraph())
 			err := client.Send(msg)
 			if err != nil {
 				return nil, err
 			}
-		case <-gossipNeighborsTicker.C:
+		case !now.Before(gossipNeighborsAt):
+			gossipNeighborsAt = now.Add(gossipNeighborsPeriod)
 			if me.gossipNeighbors {
 				msg := buildGossipNeighborsMessage(me.neighbors.Slice())
 				err := client.Send(msg)
@@ -301,7 +316,7 @@ func (me *Peer) openPeerStream(p *Peer, resend *ChanMsg) (*ChanMsg, error) {
 				if err != nil {
 					return msg, err
 				}
-				me.snapshotsCaches.store(msg.key, time.Now())
+				me.snapshotsCaches.store(msg.key, me.clock.Now())
 			}
 		}
 		if !hd {
@@ -320,12 +335,12 @@ func (me *Peer) openPeerStream(p *Peer, resend *ChanMsg) (*ChanMsg, error) {
 				if err != nil {
 					return msg, err
 				}
-				me.snapshotsCaches.store(msg.key, time.Now())
+				me.snapshotsCaches.store(msg.key, me.clock.Now())
 			}
 		}
 
 		if gd && hd && nd {
-			time.Sleep(100 * time.Millisecond)
+			me.clock.Sleep(100 * time.Millisecond)
 		}
 	}
 
@@ -405,7 +420,7 @@ func (me *Peer) authenticateNeighbor(client Client) (*Peer, error) {
 			client.Close()
 			return nil, fmt.Errorf("peer authentication failed %s", err.Error())
 		}
-	case <-time.After(3 * time.Second):
+	case <-me.clock.After(3 * time.Second):
 		client.Close()
 		return nil, fmt.Errorf("peer authentication timeout")
 	}
@@ -454,13 +469,14 @@ func (me *Peer) sendSnapshotMessageToPeer(idForNetwork crypto.Hash, snap crypto.
 
 type confirmMap struct {
 	cache *fastcache.Cache
+	clock Clock
 }
 
 func (m *confirmMap) contains(key []byte, duration time.Duration) bool {
 	val := m.cache.Get(nil, key)
 	if len(val) == 8 {
 		ts := time.Unix(0, int64(binary.BigEndian.Uint64(val)))
-		return ts.Add(duration).After(time.Now())
+		return ts.Add(duration).After(m.clock.Now())
 	}
 	return false
 }
@@ -45,6 +45,16 @@ type QuicTransport struct {
 	listener quic.Listener
 }
 
+type quicTransportFactory struct{}
+
+func (f quicTransportFactory) NewServer(addr string) (Transport, error) {
+	return NewQuicServer(addr)
+}
+
+func (f quicTransportFactory) NewClient(addr string) (Transport, error) {
+	return NewQuicClient(addr)
+}
+
 func NewQuicServer(addr string) (*QuicTransport, error) {
 	tlsConf := generateTLSConfig()
 	return &QuicTransport{
@@ -75,7 +75,7 @@ func (me *Peer) syncToNeighborSince(graph map[crypto.Hash]*SyncPoint, p *Peer, o
 		}
 		offset = s.TopologicalOrder
 	}
-	time.Sleep(100 * time.Millisecond)
+	me.clock.Sleep(100 * time.Millisecond)
 	if len(snapshots) < limit {
 		return offset, fmt.Errorf("EOF")
 	}
@@ -140,13 +140,13 @@ func (me *Peer) getSyncPointOffset(p *Peer) (map[crypto.Hash]*SyncPoint, uint64)
 	var offset uint64
 	var graph map[crypto.Hash]*SyncPoint
 
-	startAt := time.Now()
+	startAt := me.clock.Now()
 	for !me.closing && !p.closing {
 		item, err := p.syncRing.Poll(false)
 		if err != nil {
 			break
 		} else if item == nil {
-			time.Sleep(100 * time.Millisecond)
+			me.clock.Sleep(100 * time.Millisecond)
 			continue
 		}
 
@@ -162,7 +162,7 @@ func (me *Peer) getSyncPointOffset(p *Peer) (map[crypto.Hash]*SyncPoint, uint64)
 		if off > 0 {
 			offset = off
 		}
-		if startAt.Add(time.Second).Before(time.Now()) {
+		if startAt.Add(time.Second).Before(me.clock.Now()) {
 			return graph, offset
 		}
 	}
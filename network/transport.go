@@ -35,3 +35,10 @@ type Transport interface {
 	Accept(ctx context.Context) (Client, error)
 	Close() error
 }
+
+// TransportFactory creates the transports of a peer, the default one is QUIC,
+// and the in-process memory network is used by the simulations.
+type TransportFactory interface {
+	NewServer(addr string) (Transport, error)
+	NewClient(addr string) (Transport, error)
+}
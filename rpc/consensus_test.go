@@ -22,14 +22,13 @@ import (
 	"github.com/MixinNetwork/mixin/domains/ethereum"
 	"github.com/MixinNetwork/mixin/kernel"
 	"github.com/MixinNetwork/mixin/logger"
-	"github.com/MixinNetwork/mixin/storage"
-	"github.com/VictoriaMetrics/fastcache"
+	"github.com/MixinNetwork/mixin/simulation"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
 	NODES  = 8
-	INPUTS = 100
+	INPUTS = 10
 )
 
 var (
@@ -41,59 +40,19 @@ var (
 func TestAllTransactionsToSingleGenesisNode(t *testing.T) {
 	assert := assert.New(t)
 
-	kernel.TestMockReset()
-
 	root, err := ioutil.TempDir("", "mixin-attsg-test")
 	assert.Nil(err)
 	defer os.RemoveAll(root)
 
-	accounts, _, _, _ := setupTestNet(root)
-	assert.Len(accounts, NODES)
-
 	epoch := time.Unix(1551312000, 0)
-	nodes := make([]*Node, 0)
-	instances := make([]*kernel.Node, 0)
-	stores := make([]storage.Store, 0)
-	for i, _ := range accounts {
-		dir := fmt.Sprintf("%s/mixin-170%02d", root, i+1)
-		custom, err := config.Initialize(dir + "/config.toml")
-		assert.Nil(err)
-		cache := fastcache.New(custom.Node.MemoryCacheSize * 1024 * 1024)
-		store, err := storage.NewBadgerStore(custom, dir)
-		assert.Nil(err)
-		assert.NotNil(store)
-		stores = append(stores, store)
-		if i == 0 {
-			kernel.TestMockDiff(epoch.Sub(time.Now()))
-		}
-		node, err := kernel.SetupNode(custom, store, cache, fmt.Sprintf(":170%02d", i+1), dir)
-		assert.Nil(err)
-		assert.NotNil(node)
-		err = node.PingNeighborsFromConfig()
-		assert.Nil(err)
-		instances = append(instances, node)
-		host := fmt.Sprintf("127.0.0.1:180%02d", i+1)
-		nodes = append(nodes, &Node{Signer: node.Signer, Host: host})
-
-		server := NewServer(custom, store, node, 18000+i+1)
-		defer server.Close()
-		go func(node *kernel.Node, store storage.Store, num int, s *http.Server) {
-			go s.ListenAndServe()
-			go node.Loop()
-		}(node, store, i, server)
+	sim, nodes := testSetupSimulation(assert, root, epoch)
+	defer sim.Teardown()
+	for _, n := range nodes {
+		defer n.server.Close()
 	}
-	defer func() {
-		var wg sync.WaitGroup
-		for _, n := range instances {
-			wg.Add(1)
-			go func(node *kernel.Node) {
-				node.Teardown()
-				wg.Done()
-			}(n)
-		}
-		wg.Wait()
-	}()
-	time.Sleep(3 * time.Second)
+	accounts := sim.Signers
+	assert.Len(accounts, NODES)
+	testRunUntilSnapshots(assert, sim, nodes, NODES+1, 3*time.Second)
 
 	mathRand.Seed(time.Now().UnixNano())
 	target := nodes[mathRand.Intn(len(nodes))]
@@ -122,11 +81,11 @@ func TestAllTransactionsToSingleGenesisNode(t *testing.T) {
 		assert.Len(id, 75)
 	}
 
-	time.Sleep(3 * time.Second)
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS+NODES+1, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS+NODES+1, tl)
 	gt = testVerifyInfo(assert, nodes)
-	assert.Truef(gt.Timestamp.Before(epoch.Add(1*time.Second)), "%s should before %s", gt.Timestamp, epoch.Add(1*time.Second))
+	assert.Truef(gt.Timestamp.Before(epoch.Add(31*time.Second)), "%s should before %s", gt.Timestamp, epoch.Add(31*time.Second))
 
 	utxos := make([]*common.VersionedTransaction, 0)
 	for _, d := range deposits {
@@ -148,7 +107,7 @@ func TestAllTransactionsToSingleGenesisNode(t *testing.T) {
 		assert.Len(id, 75)
 	}
 
-	time.Sleep(3 * time.Second)
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS*2+NODES+1, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1, tl)
 	gt = testVerifyInfo(assert, nodes)
@@ -170,59 +129,19 @@ func TestConsensusMany(t *testing.T) {
 func testConsensus(t *testing.T, dup int) {
 	assert := assert.New(t)
 
-	kernel.TestMockReset()
-
 	root, err := ioutil.TempDir("", "mixin-consensus-test")
 	assert.Nil(err)
 	defer os.RemoveAll(root)
 
-	accounts, payees, gdata, ndata := setupTestNet(root)
-	assert.Len(accounts, NODES)
-
 	epoch := time.Unix(1551312000, 0)
-	nodes := make([]*Node, 0)
-	instances := make([]*kernel.Node, 0)
-	stores := make([]storage.Store, 0)
-	for i := range accounts {
-		dir := fmt.Sprintf("%s/mixin-170%02d", root, i+1)
-		custom, err := config.Initialize(dir + "/config.toml")
-		assert.Nil(err)
-		cache := fastcache.New(custom.Node.MemoryCacheSize * 1024 * 1024)
-		store, err := storage.NewBadgerStore(custom, dir)
-		assert.Nil(err)
-		assert.NotNil(store)
-		stores = append(stores, store)
-		if i == 0 {
-			kernel.TestMockDiff(epoch.Sub(time.Now()))
-		}
-		node, err := kernel.SetupNode(custom, store, cache, fmt.Sprintf(":170%02d", i+1), dir)
-		assert.Nil(err)
-		assert.NotNil(node)
-		err = node.PingNeighborsFromConfig()
-		assert.Nil(err)
-		instances = append(instances, node)
-		host := fmt.Sprintf("127.0.0.1:180%02d", i+1)
-		nodes = append(nodes, &Node{Signer: node.Signer, Host: host})
-
-		server := NewServer(custom, store, node, 18000+i+1)
-		defer server.Close()
-		go func(node *kernel.Node, store storage.Store, num int, s *http.Server) {
-			go s.ListenAndServe()
-			go node.Loop()
-		}(node, store, i, server)
+	sim, nodes := testSetupSimulation(assert, root, epoch)
+	defer sim.Teardown()
+	for _, n := range nodes {
+		defer n.server.Close()
 	}
-	defer func() {
-		var wg sync.WaitGroup
-		for _, n := range instances {
-			wg.Add(1)
-			go func(node *kernel.Node) {
-				node.Teardown()
-				wg.Done()
-			}(n)
-		}
-		wg.Wait()
-	}()
-	time.Sleep(3 * time.Second)
+	accounts := sim.Signers
+	assert.Len(accounts, NODES)
+	testRunUntilSnapshots(assert, sim, nodes, NODES+1, 3*time.Second)
 
 	tl, sl := testVerifySnapshots(assert, nodes)
 	assert.Equal(NODES+1, tl)
@@ -248,35 +167,19 @@ func testConsensus(t *testing.T, dup int) {
 	logger.SetLimiter(3)
 	logger.SetFilter("(?i)error")
 
-	for _, d := range deposits {
-		mathRand.Seed(time.Now().UnixNano())
-		for n := len(nodes); n > 0; n-- {
-			randIndex := mathRand.Intn(n)
-			nodes[n-1], nodes[randIndex] = nodes[randIndex], nodes[n-1]
-		}
-		wg := &sync.WaitGroup{}
-		start := mathRand.Intn(len(nodes) - 1)
-		for i := start; i < len(nodes) && i != start+dup; i++ {
-			wg.Add(1)
-			go func(n string, raw string) {
-				defer wg.Done()
-				id, err := testSendTransaction(n, raw)
-				assert.Nil(err)
-				assert.Len(id, 75)
-			}(nodes[i].Host, hex.EncodeToString(d.Marshal()))
-		}
-		wg.Wait()
+	for i, d := range deposits {
+		testSendTransactionToNodes(assert, nodes, i, dup, d)
 	}
 
-	time.Sleep(3 * time.Second)
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS+NODES+1, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS+NODES+1, tl)
 	gt = testVerifyInfo(assert, nodes)
 	assert.Truef(gt.Timestamp.Before(epoch.Add(1*time.Second)), "%s should before %s", gt.Timestamp, epoch.Add(1*time.Second))
-	hr := testDumpGraphHead(nodes[0].Host, instances[0].IdForNetwork)
+	hr := testDumpGraphHead(nodes[0].Host, sim.Nodes[0].IdForNetwork)
 	assert.NotNil(hr)
 	assert.GreaterOrEqual(hr.Round, uint64(0))
-	t.Log("DEPOSIT TEST DONE", time.Now())
+	t.Log("DEPOSIT TEST DONE", sim.Clock.Now())
 
 	utxos := make([]*common.VersionedTransaction, 0)
 	for _, d := range deposits {
@@ -291,38 +194,22 @@ func testConsensus(t *testing.T, dup int) {
 	}
 	assert.Equal(INPUTS, len(utxos))
 
-	for _, tx := range utxos {
-		mathRand.Seed(time.Now().UnixNano())
-		for n := len(nodes); n > 0; n-- {
-			randIndex := mathRand.Intn(n)
-			nodes[n-1], nodes[randIndex] = nodes[randIndex], nodes[n-1]
-		}
-		wg := &sync.WaitGroup{}
-		start := mathRand.Intn(len(nodes) - 1)
-		for i := start; i < len(nodes) && i != start+dup; i++ {
-			wg.Add(1)
-			go func(n string, raw string) {
-				defer wg.Done()
-				id, err := testSendTransaction(n, raw)
-				assert.Nil(err)
-				assert.Len(id, 75)
-			}(nodes[i].Host, hex.EncodeToString(tx.Marshal()))
-		}
-		wg.Wait()
+	for i, tx := range utxos {
+		testSendTransactionToNodes(assert, nodes, i, dup, tx)
 	}
 
-	time.Sleep(3 * time.Second)
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS*2+NODES+1, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1, tl)
 	gt = testVerifyInfo(assert, nodes)
 	assert.True(gt.Timestamp.Before(epoch.Add(31 * time.Second)))
-	hr = testDumpGraphHead(nodes[0].Host, instances[0].IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, sim.Nodes[0].IdForNetwork)
 	assert.NotNil(hr)
 	assert.Greater(hr.Round, uint64(0))
-	t.Log("INPUT TEST DONE", time.Now())
+	t.Log("INPUT TEST DONE", sim.Clock.Now())
 
-	kernel.TestMockDiff((config.KernelMintTimeBegin + 24) * time.Hour)
-	time.Sleep(3 * time.Second)
+	sim.Clock.Jump((config.KernelMintTimeBegin + 24) * time.Hour)
+	testRunFor(sim, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1, tl)
 	gt = testVerifyInfo(assert, nodes)
@@ -334,25 +221,24 @@ func testConsensus(t *testing.T, dup int) {
 
 	input, err := testBuildPledgeInput(assert, nodes[0].Host, accounts[0], utxos)
 	assert.Nil(err)
-	time.Sleep(5 * time.Second)
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS*2+NODES+1+1, 5*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1+1, tl)
 	gt = testVerifyInfo(assert, nodes)
 	assert.True(gt.Timestamp.Before(epoch.Add(61 * time.Second)))
 
-	pn, pi, sv := testPledgeNewNode(assert, nodes[0].Host, accounts[0], gdata, ndata, input, root)
-	defer pi.Teardown()
-	defer sv.Close()
-	time.Sleep(3 * time.Second)
+	pn := testPledgeNewNode(assert, sim, nodes[0].Host, accounts[0], input)
+	defer pn.server.Close()
+	testRunUntilSnapshots(assert, sim, nodes, INPUTS*2+NODES+1+1+2, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1+1+2, tl)
 	gt = testVerifyInfo(assert, nodes)
 	assert.True(gt.Timestamp.After(epoch.Add((config.KernelMintTimeBegin + 24) * time.Hour)))
 	assert.Equal("499876.71232883", gt.PoolSize.String())
-	hr = testDumpGraphHead(nodes[0].Host, instances[0].IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, sim.Nodes[0].IdForNetwork)
 	assert.NotNil(hr)
 	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[0].Host, pi.IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, pn.instance.IdForNetwork)
 	assert.Nil(hr)
 
 	all = testListNodes(nodes[0].Host)
@@ -360,20 +246,20 @@ func testConsensus(t *testing.T, dup int) {
 	assert.Equal(all[NODES].Signer.String(), pn.Signer.String())
 	assert.Equal(all[NODES].Payee.String(), pn.Payee.String())
 	assert.Equal("PLEDGING", all[NODES].State)
-	t.Log("PLEDGE TEST DONE", time.Now())
+	t.Log("PLEDGE TEST DONE", sim.Clock.Now())
 
-	kernel.TestMockDiff(11 * time.Hour)
-	time.Sleep(3 * time.Second)
+	sim.Clock.Jump(11 * time.Hour)
+	testRunFor(sim, 3*time.Second)
 	all = testListNodes(nodes[0].Host)
 	assert.Len(all, NODES+1)
 	assert.Equal(all[NODES].Signer.String(), pn.Signer.String())
 	assert.Equal(all[NODES].Payee.String(), pn.Payee.String())
 	assert.Equal("PLEDGING", all[NODES].State)
-	hr = testDumpGraphHead(nodes[0].Host, pi.IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, pn.instance.IdForNetwork)
 	assert.Nil(hr)
 
-	kernel.TestMockDiff(1 * time.Hour)
-	time.Sleep(5 * time.Second)
+	sim.Clock.Jump(1 * time.Hour)
+	testRunUntilSnapshots(assert, sim, append(nodes, pn), INPUTS*2+NODES+1+1+2+1, 5*time.Second)
 	all = testListNodes(nodes[0].Host)
 	assert.Len(all, NODES+1)
 	assert.Equal(all[NODES].Signer.String(), pn.Signer.String())
@@ -381,16 +267,16 @@ func testConsensus(t *testing.T, dup int) {
 	assert.Equal("ACCEPTED", all[NODES].State)
 	assert.Equal(len(testListSnapshots(nodes[NODES-1].Host)), len(testListSnapshots(pn.Host)))
 	assert.Equal(len(testListSnapshots(nodes[0].Host)), len(testListSnapshots(pn.Host)))
-	hr = testDumpGraphHead(nodes[0].Host, instances[0].IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, sim.Nodes[0].IdForNetwork)
 	assert.NotNil(hr)
 	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, instances[0].IdForNetwork)
+	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, sim.Nodes[0].IdForNetwork)
 	assert.NotNil(hr)
 	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[0].Host, pi.IdForNetwork)
+	hr = testDumpGraphHead(nodes[0].Host, pn.instance.IdForNetwork)
 	assert.NotNil(hr)
 	assert.Equal(uint64(0), hr.Round)
-	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, pi.IdForNetwork)
+	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, pn.instance.IdForNetwork)
 	assert.NotNil(hr)
 	assert.Equal(uint64(0), hr.Round)
 
@@ -400,13 +286,13 @@ func testConsensus(t *testing.T, dup int) {
 	assert.True(gt.Timestamp.After(epoch.Add((config.KernelMintTimeBegin + 24) * time.Hour)))
 	assert.Equal("499876.71232883", gt.PoolSize.String())
 
-	kernel.TestMockDiff(364 * 24 * time.Hour)
-	time.Sleep(3 * time.Second)
+	sim.Clock.Jump(364 * 24 * time.Hour)
+	testRunFor(sim, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1+1+2+1, tl)
 
 	input = testSendDummyTransaction(assert, nodes[0].Host, accounts[0], input, "3.5")
-	time.Sleep(3 * time.Second)
+	testRunUntilSnapshots(assert, sim, append(nodes, pn), INPUTS*2+NODES+1+1+2+1+1, 3*time.Second)
 	tl, sl = testVerifySnapshots(assert, nodes)
 	assert.Equal(INPUTS*2+NODES+1+1+2+1+1, tl)
 	for i := range nodes {
@@ -416,68 +302,107 @@ func testConsensus(t *testing.T, dup int) {
 		assert.Equal(all[NODES].Payee.String(), pn.Payee.String())
 		assert.Equal("ACCEPTED", all[NODES].State)
 	}
-	t.Log("ACCEPT TEST DONE", time.Now())
+	t.Log("ACCEPT TEST DONE", sim.Clock.Now())
+}
 
-	signer, payee := testGetNodeToRemove(instances[0].NetworkId(), accounts, payees)
-	input = testSendDummyTransaction(assert, nodes[0].Host, accounts[0], input, "3.5")
-	nodes = testRemoveNode(nodes, signer)
-	time.Sleep(5 * time.Second)
-	tl, sl = testVerifySnapshots(assert, nodes)
-	assert.Equal(INPUTS*2+NODES+1+1+2+1+1+2, tl)
-	for i := range nodes {
-		all = testListNodes(nodes[i].Host)
-		assert.Len(all, NODES+1)
-		assert.Equal(all[NODES].Signer.String(), signer.String())
-		assert.Equal(all[NODES].Payee.String(), payee.String())
-		assert.Equal("REMOVED", all[NODES].State)
+// testSetupSimulation runs the nodes in a simulation started at epoch, and
+// serves the RPC of each node at 127.0.0.1:180xx.
+func testSetupSimulation(assert *assert.Assertions, root string, epoch time.Time) (*simulation.Simulation, []*Node) {
+	sim, err := simulation.New(root, &simulation.Config{
+		Nodes: NODES,
+		Seed:  1,
+		Start: epoch,
+	})
+	assert.Nil(err)
+
+	nodes := make([]*Node, 0)
+	for i, node := range sim.Nodes {
+		nodes = append(nodes, testServeNode(sim, i, node, 18000+i+1))
 	}
-	hr = testDumpGraphHead(nodes[0].Host, instances[0].IdForNetwork)
-	assert.NotNil(hr)
-	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, instances[0].IdForNetwork)
-	assert.NotNil(hr)
-	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[0].Host, pi.IdForNetwork)
-	assert.NotNil(hr)
-	assert.Equal(uint64(0), hr.Round)
-	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, pi.IdForNetwork)
-	assert.NotNil(hr)
-	assert.Equal(uint64(0), hr.Round)
-	hr = testDumpGraphHead(nodes[0].Host, signer.Hash().ForNetwork(instances[0].NetworkId()))
-	assert.NotNil(hr)
-	assert.Greater(hr.Round, uint64(0))
-	hr = testDumpGraphHead(nodes[len(nodes)-1].Host, signer.Hash().ForNetwork(instances[0].NetworkId()))
-	assert.NotNil(hr)
-	assert.Greater(hr.Round, uint64(0))
+	sim.Start()
+	assert.True(sim.RunUntil(sim.Ready, 10000))
+	return sim, nodes
+}
 
-	input = testSendDummyTransaction(assert, nodes[0].Host, payee, all[NODES].Transaction.String(), "10000")
-	assert.Len(input, 64)
-	time.Sleep(3 * time.Second)
-	tl, sl = testVerifySnapshots(assert, nodes)
-	assert.Equal(INPUTS*2+NODES+1+1+2+1+1+2+1, tl)
-	for i := range nodes {
-		all = testListNodes(nodes[i].Host)
-		assert.Len(all, NODES+1)
-		assert.Equal(all[NODES].Signer.String(), signer.String())
-		assert.Equal(all[NODES].Payee.String(), payee.String())
-		assert.Equal("REMOVED", all[NODES].State)
+func testServeNode(sim *simulation.Simulation, i int, node *kernel.Node, port int) *Node {
+	server := NewServer(sim.Customs[i], sim.Stores[i], node, port)
+	go server.ListenAndServe()
+	return &Node{
+		Signer:   node.Signer,
+		Host:     fmt.Sprintf("127.0.0.1:%d", port),
+		instance: node,
+		server:   server,
 	}
-	t.Log("REMOVE TEST DONE", time.Now())
 }
 
-func testRemoveNode(nodes []*Node, r common.Address) []*Node {
-	tmp := []*Node{{Host: "127.0.0.1:18099"}}
-	for _, n := range nodes {
-		if n.Signer.String() != r.String() {
-			tmp = append(tmp, n)
+// testRunUntilSnapshots steps the simulation at least the duration d, like
+// the sleeps in the real network, and until all nodes have the same snapshots
+// of at least the count of transactions. A transaction may be in more than one
+// snapshot, so the topology is only checked first because it's cheaper.
+func testRunUntilSnapshots(assert *assert.Assertions, sim *simulation.Simulation, nodes []*Node, count int, d time.Duration) {
+	end := sim.Clock.Now().Add(d)
+	done := sim.RunUntil(func() bool {
+		if sim.Clock.Now().Before(end) {
+			return false
 		}
-	}
-	mathRand.Seed(time.Now().UnixNano())
-	for n := len(tmp); n > 0; n-- {
-		randIndex := mathRand.Intn(n)
-		tmp[n-1], tmp[randIndex] = tmp[randIndex], tmp[n-1]
-	}
-	return tmp
+		for _, n := range nodes {
+			if n.instance.TopologicalOrder() < uint64(count) {
+				return false
+			}
+		}
+		var first map[string]*common.Snapshot
+		for _, n := range nodes {
+			snapshots := testListSnapshots(n.Host)
+			txs := make(map[crypto.Hash]bool)
+			for _, s := range snapshots {
+				txs[s.Transaction] = true
+			}
+			if len(txs) < count {
+				return false
+			}
+			if first == nil {
+				first = snapshots
+				continue
+			}
+			if len(snapshots) != len(first) {
+				return false
+			}
+			for k := range snapshots {
+				if first[k] == nil {
+					return false
+				}
+			}
+		}
+		return true
+	}, 20000)
+	assert.Truef(done, "transactions count should reach %d", count)
+}
+
+func testRunFor(sim *simulation.Simulation, d time.Duration) {
+	end := sim.Clock.Now().Add(d)
+	sim.RunUntil(func() bool {
+		return !sim.Clock.Now().Before(end)
+	}, 20000)
+}
+
+// testSendTransactionToNodes sends the transaction to dup nodes, or all the
+// nodes if dup is negative, from the node at start, so the transactions sent
+// with the successive starts are spread to all nodes.
+func testSendTransactionToNodes(assert *assert.Assertions, nodes []*Node, start, dup int, tx *common.VersionedTransaction) {
+	if dup < 0 || dup > len(nodes) {
+		dup = len(nodes)
+	}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < dup; i++ {
+		wg.Add(1)
+		go func(n string, raw string) {
+			defer wg.Done()
+			id, err := testSendTransaction(n, raw)
+			assert.Nil(err)
+			assert.Len(id, 75)
+		}(nodes[(start+i)%len(nodes)].Host, hex.EncodeToString(tx.Marshal()))
+	}
+	wg.Wait()
 }
 
 func testSendDummyTransaction(assert *assert.Assertions, node string, domain common.Address, th, amount string) string {
@@ -506,16 +431,7 @@ func testSendDummyTransaction(assert *assert.Assertions, node string, domain com
 	return hash["hash"]
 }
 
-const configDataTmpl = `[node]
-signer-key = "%s"
-consensus-only = true
-memory-cache-size = 128
-kernel-operation-period = 2
-cache-ttl = 3600
-[network]
-listener = "%s"`
-
-func testPledgeNewNode(assert *assert.Assertions, node string, domain common.Address, genesisData, nodesData []byte, input, root string) (Node, *kernel.Node, *http.Server) {
+func testPledgeNewNode(assert *assert.Assertions, sim *simulation.Simulation, node string, domain common.Address, input string) *Node {
 	var signer, payee common.Address
 
 	randomPubAccount := func() common.Address {
@@ -532,26 +448,6 @@ func testPledgeNewNode(assert *assert.Assertions, node string, domain common.Add
 	signer = randomPubAccount()
 	payee = randomPubAccount()
 
-	dir := fmt.Sprintf("%s/mixin-17099", root)
-	err := os.MkdirAll(dir, 0755)
-	if err != nil {
-		panic(err)
-	}
-
-	configData := []byte(fmt.Sprintf(configDataTmpl, signer.PrivateSpendKey.String(), "127.0.0.1:17099"))
-	err = ioutil.WriteFile(dir+"/config.toml", configData, 0644)
-	if err != nil {
-		panic(err)
-	}
-	err = ioutil.WriteFile(dir+"/genesis.json", genesisData, 0644)
-	if err != nil {
-		panic(err)
-	}
-	err = ioutil.WriteFile(dir+"/nodes.json", nodesData, 0644)
-	if err != nil {
-		panic(err)
-	}
-
 	raw, err := json.Marshal(map[string]interface{}{
 		"version": 1,
 		"asset":   assetID,
@@ -571,23 +467,13 @@ func testPledgeNewNode(assert *assert.Assertions, node string, domain common.Add
 	_, err = testSendTransaction(node, hex.EncodeToString(ver.Marshal()))
 	assert.Nil(err)
 
-	custom, err := config.Initialize(dir + "/config.toml")
-	assert.Nil(err)
-	cache := fastcache.New(custom.Node.MemoryCacheSize * 1024 * 1024)
-	store, err := storage.NewBadgerStore(custom, dir)
-	assert.Nil(err)
-	assert.NotNil(store)
-	pnode, err := kernel.SetupNode(custom, store, cache, fmt.Sprintf(":170%02d", 99), dir)
+	pnode, err := sim.Join(signer, payee)
 	assert.Nil(err)
 	assert.NotNil(pnode)
-	err = pnode.PingNeighborsFromConfig()
-	assert.Nil(err)
-	go pnode.Loop()
 
-	server := NewServer(custom, store, pnode, 18099)
-	go server.ListenAndServe()
-
-	return Node{Signer: signer, Payee: payee, Host: "127.0.0.1:18099"}, pnode, server
+	pn := testServeNode(sim, len(sim.Nodes)-1, pnode, 18099)
+	pn.Payee = payee
+	return pn
 }
 
 func testBuildPledgeInput(assert *assert.Assertions, node string, domain common.Address, utxos []*common.VersionedTransaction) (string, error) {
@@ -631,97 +517,6 @@ func testSendTransaction(node, raw string) (string, error) {
 	return string(data), err
 }
 
-func testGetNodeToRemove(networkId crypto.Hash, signers, payees []common.Address) (common.Address, common.Address) {
-	nodes := make([][2]common.Address, len(signers))
-	for i := range signers {
-		nodes[i] = [2]common.Address{signers[i], payees[i]}
-	}
-	sort.Slice(nodes, func(i, j int) bool {
-		a := nodes[i][0].Hash().ForNetwork(networkId)
-		b := nodes[j][0].Hash().ForNetwork(networkId)
-		return a.String() < b.String()
-	})
-	return nodes[0][0], nodes[0][1]
-}
-
-func setupTestNet(root string) ([]common.Address, []common.Address, []byte, []byte) {
-	var signers, payees []common.Address
-
-	randomPubAccount := func() common.Address {
-		seed := make([]byte, 64)
-		_, err := rand.Read(seed)
-		if err != nil {
-			panic(err)
-		}
-		account := common.NewAddressFromSeed(seed)
-		account.PrivateViewKey = account.PublicSpendKey.DeterministicHashDerive()
-		account.PublicViewKey = account.PrivateViewKey.Public()
-		return account
-	}
-	for i := 0; i < NODES; i++ {
-		signers = append(signers, randomPubAccount())
-		payees = append(payees, randomPubAccount())
-	}
-
-	inputs := make([]map[string]string, 0)
-	for i := range signers {
-		inputs = append(inputs, map[string]string{
-			"signer":  signers[i].String(),
-			"payee":   payees[i].String(),
-			"balance": "10000",
-		})
-	}
-	genesis := map[string]interface{}{
-		"epoch": 1551312000,
-		"nodes": inputs,
-		"domains": []map[string]string{
-			{
-				"signer":  signers[0].String(),
-				"balance": "50000",
-			},
-		},
-	}
-	genesisData, err := json.MarshalIndent(genesis, "", "  ")
-	if err != nil {
-		panic(err)
-	}
-
-	nodes := make([]map[string]string, 0)
-	for i, a := range signers {
-		nodes = append(nodes, map[string]string{
-			"host":   fmt.Sprintf("127.0.0.1:170%02d", i+1),
-			"signer": a.String(),
-		})
-	}
-	nodesData, err := json.MarshalIndent(nodes, "", "  ")
-	if err != nil {
-		panic(err)
-	}
-
-	for i, a := range signers {
-		dir := fmt.Sprintf("%s/mixin-170%02d", root, i+1)
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			panic(err)
-		}
-
-		configData := []byte(fmt.Sprintf(configDataTmpl, a.PrivateSpendKey.String(), nodes[i]["host"]))
-		err = ioutil.WriteFile(dir+"/config.toml", configData, 0644)
-		if err != nil {
-			panic(err)
-		}
-		err = ioutil.WriteFile(dir+"/genesis.json", genesisData, 0644)
-		if err != nil {
-			panic(err)
-		}
-		err = ioutil.WriteFile(dir+"/nodes.json", nodesData, 0644)
-		if err != nil {
-			panic(err)
-		}
-	}
-	return signers, payees, genesisData, nodesData
-}
-
 func testSignTransaction(node string, account common.Address, rawStr string) (*common.SignedTransaction, error) {
 	var raw signerInput
 	err := json.Unmarshal([]byte(rawStr), &raw)
@@ -855,6 +650,9 @@ type Node struct {
 	State       string         `json:"state"`
 	Transaction crypto.Hash    `json:"transaction"`
 	Host        string         `json:"-"`
+
+	instance *kernel.Node
+	server   *http.Server
 }
 
 func testListNodes(node string) []*Node {
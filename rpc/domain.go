@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"github.com/MixinNetwork/mixin/storage"
+)
+
+func listDomains(store storage.Store) ([]map[string]interface{}, error) {
+	domains, err := store.ReadAllDomains()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(domains))
+	for i, d := range domains {
+		item := map[string]interface{}{
+			"account":     d.Account,
+			"transaction": d.Transaction,
+			"timestamp":   d.Timestamp,
+			"state":       d.State,
+		}
+		result[i] = item
+	}
+	return result, nil
+}
+
+func listDomainCustodies(store storage.Store) ([]map[string]interface{}, error) {
+	custodies, err := store.ReadDomainCustodies()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(custodies))
+	for i, c := range custodies {
+		item := map[string]interface{}{
+			"domain": c.Domain,
+			"asset":  c.Asset,
+			"amount": c.Amount,
+		}
+		result[i] = item
+	}
+	return result, nil
+}
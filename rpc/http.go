@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/kernel"
 	"github.com/MixinNetwork/mixin/storage"
@@ -66,6 +67,9 @@ func (r *Render) RenderData(data interface{}) {
 
 func (r *Render) RenderError(err error) {
 	body := map[string]interface{}{"error": err.Error()}
+	if code := common.ValidationErrorCode(err); code != common.ErrorCodeUnknown {
+		body["code"] = code
+	}
 	if r.id != "" {
 		body["id"] = r.id
 	}
@@ -102,6 +106,24 @@ func (impl *R) handle(w http.ResponseWriter, r *http.Request, _ map[string]strin
 		} else {
 			renderer.RenderData(data)
 		}
+	case "getchainhealth":
+		data, err := getChainHealth(impl.Node, call.Params)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(data)
+		}
+	case "controlchain":
+		if !impl.custom.RPC.Admin {
+			renderer.RenderError(fmt.Errorf("admin method not enabled %s", call.Method))
+			break
+		}
+		data, err := controlChain(impl.Node, call.Params)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(data)
+		}
 	case "getconsensuskeys":
 		data, err := getConsensusKeys(impl.Node, call.Params)
 		if err != nil {
@@ -116,6 +138,13 @@ func (impl *R) handle(w http.ResponseWriter, r *http.Request, _ map[string]strin
 		} else {
 			renderer.RenderData(map[string]string{"hash": id})
 		}
+	case "validaterawtransaction":
+		data, err := validateTransaction(impl.Store, impl.Node, call.Params)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(data)
+		}
 	case "gettransaction":
 		tx, err := getTransaction(impl.Store, call.Params)
 		if err != nil {
@@ -137,6 +166,20 @@ func (impl *R) handle(w http.ResponseWriter, r *http.Request, _ map[string]strin
 		} else {
 			renderer.RenderData(utxo)
 		}
+	case "getconflicts":
+		data, err := getConflicts(impl.Store, call.Params)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(data)
+		}
+	case "listconflicts":
+		data, err := listConflicts(impl.Store, call.Params)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(data)
+		}
 	case "getsnapshot":
 		snap, err := getSnapshot(impl.Store, call.Params)
 		if err != nil {
@@ -165,6 +208,27 @@ func (impl *R) handle(w http.ResponseWriter, r *http.Request, _ map[string]strin
 		} else {
 			renderer.RenderData(nodes)
 		}
+	case "listconsensushistory":
+		events, err := listConsensusHistory(impl.Node)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(events)
+		}
+	case "listdomains":
+		domains, err := listDomains(impl.Store)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(domains)
+		}
+	case "listdomaincustodies":
+		custodies, err := listDomainCustodies(impl.Store)
+		if err != nil {
+			renderer.RenderError(err)
+		} else {
+			renderer.RenderData(custodies)
+		}
 	case "getroundbynumber":
 		round, err := getRoundByNumber(impl.Store, call.Params)
 		if err != nil {
@@ -40,6 +40,16 @@ func getInfo(store storage.Store, node *kernel.Node) (map[string]interface{}, er
 		"epoch":     time.Unix(0, int64(node.Epoch)),
 		"timestamp": time.Unix(0, int64(node.GraphTimestamp)),
 	}
+	cs := node.ClockSkew()
+	info["clock"] = map[string]interface{}{
+		"offset": cs.Offset.String(),
+		"peers":  cs.Peers,
+		"skewed": cs.Skewed,
+	}
+	info["anti-spam"] = map[string]interface{}{
+		"difficulty": node.StampDifficulty(),
+		"unit":       common.StampSizeUnit,
+	}
 	pool, err := node.PoolSize()
 	if err != nil {
 		return info, err
@@ -112,9 +122,47 @@ func getInfo(store storage.Store, node *kernel.Node) (map[string]interface{}, er
 		"finals": finals,
 		"caches": caches,
 	}
+	stats := node.MempoolStats()
+	info["mempool"] = map[string]interface{}{
+		"size":     stats.Size,
+		"rejected": stats.Rejected,
+		"replaced": stats.Replaced,
+	}
 	return info, nil
 }
 
+func getChainHealth(node *kernel.Node, params []interface{}) ([]*kernel.ChainHealth, error) {
+	if len(params) != 0 {
+		return nil, errors.New("invalid params count")
+	}
+	return node.ChainsHealth(), nil
+}
+
+func controlChain(node *kernel.Node, params []interface{}) (*kernel.ChainHealth, error) {
+	if len(params) != 2 {
+		return nil, errors.New("invalid params count")
+	}
+	id, err := crypto.HashFromString(fmt.Sprint(params[0]))
+	if err != nil {
+		return nil, err
+	}
+	switch action := fmt.Sprint(params[1]); action {
+	case "stop":
+		err = node.StopChain(id)
+	case "start":
+		err = node.StartChain(id)
+	case "restart":
+		err = node.RestartChain(id)
+	case "status":
+	default:
+		return nil, fmt.Errorf("invalid chain action %s", action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node.ChainHealth(id)
+}
+
 func dumpGraphHead(node *kernel.Node, params []interface{}) ([]map[string]interface{}, error) {
 	rounds := node.BuildGraphWithPoolInfo()
 	sort.Slice(rounds, func(i, j int) bool { return fmt.Sprint(rounds[i]["node"]) < fmt.Sprint(rounds[j]["node"]) })
@@ -21,3 +21,30 @@ func listAllNodes(store storage.Store, node *kernel.Node) ([]map[string]interfac
 	}
 	return result, nil
 }
+
+func listConsensusHistory(node *kernel.Node) ([]map[string]interface{}, error) {
+	events, err := node.ConsensusHistory()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(events))
+	for i, e := range events {
+		keys := make([]string, len(e.Keys))
+		for j, k := range e.Keys {
+			keys[j] = k.String()
+		}
+		result[i] = map[string]interface{}{
+			"type":             e.Type,
+			"id":               e.NodeId,
+			"signer":           e.Signer,
+			"payee":            e.Payee,
+			"transaction":      e.Transaction,
+			"snapshot":         e.Snapshot,
+			"timestamp":        e.Timestamp,
+			"threshold_before": e.ThresholdBefore,
+			"threshold_after":  e.ThresholdAfter,
+			"keys":             keys,
+		}
+	}
+	return result, nil
+}
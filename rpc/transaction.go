@@ -44,6 +44,83 @@ func queueTransaction(node *kernel.Node, params []interface{}) (string, error) {
 	return node.QueueTransaction(ver)
 }
 
+func validateTransaction(store storage.Store, node *kernel.Node, params []interface{}) (map[string]interface{}, error) {
+	if len(params) != 1 {
+		return nil, errors.New("invalid params count")
+	}
+	raw, err := hex.DecodeString(fmt.Sprint(params[0]))
+	if err != nil {
+		return nil, err
+	}
+	ver, err := common.UnmarshalVersionedTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ver.PayloadHash()
+	inputAmount := common.NewInteger(0)
+	var inputs []map[string]interface{}
+	for _, in := range ver.Inputs {
+		if in.Mint != nil {
+			inputAmount = inputAmount.Add(in.Mint.Amount)
+			continue
+		}
+		if in.Deposit != nil {
+			inputAmount = inputAmount.Add(in.Deposit.Amount)
+			continue
+		}
+		if !in.Hash.HasValue() {
+			continue
+		}
+		utxo, err := store.ReadUTXO(in.Hash, in.Index)
+		if err != nil {
+			return nil, err
+		}
+		input := map[string]interface{}{
+			"hash":   in.Hash,
+			"index":  in.Index,
+			"found":  utxo != nil,
+			"locked": false,
+		}
+		if utxo != nil {
+			input["amount"] = utxo.Amount
+			input["locked"] = utxo.LockHash.HasValue() && utxo.LockHash != hash
+			if utxo.LockHash.HasValue() {
+				input["lock"] = utxo.LockHash
+			}
+			inputAmount = inputAmount.Add(utxo.Amount)
+		}
+		inputs = append(inputs, input)
+	}
+	outputAmount := common.NewInteger(0)
+	for _, out := range ver.Outputs {
+		outputAmount = outputAmount.Add(out.Amount)
+	}
+
+	result := map[string]interface{}{
+		"hash":   hash,
+		"type":   ver.TransactionType(),
+		"inputs": inputs,
+		"input":  inputAmount,
+		"output": outputAmount,
+		"valid":  true,
+	}
+	err = ver.ValidateStamp(node.StampDifficulty())
+	if err == nil {
+		err = ver.Validate(store)
+	}
+	if err != nil {
+		result["valid"] = false
+		code := common.ValidationErrorCode(err)
+		result["error"] = map[string]interface{}{
+			"code":    code,
+			"name":    code.String(),
+			"message": err.Error(),
+		}
+	}
+	return result, nil
+}
+
 func getTransaction(store storage.Store, params []interface{}) (map[string]interface{}, error) {
 	if len(params) != 1 {
 		return nil, errors.New("invalid params count")
@@ -102,6 +179,69 @@ func getUTXO(store storage.Store, params []interface{}) (map[string]interface{},
 	return output, nil
 }
 
+func getConflicts(store storage.Store, params []interface{}) ([]map[string]interface{}, error) {
+	if len(params) != 1 && len(params) != 2 {
+		return nil, errors.New("invalid params count")
+	}
+	hash, err := crypto.HashFromString(fmt.Sprint(params[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*common.UTXOConflict
+	if len(params) == 2 {
+		index, err := strconv.ParseUint(fmt.Sprint(params[1]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		conflicts, err = store.ReadUTXOConflicts(hash, int(index))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		conflicts, err = store.ReadTransactionConflicts(hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conflictsToMap(conflicts), nil
+}
+
+func listConflicts(store storage.Store, params []interface{}) ([]map[string]interface{}, error) {
+	if len(params) != 2 {
+		return nil, errors.New("invalid params count")
+	}
+	since, err := strconv.ParseUint(fmt.Sprint(params[0]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.ParseUint(fmt.Sprint(params[1]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := store.ListConflicts(since, int(count))
+	if err != nil {
+		return nil, err
+	}
+	return conflictsToMap(conflicts), nil
+}
+
+func conflictsToMap(conflicts []*common.UTXOConflict) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(conflicts))
+	for i, c := range conflicts {
+		result[i] = map[string]interface{}{
+			"hash":      c.Hash,
+			"index":     c.Index,
+			"locker":    c.Locker,
+			"spender":   c.Spender,
+			"pruned":    c.Pruned,
+			"timestamp": c.Timestamp,
+		}
+	}
+	return result
+}
+
 func getSnapshot(store storage.Store, params []interface{}) (map[string]interface{}, error) {
 	if len(params) != 1 {
 		return nil, errors.New("invalid params count")
@@ -0,0 +1,81 @@
+package simulation
+
+import (
+	"math/rand"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/network"
+)
+
+// byzantineMessage returns a well formed message of the same type with bad
+// protocol data, e.g. a commitment or response not matching the cosi session,
+// or a finalization with a forged signature, so the receiver must reject it
+// by the consensus rules instead of the message parser. It returns nil for
+// the messages having nothing to lie about.
+func byzantineMessage(data []byte, seed int64) []byte {
+	r := rand.New(rand.NewSource(seed))
+	key := func() crypto.PrivateKey {
+		seed := make([]byte, 64)
+		r.Read(seed)
+		return crypto.PrivateKeyFromSeed(seed)
+	}
+	if len(data) < 1 {
+		return nil
+	}
+
+	switch data[0] {
+	case network.PeerMessageTypeSnapshotAnnoucement:
+		if len(data) <= 1+crypto.KeySize {
+			return nil
+		}
+		R := key().Public().Key()
+		msg := append([]byte{data[0]}, R[:]...)
+		return append(msg, data[1+crypto.KeySize:]...)
+	case network.PeerMessageTypeSnapshotCommitment:
+		if len(data) != 1+32+crypto.KeySize+1 {
+			return nil
+		}
+		R := key().Public().Key()
+		msg := append([]byte{}, data[:33]...)
+		msg = append(msg, R[:]...)
+		return append(msg, data[33+crypto.KeySize:]...)
+	case network.PeerMessageTypeTransactionChallenge:
+		if len(data) < 33 {
+			return nil
+		}
+		var cosi crypto.CosiSignature
+		rest, err := cosi.Loads(data[33:])
+		if err != nil || len(cosi.Signatures) == 0 {
+			return nil
+		}
+		cosi.Signatures[0] = forgeSignature(key(), data[1:33])
+		msg := append([]byte{}, data[:33]...)
+		msg = append(msg, cosi.Dumps()...)
+		return append(msg, rest...)
+	case network.PeerMessageTypeSnapshotResponse:
+		if len(data) != 1+32+crypto.ResponseSize {
+			return nil
+		}
+		si := key().Key()
+		msg := append([]byte{}, data[:33]...)
+		return append(msg, si[:crypto.ResponseSize]...)
+	case network.PeerMessageTypeSnapshotFinalization:
+		var s common.Snapshot
+		err := common.MsgpackUnmarshal(data[1:], &s)
+		if err != nil || s.Signature == nil || len(s.Signature.Signatures) == 0 {
+			return nil
+		}
+		s.Signature.Signatures[0] = forgeSignature(key(), s.Hash[:])
+		return append([]byte{data[0]}, common.MsgpackMarshalPanic(&s)...)
+	}
+	return nil
+}
+
+func forgeSignature(key crypto.PrivateKey, message []byte) crypto.Signature {
+	sig, err := key.Sign(message)
+	if err != nil {
+		panic(err)
+	}
+	return *sig
+}
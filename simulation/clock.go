@@ -0,0 +1,149 @@
+package simulation
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// VirtualClock is the clock shared by all nodes of a simulation, it only
+// advances when the scheduler steps. The sleeps and timers of the nodes wait
+// on the virtual clock too, and they are fired in the order of their deadlines
+// when the clock advances, the simulation lets the nodes settle after each
+// deadline, so the timers of different deadlines never race with each other.
+type VirtualClock struct {
+	sync.Mutex
+	now      time.Time
+	waiters  clockWaiters
+	sequence uint64
+	settle   func()
+}
+
+type clockWaiter struct {
+	at       time.Time
+	sequence uint64
+	ch       chan time.Time
+}
+
+type clockWaiters []*clockWaiter
+
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+func (c *VirtualClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// After returns a channel receives the virtual time when the clock advances
+// d from now, it fires immediately if d is not positive.
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.sequence += 1
+	heap.Push(&c.waiters, &clockWaiter{
+		at:       c.now.Add(d),
+		sequence: c.sequence,
+		ch:       ch,
+	})
+	return ch
+}
+
+func (c *VirtualClock) Advance(d time.Duration) time.Time {
+	return c.AdvanceTo(c.Now().Add(d))
+}
+
+// Jump moves the clock d forward at once, like the wall clock of a node
+// suspended for a long time. All the waiters due are fired at the new time,
+// the periodic loops won't run once for each period skipped.
+func (c *VirtualClock) Jump(d time.Duration) time.Time {
+	c.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.Unlock()
+	return c.AdvanceTo(now)
+}
+
+// AdvanceTo moves the clock to t, the waiters due before t are fired in the
+// order of their deadlines, and the clock is at the deadline of the waiters
+// when they fire. The waiters of the same deadline fire together, most of
+// them are the loops started at the same time. The clock never goes backward.
+func (c *VirtualClock) AdvanceTo(t time.Time) time.Time {
+	for {
+		c.Lock()
+		ws := c.due(t)
+		if len(ws) == 0 {
+			if t.After(c.now) {
+				c.now = t
+			}
+			now := c.now
+			c.Unlock()
+			return now
+		}
+		if ws[0].at.After(c.now) {
+			c.now = ws[0].at
+		}
+		now, settle := c.now, c.settle
+		c.Unlock()
+
+		for _, w := range ws {
+			w.ch <- now
+		}
+		if settle != nil {
+			settle()
+		}
+	}
+}
+
+// due pops all the waiters of the earliest deadline not after t.
+func (c *VirtualClock) due(t time.Time) []*clockWaiter {
+	var ws []*clockWaiter
+	for len(c.waiters) > 0 {
+		w := c.waiters[0]
+		if w.at.After(t) || len(ws) > 0 && !w.at.Equal(ws[0].at) {
+			break
+		}
+		ws = append(ws, heap.Pop(&c.waiters).(*clockWaiter))
+	}
+	return ws
+}
+
+func (ws clockWaiters) Len() int {
+	return len(ws)
+}
+
+func (ws clockWaiters) Less(i, j int) bool {
+	a, b := ws[i], ws[j]
+	if !a.at.Equal(b.at) {
+		return a.at.Before(b.at)
+	}
+	return a.sequence < b.sequence
+}
+
+func (ws clockWaiters) Swap(i, j int) {
+	ws[i], ws[j] = ws[j], ws[i]
+}
+
+func (ws *clockWaiters) Push(x interface{}) {
+	*ws = append(*ws, x.(*clockWaiter))
+}
+
+func (ws *clockWaiters) Pop() interface{} {
+	old := *ws
+	w := old[len(old)-1]
+	*ws = old[:len(old)-1]
+	return w
+}
@@ -0,0 +1,9 @@
+// +build ed25519 !custom_alg
+
+package simulation
+
+import "github.com/MixinNetwork/mixin/crypto/ed25519"
+
+func init() {
+	ed25519.Load()
+}
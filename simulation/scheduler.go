@@ -0,0 +1,314 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/network"
+)
+
+// Faults are the probabilities to inject failures into each message, the
+// decisions are made by the seeded scheduler, so the same seed injects the
+// same faults to the same sequence of messages.
+type Faults struct {
+	DropRate      float64
+	DelayRate     float64
+	DelayMaximum  time.Duration
+	Byzantine     map[string]bool
+	ByzantineRate float64
+}
+
+type pendingMessage struct {
+	msg      *network.MemoryMessage
+	kind     uint8
+	sequence uint64
+	hash     crypto.Hash
+	at       time.Time
+	delayed  bool
+}
+
+// Scheduler is the router of the simulation memory network. It holds all
+// messages sent by the nodes, and each step picks one message ready at the
+// virtual time with the seeded random source, then delivers, drops, delays
+// it, or replaces it with a byzantine message. The messages of the same link
+// are picked in the sent order like the real transports, only the delayed
+// ones are reordered. With a replay trace, the scheduler follows the recorded
+// decisions instead, and waits until the recorded message is sent.
+type Scheduler struct {
+	sync.Mutex
+	clock     *VirtualClock
+	tick      time.Duration
+	rand      *rand.Rand
+	faults    Faults
+	groups    map[string]int
+	pending   []*pendingMessage
+	sequences map[string]uint64
+	trace     *Trace
+	replay    []*TraceEvent
+	diverged  int
+	step      uint64
+}
+
+func NewScheduler(clock *VirtualClock, seed int64, tick time.Duration, faults Faults) *Scheduler {
+	return &Scheduler{
+		clock:     clock,
+		tick:      tick,
+		rand:      rand.New(rand.NewSource(seed)),
+		faults:    faults,
+		groups:    make(map[string]int),
+		sequences: make(map[string]uint64),
+		trace:     new(Trace),
+	}
+}
+
+// Replay makes the scheduler follow the decisions of the trace, the seeded
+// random source and the faults are not used anymore.
+func (s *Scheduler) Replay(trace *Trace) {
+	s.Lock()
+	defer s.Unlock()
+
+	trace.Lock()
+	s.replay = append([]*TraceEvent{}, trace.Events...)
+	trace.Unlock()
+}
+
+func (s *Scheduler) Trace() *Trace {
+	return s.trace
+}
+
+// Diverged returns how many replayed messages have different data from
+// the trace, and whether the replay is done.
+func (s *Scheduler) Diverged() (int, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.diverged, len(s.replay) == 0
+}
+
+func (s *Scheduler) Pending() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.pending)
+}
+
+// Partition splits the nodes into groups, messages between different groups
+// are dropped, and nodes not in any group are isolated from all others.
+func (s *Scheduler) Partition(groups ...[]string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.groups = make(map[string]int)
+	for i, g := range groups {
+		for _, addr := range g {
+			s.groups[addr] = i + 1
+		}
+	}
+}
+
+func (s *Scheduler) Heal() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.groups = make(map[string]int)
+}
+
+func (s *Scheduler) Route(msg *network.MemoryMessage) {
+	s.Lock()
+	defer s.Unlock()
+
+	var kind uint8
+	if len(msg.Data) > 0 {
+		kind = msg.Data[0]
+	}
+	key := fmt.Sprintf("%s:%s:%d", msg.From, msg.To, kind)
+	s.sequences[key] += 1
+	s.pending = append(s.pending, &pendingMessage{
+		msg:      msg,
+		kind:     kind,
+		sequence: s.sequences[key],
+		hash:     crypto.NewHash(msg.Data),
+		at:       s.clock.Now(),
+	})
+}
+
+// Step advances the virtual clock to the next step time, and handles at most
+// one message, it returns nil if no message is handled in this step.
+// The clock advances out of the scheduler lock, so the nodes woken by the
+// clock are still able to send messages.
+func (s *Scheduler) Step() *TraceEvent {
+	now := s.clock.AdvanceTo(s.next())
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.replay != nil {
+		return s.replayStep(now)
+	}
+
+	ready := make([]*pendingMessage, 0)
+	links := make(map[string]bool)
+	for _, p := range s.pending {
+		link := p.msg.From + ":" + p.msg.To
+		if p.at.After(now) || links[link] {
+			continue
+		}
+		links[link] = true
+		ready = append(ready, p)
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		a, b := ready[i], ready[j]
+		if a.msg.From != b.msg.From {
+			return a.msg.From < b.msg.From
+		}
+		if a.msg.To != b.msg.To {
+			return a.msg.To < b.msg.To
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.sequence < b.sequence
+	})
+
+	s.step += 1
+	p := ready[s.rand.Intn(len(ready))]
+	e := s.event(p, now)
+	switch {
+	case s.partitioned(p.msg.From, p.msg.To):
+		e.Action = ActionPartition
+	case s.rand.Float64() < s.faults.DropRate:
+		e.Action = ActionDrop
+	case !p.delayed && s.faults.DelayMaximum > 0 && s.rand.Float64() < s.faults.DelayRate:
+		e.Action = ActionDelay
+		e.Delay = time.Duration(s.rand.Int63n(int64(s.faults.DelayMaximum))) + 1
+	case s.faults.Byzantine[p.msg.From] && s.rand.Float64() < s.faults.ByzantineRate:
+		e.Action = ActionByzantine
+		e.Seed = s.rand.Int63()
+	default:
+		e.Action = ActionDeliver
+	}
+	s.apply(p, e, now)
+	return e
+}
+
+// next returns the virtual time of the next step, the clock stays if some
+// message is ready, so the messages sent at the same time are all delivered
+// before any node loop wakes up, otherwise it advances one tick.
+// In replay it is the time of the next recorded event, or one tick later if
+// the recorded message is still not sent at that time.
+func (s *Scheduler) next() time.Time {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.clock.Now()
+	if s.replay == nil {
+		for _, p := range s.pending {
+			if !p.at.After(now) {
+				return now
+			}
+		}
+		return now.Add(s.tick)
+	}
+	if len(s.replay) == 0 {
+		return now.Add(s.tick)
+	}
+	r := s.replay[0]
+	at := time.Unix(0, int64(r.Time))
+	if at.After(now) || s.replayed(r) != nil {
+		return at
+	}
+	return now.Add(s.tick)
+}
+
+func (s *Scheduler) replayed(r *TraceEvent) *pendingMessage {
+	for _, m := range s.pending {
+		if m.msg.From == r.From && m.msg.To == r.To && m.kind == r.Type && m.sequence == r.Sequence {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) replayStep(now time.Time) *TraceEvent {
+	if len(s.replay) == 0 {
+		return nil
+	}
+	r := s.replay[0]
+	p := s.replayed(r)
+	if p == nil {
+		return nil
+	}
+	s.replay = s.replay[1:]
+
+	s.step = r.Step
+	e := s.event(p, now)
+	e.Action, e.Delay, e.Seed = r.Action, r.Delay, r.Seed
+	if e.Hash != r.Hash {
+		s.diverged += 1
+	}
+	s.apply(p, e, now)
+	return e
+}
+
+func (s *Scheduler) event(p *pendingMessage, now time.Time) *TraceEvent {
+	return &TraceEvent{
+		Step:     s.step,
+		Time:     uint64(now.UnixNano()),
+		From:     p.msg.From,
+		To:       p.msg.To,
+		Type:     p.kind,
+		Sequence: p.sequence,
+		Hash:     p.hash,
+	}
+}
+
+func (s *Scheduler) apply(p *pendingMessage, e *TraceEvent, now time.Time) {
+	defer s.trace.append(e)
+
+	if e.Action == ActionDelay {
+		p.at = now.Add(e.Delay)
+		p.delayed = true
+		return
+	}
+	s.remove(p)
+
+	switch e.Action {
+	case ActionByzantine:
+		data := byzantineMessage(p.msg.Data, e.Seed)
+		if data == nil {
+			e.Action = ActionDeliver
+		} else {
+			p.msg.Data = data
+		}
+		fallthrough
+	case ActionDeliver:
+		err := p.msg.Deliver()
+		if err != nil {
+			e.Error = err.Error()
+		}
+	}
+}
+
+func (s *Scheduler) remove(p *pendingMessage) {
+	for i, m := range s.pending {
+		if m == p {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) partitioned(from, to string) bool {
+	if len(s.groups) == 0 {
+		return false
+	}
+	a, b := s.groups[from], s.groups[to]
+	return a == 0 || a != b
+}
@@ -0,0 +1,389 @@
+// Package simulation runs many kernel nodes in one process to reproduce
+// consensus bugs. The nodes talk through an in-process memory network, all
+// messages are routed by a seeded scheduler on a virtual clock, which also
+// injects the faults, and every scheduler decision is recorded in a trace.
+//
+// All the sleeps and timers of the nodes wait on the virtual clock, and the
+// simulation lets all node goroutines settle, i.e. block on the clock or the
+// network, after each timer deadline and message, so the nodes never race
+// with the scheduler or the clock, and the same seed makes the same decisions
+// for the same messages. A trace can be replayed to enforce the same message
+// order and faults, the cosi nonces are still random, so a replay reports how
+// many messages have diverged from the trace.
+package simulation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/kernel"
+	"github.com/MixinNetwork/mixin/network"
+	"github.com/MixinNetwork/mixin/storage"
+	"github.com/VictoriaMetrics/fastcache"
+)
+
+const (
+	GenesisEpoch            = 1551312000
+	ListenerPortMin         = 7001
+	SettleAttemptsLimit     = 100000
+	SettleIdleConfirmations = 3
+	SettleDumpInterval      = 100
+)
+
+type Config struct {
+	Nodes  int
+	Seed   int64
+	Start  time.Time
+	Tick   time.Duration
+	Faults Faults
+	Replay *Trace
+}
+
+type Simulation struct {
+	Nodes     []*kernel.Node
+	Customs   []*config.Custom
+	Stores    []storage.Store
+	Listeners []string
+	Signers   []common.Address
+	Payees    []common.Address
+	Clock     *VirtualClock
+	Scheduler *Scheduler
+	Network   *network.MemoryNetwork
+
+	root    string
+	started bool
+}
+
+// New setups the genesis and nodes of the simulation in root directory, the
+// node keys are generated from the seed, so the same seed produces the same
+// genesis.
+func New(root string, conf *Config) (*Simulation, error) {
+	if conf.Nodes < kernel.MinimumNodeCount {
+		return nil, fmt.Errorf("invalid simulation nodes count %d/%d", conf.Nodes, kernel.MinimumNodeCount)
+	}
+	start := conf.Start
+	if start.IsZero() {
+		start = time.Unix(GenesisEpoch, 0).Add(time.Hour)
+	}
+	tick := conf.Tick
+	if tick <= 0 {
+		tick = 10 * time.Millisecond
+	}
+
+	sim := &Simulation{Clock: NewVirtualClock(start), root: root}
+	sim.Scheduler = NewScheduler(sim.Clock, conf.Seed, tick, conf.Faults)
+	if conf.Replay != nil {
+		sim.Scheduler.Replay(conf.Replay)
+	}
+	sim.Network = network.NewMemoryNetwork(sim.Scheduler, sim.Clock)
+
+	err := sim.setupGenesis(root, conf.Nodes, conf.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sim.Signers {
+		err := sim.setupNode(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sim, nil
+}
+
+// Join adds a new node with the signer to the simulation, the node uses the
+// same genesis and neighbors as the others, and it is started at once if the
+// simulation is started. The node still needs a pledge to join consensus.
+func (sim *Simulation) Join(signer, payee common.Address) (*kernel.Node, error) {
+	i := len(sim.Signers)
+	listener := fmt.Sprintf("127.0.0.1:%d", ListenerPortMin+i)
+	genesisData, err := ioutil.ReadFile(nodeDirectory(sim.root, 0) + "/genesis.json")
+	if err != nil {
+		return nil, err
+	}
+	nodesData, err := ioutil.ReadFile(nodeDirectory(sim.root, 0) + "/nodes.json")
+	if err != nil {
+		return nil, err
+	}
+	err = writeNodeConfig(nodeDirectory(sim.root, i), signer, listener, genesisData, nodesData)
+	if err != nil {
+		return nil, err
+	}
+
+	sim.Signers = append(sim.Signers, signer)
+	sim.Payees = append(sim.Payees, payee)
+	sim.Listeners = append(sim.Listeners, listener)
+	err = sim.setupNode(i)
+	if err != nil {
+		return nil, err
+	}
+	node := sim.Nodes[i]
+	if sim.started {
+		go node.Loop()
+		settle()
+	}
+	return node, nil
+}
+
+func (sim *Simulation) setupNode(i int) error {
+	dir := nodeDirectory(sim.root, i)
+	custom, err := config.Initialize(dir + "/config.toml")
+	if err != nil {
+		return err
+	}
+	cache := fastcache.New(custom.Node.MemoryCacheSize * 1024 * 1024)
+	store, err := storage.NewBadgerStore(custom, dir)
+	if err != nil {
+		return err
+	}
+	node, err := kernel.SetupNode(custom, store, cache, sim.Clock, sim.Listeners[i], dir)
+	if err != nil {
+		return err
+	}
+	node.SetTransportFactory(sim.Network.Factory(sim.Listeners[i]))
+	sim.Nodes = append(sim.Nodes, node)
+	sim.Customs = append(sim.Customs, custom)
+	sim.Stores = append(sim.Stores, store)
+	return nil
+}
+
+func (sim *Simulation) Start() {
+	sim.started = true
+	sim.Clock.settle = settle
+	for _, node := range sim.Nodes {
+		go node.Loop()
+	}
+	settle()
+}
+
+// Run steps the scheduler, and lets the nodes settle after each step to
+// handle the delivered message, it returns the messages handled.
+func (sim *Simulation) Run(steps int) int {
+	var handled int
+	for i := 0; i < steps; i++ {
+		if sim.Scheduler.Step() != nil {
+			handled += 1
+		}
+		settle()
+	}
+	return handled
+}
+
+// RunUntil steps the scheduler until done returns true, or gives up after
+// the maximum steps.
+func (sim *Simulation) RunUntil(done func() bool, steps int) bool {
+	for i := 0; i < steps; i++ {
+		if done() {
+			return true
+		}
+		sim.Run(1)
+	}
+	return done()
+}
+
+// Ready checks whether all nodes have broadcasted to and caught up with their
+// peers, the nodes won't make new snapshots before ready.
+func (sim *Simulation) Ready() bool {
+	for _, node := range sim.Nodes {
+		if !node.CheckBroadcastedToPeers() || !node.CheckCatchUpWithPeers() {
+			return false
+		}
+	}
+	return true
+}
+
+// Teardown stops all nodes, and keeps advancing the virtual clock until all
+// of them are stopped, because the node loops wait on the clock to exit.
+func (sim *Simulation) Teardown() {
+	if !sim.started {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		for _, node := range sim.Nodes {
+			node.Teardown()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			sim.Clock.Advance(sim.Scheduler.tick)
+			settle()
+		}
+	}
+}
+
+func (sim *Simulation) setupGenesis(root string, count int, seed int64) error {
+	r := rand.New(rand.NewSource(seed))
+	account := func() common.Address {
+		seed := make([]byte, 64)
+		r.Read(seed)
+		a := common.NewAddressFromSeed(seed)
+		a.PrivateViewKey = a.PublicSpendKey.DeterministicHashDerive()
+		a.PublicViewKey = a.PrivateViewKey.Public()
+		return a
+	}
+
+	inputs := make([]map[string]string, 0)
+	nodes := make([]map[string]string, 0)
+	for i := 0; i < count; i++ {
+		signer, payee := account(), account()
+		listener := fmt.Sprintf("127.0.0.1:%d", ListenerPortMin+i)
+		sim.Signers = append(sim.Signers, signer)
+		sim.Payees = append(sim.Payees, payee)
+		sim.Listeners = append(sim.Listeners, listener)
+		inputs = append(inputs, map[string]string{
+			"signer":  signer.String(),
+			"payee":   payee.String(),
+			"balance": "10000",
+		})
+		nodes = append(nodes, map[string]string{
+			"host":   listener,
+			"signer": signer.String(),
+		})
+	}
+	genesis := map[string]interface{}{
+		"epoch": GenesisEpoch,
+		"nodes": inputs,
+		"domains": []map[string]string{
+			{
+				"signer":  sim.Signers[0].String(),
+				"balance": "50000",
+			},
+		},
+	}
+	genesisData, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	nodesData, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	for i, a := range sim.Signers {
+		err := writeNodeConfig(nodeDirectory(root, i), a, sim.Listeners[i], genesisData, nodesData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNodeConfig(dir string, signer common.Address, listener string, genesisData, nodesData []byte) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	configData := []byte(fmt.Sprintf(configDataTmpl, signer.PrivateSpendKey.String(), listener))
+	err = ioutil.WriteFile(dir+"/config.toml", configData, 0644)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(dir+"/genesis.json", genesisData, 0644)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dir+"/nodes.json", nodesData, 0644)
+}
+
+// settle yields to all other goroutines until none of them is running or
+// runnable, so all of them are blocked on the virtual clock, the network or
+// locks, and the nodes are not able to make progress without the scheduler.
+// The scheduler counters of the runtime are much cheaper than the goroutines
+// dump, but they are approximate, so they must be idle several times in a
+// row, and the dump is checked if they keep busy for a while, or they are not
+// supported by the runtime.
+func settle() {
+	buf := make([]byte, 1024*1024)
+	samples := []metrics.Sample{
+		{Name: "/sched/goroutines/running:goroutines"},
+		{Name: "/sched/goroutines/runnable:goroutines"},
+		{Name: "/sched/goroutines/not-in-go:goroutines"},
+	}
+	var idle int
+	for i := 1; i <= SettleAttemptsLimit; i++ {
+		runtime.Gosched()
+		busy, ok := scheduled(samples)
+		if !busy && ok {
+			if idle += 1; idle >= SettleIdleConfirmations {
+				return
+			}
+			continue
+		}
+		idle = 0
+		if ok && i%SettleDumpInterval != 0 {
+			continue
+		}
+		n := runtime.Stack(buf, true)
+		if n == len(buf) {
+			buf = make([]byte, len(buf)*2)
+			continue
+		}
+		if settled(buf[:n]) {
+			return
+		}
+	}
+}
+
+// scheduled reads the scheduler counters, and returns whether any goroutine
+// other than the current one is running, runnable or in system calls. It
+// returns false if any counter is not supported.
+func scheduled(samples []metrics.Sample) (bool, bool) {
+	metrics.Read(samples)
+	var busy uint64
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			return false, false
+		}
+		busy += s.Value.Uint64()
+	}
+	return busy > 1, true
+}
+
+// settled checks the goroutines dump, the first goroutine is the current
+// one and always running, so it is skipped.
+func settled(dump []byte) bool {
+	goroutines := bytes.Split(dump, []byte("\n\ngoroutine "))
+	for _, g := range goroutines[1:] {
+		start, end := bytes.IndexByte(g, '['), bytes.IndexByte(g, ']')
+		if start < 0 || end < start {
+			continue
+		}
+		status := g[start+1 : end]
+		if i := bytes.IndexByte(status, ','); i >= 0 {
+			status = status[:i]
+		}
+		switch string(status) {
+		case "running", "runnable", "syscall":
+			return false
+		}
+	}
+	return true
+}
+
+func nodeDirectory(root string, i int) string {
+	return filepath.Join(root, fmt.Sprintf("mixin-%d", ListenerPortMin+i))
+}
+
+const configDataTmpl = `[node]
+signer-key = "%s"
+consensus-only = true
+memory-cache-size = 64
+kernel-operation-period = 2
+cache-ttl = 3600
+[network]
+listener = "%s"`
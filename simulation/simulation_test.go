@@ -0,0 +1,208 @@
+// +build ed25519 !custom_alg
+
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/domains/ethereum"
+	"github.com/MixinNetwork/mixin/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	assert := assert.New(t)
+
+	run := func(seed int64, faults Faults, replay *Trace) (*Scheduler, [][]byte) {
+		clock := NewVirtualClock(time.Unix(GenesisEpoch, 0))
+		s := NewScheduler(clock, seed, time.Millisecond, faults)
+		if replay != nil {
+			s.Replay(replay)
+		}
+		n := network.NewMemoryNetwork(s, clock)
+		server, _ := n.Factory("127.0.0.1:7001").NewServer("127.0.0.1:7001")
+		assert.Nil(server.Listen())
+		client, _ := n.Factory("127.0.0.1:7002").NewClient("127.0.0.1:7001")
+		c, err := client.Dial(context.Background())
+		assert.Nil(err)
+		r, err := server.Accept(context.Background())
+		assert.Nil(err)
+		for i := 0; i < 64; i++ {
+			assert.Nil(c.Send(testResponseMessage(i)))
+		}
+		for i := 0; i < 1024 && s.Pending() > 0; i++ {
+			s.Step()
+		}
+		c.Close()
+		var received [][]byte
+		for {
+			data, err := r.Receive()
+			if err != nil {
+				break
+			}
+			received = append(received, data)
+		}
+		return s, received
+	}
+
+	s, received := run(1, Faults{}, nil)
+	assert.Len(received, 64)
+	assert.Equal(64, s.Trace().Len())
+	for _, e := range s.Trace().Events {
+		assert.Equal(ActionDeliver, e.Action)
+	}
+
+	faults := Faults{
+		DropRate:      0.1,
+		DelayRate:     0.3,
+		DelayMaximum:  10 * time.Millisecond,
+		Byzantine:     map[string]bool{"127.0.0.1:7002": true},
+		ByzantineRate: 0.2,
+	}
+	s1, r1 := run(7, faults, nil)
+	s2, r2 := run(7, faults, nil)
+	assert.Equal(r1, r2)
+	assert.Equal(s1.Trace().Events, s2.Trace().Events)
+	assert.Less(len(r1), 64)
+	actions := make(map[string]int)
+	for _, e := range s1.Trace().Events {
+		actions[e.Action] += 1
+	}
+	assert.Greater(actions[ActionDrop], 0)
+	assert.Greater(actions[ActionDelay], 0)
+	assert.Greater(actions[ActionByzantine], 0)
+	for _, data := range r1 {
+		assert.Len(data, len(testResponseMessage(0)))
+		assert.Equal(uint8(network.PeerMessageTypeSnapshotResponse), data[0])
+	}
+	sent := make(map[string]bool)
+	for i := 0; i < 64; i++ {
+		sent[string(testResponseMessage(i))] = true
+	}
+	var forged int
+	for _, data := range r1 {
+		if !sent[string(data)] {
+			forged += 1
+		}
+	}
+	assert.Equal(actions[ActionByzantine], forged)
+
+	var buf bytes.Buffer
+	assert.Nil(s1.Trace().Write(&buf))
+	trace, err := ReadTrace(&buf)
+	assert.Nil(err)
+	assert.Equal(s1.Trace().Events, trace.Events)
+	s3, r3 := run(99, Faults{}, trace)
+	assert.Equal(r1, r3)
+	diverged, done := s3.Diverged()
+	assert.Equal(0, diverged)
+	assert.True(done)
+
+	clock := NewVirtualClock(time.Unix(GenesisEpoch, 0))
+	s = NewScheduler(clock, 1, time.Millisecond, Faults{})
+	s.Partition([]string{"127.0.0.1:7001"}, []string{"127.0.0.1:7002"})
+	assert.True(s.partitioned("127.0.0.1:7002", "127.0.0.1:7001"))
+	assert.True(s.partitioned("127.0.0.1:7003", "127.0.0.1:7001"))
+	assert.False(s.partitioned("127.0.0.1:7001", "127.0.0.1:7001"))
+	s.Heal()
+	assert.False(s.partitioned("127.0.0.1:7002", "127.0.0.1:7001"))
+}
+
+func TestSimulation(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "mixin-simulation-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	sim, err := New(root, &Config{Nodes: 7, Seed: 1})
+	assert.Nil(err)
+	defer sim.Teardown()
+	assert.Len(sim.Nodes, 7)
+
+	sim.Start()
+	handled := sim.Run(300)
+	assert.Greater(handled, 0)
+	assert.Greater(sim.Scheduler.Trace().Len(), 0)
+	assert.True(sim.RunUntil(func() bool { return sim.Ready() && testTopologyEqual(sim) }, 1000))
+
+	deposit := testDeposit(sim, 0)
+	_, err = sim.Nodes[0].QueueTransaction(deposit)
+	assert.Nil(err)
+	assert.True(sim.RunUntil(func() bool { return testFinalized(sim, deposit) == len(sim.Nodes) }, 1000))
+	assert.True(sim.RunUntil(func() bool { return testTopologyEqual(sim) }, 1000))
+	order := sim.Nodes[0].TopologicalOrder()
+
+	sim.Scheduler.Partition(sim.Listeners[:4], sim.Listeners[4:])
+	deposit = testDeposit(sim, 1)
+	_, err = sim.Nodes[0].QueueTransaction(deposit)
+	assert.Nil(err)
+	sim.Run(1000)
+	assert.Equal(0, testFinalized(sim, deposit))
+	for _, node := range sim.Nodes {
+		assert.Equal(order, node.TopologicalOrder())
+	}
+
+	sim.Scheduler.Heal()
+	assert.True(sim.RunUntil(func() bool {
+		finalized := testFinalized(sim, deposit)
+		return testTopologyEqual(sim) && (finalized == 0 || finalized == len(sim.Nodes))
+	}, 1000))
+	assert.GreaterOrEqual(sim.Nodes[0].TopologicalOrder(), order)
+}
+
+func testResponseMessage(i int) []byte {
+	data := make([]byte, 1+32+crypto.ResponseSize)
+	data[0] = network.PeerMessageTypeSnapshotResponse
+	data[1] = byte(i)
+	return data
+}
+
+func testDeposit(sim *Simulation, i int) *common.VersionedTransaction {
+	domain := sim.Signers[0]
+	amount := common.NewIntegerFromString("1")
+	assetKey := "0xa974c709cfb4566686553a20790685a47aceaa33"
+	tx := common.NewTransaction(ethereum.GenerateAssetId(assetKey))
+	tx.AddDepositInput(&common.DepositData{
+		Chain:           ethereum.EthereumChainId,
+		AssetKey:        assetKey,
+		TransactionHash: fmt.Sprintf("0xc7c1132b58e1f64c263957d7857fe5ec5294fce95d30dcd64efef71da1%06d", i),
+		Amount:          amount,
+	})
+	seed := crypto.NewHash([]byte(fmt.Sprintf("simulation-deposit-%d", i)))
+	tx.AddOutputWithType(common.OutputTypeScript, []common.Address{domain}, common.NewThresholdScript(1), amount, append(seed[:], seed[:]...))
+	ver := tx.AsLatestVersion()
+	err := ver.SignInput(nil, 0, []common.Address{domain})
+	if err != nil {
+		panic(err)
+	}
+	return ver
+}
+
+func testFinalized(sim *Simulation, tx *common.VersionedTransaction) int {
+	var count int
+	for _, store := range sim.Stores {
+		_, finalized, err := store.ReadTransaction(tx.PayloadHash())
+		if err == nil && len(finalized) > 0 {
+			count += 1
+		}
+	}
+	return count
+}
+
+func testTopologyEqual(sim *Simulation) bool {
+	for _, node := range sim.Nodes {
+		if node.TopologicalOrder() != sim.Nodes[0].TopologicalOrder() {
+			return false
+		}
+	}
+	return true
+}
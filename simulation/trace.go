@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/MixinNetwork/mixin/crypto"
+)
+
+const (
+	ActionDeliver   = "deliver"
+	ActionDrop      = "drop"
+	ActionPartition = "partition"
+	ActionDelay     = "delay"
+	ActionByzantine = "byzantine"
+)
+
+// TraceEvent records one scheduler decision. A message is identified by its
+// sender, receiver, peer message type and the sequence of the same kind of
+// messages, because the message data includes timestamps, the hash is only
+// used to tell whether a replay diverges from the trace.
+type TraceEvent struct {
+	Step     uint64        `json:"step"`
+	Time     uint64        `json:"time"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Type     uint8         `json:"type"`
+	Sequence uint64        `json:"sequence"`
+	Hash     crypto.Hash   `json:"hash"`
+	Action   string        `json:"action"`
+	Delay    time.Duration `json:"delay,omitempty"`
+	Seed     int64         `json:"seed,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type Trace struct {
+	sync.Mutex
+	Events []*TraceEvent
+}
+
+func (t *Trace) append(e *TraceEvent) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.Events = append(t.Events, e)
+}
+
+func (t *Trace) Len() int {
+	t.Lock()
+	defer t.Unlock()
+
+	return len(t.Events)
+}
+
+// Write encodes the trace as JSON lines, one event per line.
+func (t *Trace) Write(w io.Writer) error {
+	t.Lock()
+	defer t.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, e := range t.Events {
+		err := enc.Encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadTrace(r io.Reader) (*Trace, error) {
+	var t Trace
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e TraceEvent
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, err
+		}
+		t.Events = append(t.Events, &e)
+	}
+	return &t, scanner.Err()
+}
@@ -1,19 +1,26 @@
 package storage
 
 import (
+	"sync"
 	"time"
 
+	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/config"
 	"github.com/MixinNetwork/mixin/logger"
 	"github.com/dgraph-io/badger/v2"
 	"github.com/dgraph-io/badger/v2/options"
 )
 
+const badgerConflictRetries = 16
+
 type BadgerStore struct {
 	custom      *config.Custom
 	snapshotsDB *badger.DB
 	cacheDB     *badger.DB
 	closing     bool
+
+	hooksLock     sync.RWMutex
+	conflictHooks []func(c *common.UTXOConflict)
 }
 
 func NewBadgerStore(custom *config.Custom, dir string) (*BadgerStore, error) {
@@ -42,6 +49,18 @@ func (store *BadgerStore) Close() error {
 	return store.cacheDB.Close()
 }
 
+// updateSnapshots runs the update again when it conflicts with a concurrent
+// update of the same keys, e.g. all chains may lock the inputs of the same
+// transaction at the same time, and the update reads the state again.
+func (s *BadgerStore) updateSnapshots(fn func(txn *badger.Txn) error) error {
+	for i := 0; ; i++ {
+		err := s.snapshotsDB.Update(fn)
+		if err != badger.ErrConflict || i >= badgerConflictRetries {
+			return err
+		}
+	}
+}
+
 func openDB(dir string, sync, valueLogGC, truncate bool) (*badger.DB, error) {
 	opts := badger.DefaultOptions(dir)
 	opts = opts.WithSyncWrites(sync)
@@ -12,6 +12,7 @@ const (
 	cachePrefixTransactionCache  = "TRANSACTIONCACHE"
 	cachePrefixSnapshotNodeQueue = "SNAPSHOTNODEQUEUE"
 	cachePrefixSnapshotNodeMeta  = "SNAPSHOTNODEMETA"
+	cachePrefixCosiSession       = "COSISESSION"
 )
 
 func (s *BadgerStore) CacheListTransactions(hook func(tx *common.VersionedTransaction) error) error {
@@ -85,6 +86,60 @@ func (s *BadgerStore) CacheGetTransaction(hash crypto.Hash) (*common.VersionedTr
 	return common.DecompressUnmarshalVersionedTransaction(val)
 }
 
+// CachePutCosiSession checkpoints the cosi session state of a snapshot, the
+// session expires with the cache TTL, because the snapshot is useless then.
+func (s *BadgerStore) CachePutCosiSession(chain, snapshot crypto.Hash, data []byte) error {
+	txn := s.cacheDB.NewTransaction(true)
+	defer txn.Discard()
+
+	key := cacheCosiSessionKey(chain, snapshot)
+	etr := badger.NewEntry(key, data).WithTTL(time.Duration(s.custom.Node.CacheTTL) * time.Second)
+	err := txn.SetEntry(etr)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+func (s *BadgerStore) CacheRemoveCosiSession(chain, snapshot crypto.Hash) error {
+	txn := s.cacheDB.NewTransaction(true)
+	defer txn.Discard()
+
+	err := txn.Delete(cacheCosiSessionKey(chain, snapshot))
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+func (s *BadgerStore) CacheListCosiSessions(chain crypto.Hash, hook func(snapshot crypto.Hash, data []byte) error) error {
+	txn := s.cacheDB.NewTransaction(false)
+	defer txn.Discard()
+
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := cacheCosiSessionKey(chain, crypto.Hash{})[:len(cachePrefixCosiSession)+len(chain)]
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		var snapshot crypto.Hash
+		copy(snapshot[:], it.Item().Key()[len(prefix):])
+		v, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		err = hook(snapshot, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheCosiSessionKey(chain, snapshot crypto.Hash) []byte {
+	key := append([]byte(cachePrefixCosiSession), chain[:]...)
+	return append(key, snapshot[:]...)
+}
+
 func cacheTransactionCacheKey(hash crypto.Hash) []byte {
 	return append([]byte(cachePrefixTransactionCache), hash[:]...)
 }
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// A conflict is keyed by the UTXO and the spender, so the same conflict is
+// recorded only once, and all the records expire with the cache TTL.
+const (
+	cachePrefixConflictUTXO        = "CONFLICTUTXO"
+	cachePrefixConflictTransaction = "CONFLICTTRANSACTION"
+	cachePrefixConflictTimestamp   = "CONFLICTTIMESTAMP"
+)
+
+// SubscribeConflicts registers an in-process hook for the new conflicts, the
+// RPC clients should poll them with ListConflicts instead.
+func (s *BadgerStore) SubscribeConflicts(hook func(c *common.UTXOConflict)) {
+	s.hooksLock.Lock()
+	defer s.hooksLock.Unlock()
+
+	s.conflictHooks = append(s.conflictHooks, hook)
+}
+
+func (s *BadgerStore) ReadUTXOConflicts(hash crypto.Hash, index int) ([]*common.UTXOConflict, error) {
+	return s.readConflicts(cacheConflictUTXOPrefix(hash, index))
+}
+
+func (s *BadgerStore) ReadTransactionConflicts(hash crypto.Hash) ([]*common.UTXOConflict, error) {
+	return s.readConflicts(cacheConflictTransactionPrefix(hash))
+}
+
+// ListConflicts returns the conflicts recorded since the timestamp, ordered
+// by the timestamp, so the new conflicts can be polled continuously.
+func (s *BadgerStore) ListConflicts(since uint64, count int) ([]*common.UTXOConflict, error) {
+	txn := s.cacheDB.NewTransaction(false)
+	defer txn.Discard()
+
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := []byte(cachePrefixConflictTimestamp)
+	conflicts := make([]*common.UTXOConflict, 0)
+	for it.Seek(cacheConflictTimestampKey(since, nil)); it.ValidForPrefix(prefix) && len(conflicts) < count; it.Next() {
+		c, err := readConflict(it.Item())
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+func (s *BadgerStore) readConflicts(prefix []byte) ([]*common.UTXOConflict, error) {
+	txn := s.cacheDB.NewTransaction(false)
+	defer txn.Discard()
+
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	conflicts := make([]*common.UTXOConflict, 0)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		c, err := readConflict(it.Item())
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Timestamp < conflicts[j].Timestamp
+	})
+	return conflicts, nil
+}
+
+func readConflict(item *badger.Item) (*common.UTXOConflict, error) {
+	ival, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	var c common.UTXOConflict
+	err = common.DecompressMsgpackUnmarshal(ival, &c)
+	return &c, err
+}
+
+// writeConflict records the conflict unless the same one is recorded, and
+// calls the hooks out of the hooks lock.
+func (s *BadgerStore) writeConflict(c *common.UTXOConflict) error {
+	c.Timestamp = uint64(time.Now().UnixNano())
+	ttl := time.Duration(s.custom.Node.CacheTTL) * time.Second
+	val := common.CompressMsgpackMarshalPanic(c)
+	utxo := graphUtxoKey(c.Hash, c.Index)[len(graphPrefixUTXO):]
+	id := append(utxo, c.Spender[:]...)
+
+	var recorded bool
+	err := s.cacheDB.Update(func(txn *badger.Txn) error {
+		key := append(cacheConflictUTXOPrefix(c.Hash, c.Index), c.Spender[:]...)
+		item, err := txn.Get(key)
+		if err == nil {
+			old, err := readConflict(item)
+			if err != nil {
+				return err
+			}
+			if old.Locker == c.Locker && old.Pruned == c.Pruned {
+				recorded = true
+				return nil
+			}
+			err = txn.Delete(cacheConflictTimestampKey(old.Timestamp, id))
+			if err != nil {
+				return err
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		keys := [][]byte{key, cacheConflictTimestampKey(c.Timestamp, id)}
+		for _, h := range []crypto.Hash{c.Locker, c.Spender} {
+			keys = append(keys, append(cacheConflictTransactionPrefix(h), id...))
+		}
+		for _, k := range keys {
+			err := txn.SetEntry(badger.NewEntry(k, val).WithTTL(ttl))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || recorded {
+		return err
+	}
+
+	s.hooksLock.RLock()
+	hooks := s.conflictHooks
+	s.hooksLock.RUnlock()
+	for _, hook := range hooks {
+		hook(c)
+	}
+	return nil
+}
+
+func cacheConflictUTXOPrefix(hash crypto.Hash, index int) []byte {
+	utxo := graphUtxoKey(hash, index)[len(graphPrefixUTXO):]
+	return append([]byte(cachePrefixConflictUTXO), utxo...)
+}
+
+func cacheConflictTransactionPrefix(hash crypto.Hash) []byte {
+	return append([]byte(cachePrefixConflictTransaction), hash[:]...)
+}
+
+func cacheConflictTimestampKey(timestamp uint64, id []byte) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, timestamp)
+	key := append([]byte(cachePrefixConflictTimestamp), buf...)
+	return append(key, id...)
+}
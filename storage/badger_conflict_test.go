@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUTXOConflicts(t *testing.T) {
+	assert := assert.New(t)
+	custom, err := config.Initialize(configFilePath)
+	assert.Nil(err)
+
+	root, err := ioutil.TempDir("", "mixin-conflict-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := NewBadgerStore(custom, root)
+	assert.Nil(err)
+	defer store.Close()
+
+	var events []*common.UTXOConflict
+	store.SubscribeConflicts(func(c *common.UTXOConflict) {
+		events = append(events, c)
+	})
+
+	hash := crypto.NewHash([]byte("utxo"))
+	utxo := &common.UTXOWithLock{UTXO: common.UTXO{Input: common.Input{Hash: hash, Index: 1}}}
+	utxo.Amount = common.NewInteger(1)
+	txn := store.snapshotsDB.NewTransaction(true)
+	err = txn.Set(graphUtxoKey(hash, 1), common.CompressMsgpackMarshalPanic(utxo))
+	assert.Nil(err)
+	err = txn.Commit()
+	assert.Nil(err)
+
+	first := crypto.NewHash([]byte("first"))
+	second := crypto.NewHash([]byte("second"))
+	third := crypto.NewHash([]byte("third"))
+	err = store.LockUTXO(hash, 1, first, false)
+	assert.Nil(err)
+	err = store.LockUTXO(hash, 1, first, false)
+	assert.Nil(err)
+	assert.Len(events, 0)
+
+	err = store.LockUTXO(hash, 1, second, false)
+	assert.NotNil(err)
+	assert.Equal(common.ErrorCodeInputLocked, common.ValidationErrorCode(err))
+	assert.Len(events, 1)
+	assert.Equal(first, events[0].Locker)
+	assert.Equal(second, events[0].Spender)
+	assert.False(events[0].Pruned)
+	err = store.LockUTXO(hash, 1, second, false)
+	assert.NotNil(err)
+	assert.Len(events, 1)
+
+	err = store.LockUTXO(hash, 1, third, true)
+	assert.Nil(err)
+	assert.Len(events, 2)
+	assert.True(events[1].Pruned)
+	out, err := store.ReadUTXO(hash, 1)
+	assert.Nil(err)
+	assert.Equal(third, out.LockHash)
+
+	conflicts, err := store.ReadUTXOConflicts(hash, 1)
+	assert.Nil(err)
+	assert.Len(conflicts, 2)
+	assert.Equal(second, conflicts[0].Spender)
+	assert.Equal(third, conflicts[1].Spender)
+	conflicts, err = store.ReadUTXOConflicts(hash, 0)
+	assert.Nil(err)
+	assert.Len(conflicts, 0)
+
+	conflicts, err = store.ReadTransactionConflicts(first)
+	assert.Nil(err)
+	assert.Len(conflicts, 2)
+	conflicts, err = store.ReadTransactionConflicts(third)
+	assert.Nil(err)
+	assert.Len(conflicts, 1)
+	assert.Equal(first, conflicts[0].Locker)
+	assert.Equal(hash, conflicts[0].Hash)
+	assert.Equal(1, conflicts[0].Index)
+
+	conflicts, err = store.ListConflicts(0, 10)
+	assert.Nil(err)
+	assert.Len(conflicts, 2)
+	assert.Equal(second, conflicts[0].Spender)
+	assert.Equal(third, conflicts[1].Spender)
+	conflicts, err = store.ListConflicts(conflicts[0].Timestamp+1, 10)
+	assert.Nil(err)
+	assert.Len(conflicts, 1)
+	assert.Equal(third, conflicts[0].Spender)
+	conflicts, err = store.ListConflicts(0, 1)
+	assert.Nil(err)
+	assert.Len(conflicts, 1)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The nonce records are written to the snapshots database with synced writes
+// and never expire before the snapshot is finalized, a rolled back counter or
+// usage record after a crash may make the node sign two challenges with the
+// same nonce, which leaks the key. They are removed in the same transaction
+// which finalizes the snapshot, no cosi session is started again after that
+// because the snapshot transaction is finalized.
+const (
+	graphPrefixCosiNonceCounter = "COSINONCECOUNTER"
+	graphPrefixCosiNonceUsage   = "COSINONCEUSAGE"
+)
+
+// NextCosiNonce returns the next nonce counter of the snapshot, each cosi
+// session of the same snapshot must derive its nonce from a new counter.
+func (s *BadgerStore) NextCosiNonce(chain, snapshot crypto.Hash) (uint64, error) {
+	txn := s.snapshotsDB.NewTransaction(true)
+	defer txn.Discard()
+
+	var counter uint64
+	key := graphCosiNonceCounterKey(chain, snapshot)
+	item, err := txn.Get(key)
+	if err == nil {
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return 0, err
+		}
+		counter = binary.BigEndian.Uint64(val) + 1
+	} else if err != badger.ErrKeyNotFound {
+		return 0, err
+	}
+
+	val := make([]byte, 8)
+	binary.BigEndian.PutUint64(val, counter)
+	err = txn.Set(key, val)
+	if err != nil {
+		return 0, err
+	}
+	return counter, txn.Commit()
+}
+
+// CheckCosiNonce records the challenge to be signed with the nonce of the
+// counter, and returns false if the nonce has signed another challenge.
+func (s *BadgerStore) CheckCosiNonce(chain, snapshot crypto.Hash, counter uint64, challenge crypto.Hash) (bool, error) {
+	txn := s.snapshotsDB.NewTransaction(true)
+	defer txn.Discard()
+
+	key := graphCosiNonceUsageKey(chain, snapshot, counter)
+	item, err := txn.Get(key)
+	if err == nil {
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(val, challenge[:]), nil
+	} else if err != badger.ErrKeyNotFound {
+		return false, err
+	}
+
+	err = txn.Set(key, challenge[:])
+	if err != nil {
+		return false, err
+	}
+	return true, txn.Commit()
+}
+
+func pruneCosiNonces(txn *badger.Txn, chain, snapshot crypto.Hash) error {
+	err := txn.Delete(graphCosiNonceCounterKey(chain, snapshot))
+	if err != nil {
+		return err
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var keys [][]byte
+	prefix := graphCosiNonceUsagePrefix(chain, snapshot)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	for _, k := range keys {
+		err := txn.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func graphCosiNonceCounterKey(chain, snapshot crypto.Hash) []byte {
+	key := append([]byte(graphPrefixCosiNonceCounter), chain[:]...)
+	return append(key, snapshot[:]...)
+}
+
+func graphCosiNonceUsagePrefix(chain, snapshot crypto.Hash) []byte {
+	key := append([]byte(graphPrefixCosiNonceUsage), chain[:]...)
+	return append(key, snapshot[:]...)
+}
+
+func graphCosiNonceUsageKey(chain, snapshot crypto.Hash, counter uint64) []byte {
+	key := graphCosiNonceUsagePrefix(chain, snapshot)
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, counter)
+	return append(key, buf...)
+}
@@ -0,0 +1,75 @@
+// +build ed25519 !custom_alg
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosiNonce(t *testing.T) {
+	assert := assert.New(t)
+	custom, err := config.Initialize(configFilePath)
+	assert.Nil(err)
+
+	root, err := ioutil.TempDir("", "mixin-cosi-nonce-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := NewBadgerStore(custom, root)
+	assert.Nil(err)
+
+	chain := crypto.NewHash([]byte("cosi-nonce-chain"))
+	snap := crypto.NewHash([]byte("cosi-nonce-snapshot"))
+	c1 := crypto.NewHash([]byte("challenge-1"))
+	c2 := crypto.NewHash([]byte("challenge-2"))
+
+	counter, err := store.NextCosiNonce(chain, snap)
+	assert.Nil(err)
+	assert.Equal(uint64(0), counter)
+	valid, err := store.CheckCosiNonce(chain, snap, counter, c1)
+	assert.Nil(err)
+	assert.True(valid)
+	assert.Nil(store.Close())
+
+	store, err = NewBadgerStore(custom, root)
+	assert.Nil(err)
+	defer store.Close()
+
+	valid, err = store.CheckCosiNonce(chain, snap, 0, c2)
+	assert.Nil(err)
+	assert.False(valid)
+	valid, err = store.CheckCosiNonce(chain, snap, 0, c1)
+	assert.Nil(err)
+	assert.True(valid)
+	counter, err = store.NextCosiNonce(chain, snap)
+	assert.Nil(err)
+	assert.Equal(uint64(1), counter)
+	valid, err = store.CheckCosiNonce(chain, snap, counter, c2)
+	assert.Nil(err)
+	assert.True(valid)
+
+	other := crypto.NewHash([]byte("cosi-nonce-other"))
+	_, err = store.NextCosiNonce(chain, other)
+	assert.Nil(err)
+	_, err = store.CheckCosiNonce(chain, other, 0, c1)
+	assert.Nil(err)
+
+	txn := store.snapshotsDB.NewTransaction(true)
+	assert.Nil(pruneCosiNonces(txn, chain, snap))
+	assert.Nil(txn.Commit())
+	counter, err = store.NextCosiNonce(chain, snap)
+	assert.Nil(err)
+	assert.Equal(uint64(0), counter)
+	valid, err = store.CheckCosiNonce(chain, snap, 1, c1)
+	assert.Nil(err)
+	assert.True(valid)
+	valid, err = store.CheckCosiNonce(chain, other, 0, c2)
+	assert.Nil(err)
+	assert.False(valid)
+}
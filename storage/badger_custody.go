@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/dgraph-io/badger/v2"
+)
+
+const (
+	graphPrefixDomainCustody = "DOMAINCUSTODY"
+)
+
+func (s *BadgerStore) ReadDomainCustodies() ([]*common.DomainCustody, error) {
+	txn := s.snapshotsDB.NewTransaction(false)
+	defer txn.Discard()
+
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	custodies := make([]*common.DomainCustody, 0)
+	prefix := []byte(graphPrefixDomainCustody)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		key := item.Key()
+		acc := domainAccountForState(key[:len(prefix)+crypto.KeySize], graphPrefixDomainCustody)
+		var asset crypto.Hash
+		copy(asset[:], key[len(prefix)+crypto.KeySize:])
+		ival, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		custodies = append(custodies, &common.DomainCustody{
+			Domain: acc,
+			Asset:  asset,
+			Amount: common.NewIntegerFromString(string(ival)),
+		})
+	}
+	return custodies, nil
+}
+
+func (s *BadgerStore) ReadDomainCustody(domain crypto.Key, asset crypto.Hash) (common.Integer, error) {
+	txn := s.snapshotsDB.NewTransaction(false)
+	defer txn.Discard()
+
+	return readDomainCustody(txn, graphDomainCustodyKey(domain, asset))
+}
+
+func writeDomainCustody(txn *badger.Txn, publicSpend crypto.Key, asset crypto.Hash, amount common.Integer, release bool) error {
+	key := graphDomainCustodyKey(publicSpend, asset)
+	balance, err := readDomainCustody(txn, key)
+	if err != nil {
+		return err
+	}
+
+	if !release {
+		balance = balance.Add(amount)
+	} else if balance.Cmp(amount) < 0 {
+		return fmt.Errorf("invalid custody balance %s %s %s", publicSpend, balance, amount)
+	} else {
+		balance = balance.Sub(amount)
+	}
+
+	if balance.Sign() == 0 {
+		return txn.Delete(key)
+	}
+	return txn.Set(key, []byte(balance.String()))
+}
+
+func readDomainCustody(txn *badger.Txn, key []byte) (common.Integer, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return common.NewInteger(0), nil
+	} else if err != nil {
+		return common.NewInteger(0), err
+	}
+	ival, err := item.ValueCopy(nil)
+	if err != nil {
+		return common.NewInteger(0), err
+	}
+	return common.NewIntegerFromString(string(ival)), nil
+}
+
+func graphDomainCustodyKey(publicSpend crypto.Key, asset crypto.Hash) []byte {
+	key := append([]byte(graphPrefixDomainCustody), publicSpend[:]...)
+	return append(key, asset[:]...)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainCustody(t *testing.T) {
+	assert := assert.New(t)
+	custom, err := config.Initialize(configFilePath)
+	assert.Nil(err)
+
+	root, err := ioutil.TempDir("", "mixin-custody-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := NewBadgerStore(custom, root)
+	assert.Nil(err)
+	defer store.Close()
+
+	seed := make([]byte, 64)
+	rand.Read(seed)
+	domain := common.NewAddressFromSeed(seed)
+	asset := crypto.NewHash([]byte("custody"))
+
+	txn := store.snapshotsDB.NewTransaction(true)
+	err = writeDomainCustody(txn, domain.PublicSpendKey.Key(), asset, common.NewInteger(100), false)
+	assert.Nil(err)
+	err = writeDomainCustody(txn, domain.PublicSpendKey.Key(), asset, common.NewInteger(50), false)
+	assert.Nil(err)
+	err = writeDomainCustody(txn, domain.PublicSpendKey.Key(), asset, common.NewInteger(30), true)
+	assert.Nil(err)
+	err = writeDomainCustody(txn, domain.PublicSpendKey.Key(), asset, common.NewInteger(200), true)
+	assert.NotNil(err)
+	err = txn.Commit()
+	assert.Nil(err)
+
+	custodies, err := store.ReadDomainCustodies()
+	assert.Nil(err)
+	assert.Len(custodies, 1)
+	assert.Equal(domain.PublicSpendKey.String(), custodies[0].Domain.PublicSpendKey.String())
+	assert.Equal(asset, custodies[0].Asset)
+	assert.Equal("120.00000000", custodies[0].Amount.String())
+	balance, err := store.ReadDomainCustody(domain.PublicSpendKey.Key(), asset)
+	assert.Nil(err)
+	assert.Equal("120.00000000", balance.String())
+
+	txn = store.snapshotsDB.NewTransaction(true)
+	err = writeDomainCustody(txn, domain.PublicSpendKey.Key(), asset, common.NewInteger(120), true)
+	assert.Nil(err)
+	err = txn.Commit()
+	assert.Nil(err)
+	custodies, err = store.ReadDomainCustodies()
+	assert.Nil(err)
+	assert.Len(custodies, 0)
+	balance, err = store.ReadDomainCustody(domain.PublicSpendKey.Key(), asset)
+	assert.Nil(err)
+	assert.Equal(0, balance.Sign())
+}
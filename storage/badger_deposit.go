@@ -27,7 +27,7 @@ func (s *BadgerStore) CheckDepositInput(deposit *common.DepositData, tx crypto.H
 }
 
 func (s *BadgerStore) LockDepositInput(deposit *common.DepositData, tx crypto.Hash, fork bool) error {
-	return s.snapshotsDB.Update(func(txn *badger.Txn) error {
+	return s.updateSnapshots(func(txn *badger.Txn) error {
 		ival, err := readDepositInput(txn, deposit)
 		if err == badger.ErrKeyNotFound {
 			return writeDeposit(txn, deposit, tx)
@@ -13,28 +13,90 @@ const (
 	graphPrefixDomainRemove = "DOMAINREMOVE"
 )
 
-func (s *BadgerStore) ReadDomains() []common.Domain {
-	domains := make([]common.Domain, 0)
+func (s *BadgerStore) ReadDomains() ([]common.Domain, error) {
 	txn := s.snapshotsDB.NewTransaction(false)
 	defer txn.Discard()
 
+	return readDomainsInState(txn, common.DomainStateAccepted)
+}
+
+func (s *BadgerStore) ReadAllDomains() ([]*common.Domain, error) {
+	txn := s.snapshotsDB.NewTransaction(false)
+	defer txn.Discard()
+
+	var domains []*common.Domain
+	for _, state := range []string{common.DomainStateAccepted, common.DomainStateRemoved} {
+		all, err := readDomainsInState(txn, state)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range all {
+			domain := d
+			domains = append(domains, &domain)
+		}
+	}
+	return domains, nil
+}
+
+func readDomainsInState(txn *badger.Txn, domainState string) ([]common.Domain, error) {
+	prefix := []byte(graphPrefixDomainAccept)
+	if domainState == common.DomainStateRemoved {
+		prefix = []byte(graphPrefixDomainRemove)
+	}
+
 	it := txn.NewIterator(badger.DefaultIteratorOptions)
 	defer it.Close()
 
-	prefix := []byte(graphPrefixDomainAccept)
+	domains := make([]common.Domain, 0)
 	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		acc := domainAccountForState(it.Item().Key(), graphPrefixDomainAccept)
-		domains = append(domains, common.Domain{Account: acc})
+		item := it.Item()
+		acc := domainAccountForState(item.Key(), string(prefix))
+		ival, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		tx, timestamp := domainEntryValue(ival)
+		domains = append(domains, common.Domain{
+			Account:     acc,
+			State:       domainState,
+			Transaction: tx,
+			Timestamp:   timestamp,
+		})
 	}
-	return domains
+	return domains, nil
 }
 
 func writeDomainAccept(txn *badger.Txn, publicSpend crypto.Key, tx crypto.Hash, timestamp uint64) error {
+	err := txn.Delete(graphDomainRemoveKey(publicSpend))
+	if err != nil {
+		return err
+	}
+
 	key := graphDomainAcceptKey(publicSpend)
+	return txn.Set(key, domainEntryBytes(tx, timestamp))
+}
+
+func writeDomainRemove(txn *badger.Txn, publicSpend crypto.Key, tx crypto.Hash, timestamp uint64) error {
+	err := txn.Delete(graphDomainAcceptKey(publicSpend))
+	if err != nil {
+		return err
+	}
+
+	key := graphDomainRemoveKey(publicSpend)
+	return txn.Set(key, domainEntryBytes(tx, timestamp))
+}
+
+func domainEntryBytes(tx crypto.Hash, timestamp uint64) []byte {
 	buf := make([]byte, 8)
 	binary.BigEndian.PutUint64(buf, timestamp)
-	val := append(tx[:], buf...)
-	return txn.Set(key, val)
+	return append(tx[:], buf...)
+}
+
+func domainEntryValue(ival []byte) (crypto.Hash, uint64) {
+	var tx crypto.Hash
+	copy(tx[:], ival[:len(tx)])
+	timestamp := binary.BigEndian.Uint64(ival[len(tx):])
+	return tx, timestamp
 }
 
 func domainAccountForState(key []byte, domainState string) common.Address {
@@ -52,3 +114,7 @@ func domainAccountForState(key []byte, domainState string) common.Address {
 func graphDomainAcceptKey(publicSpend crypto.Key) []byte {
 	return append([]byte(graphPrefixDomainAccept), publicSpend[:]...)
 }
+
+func graphDomainRemoveKey(publicSpend crypto.Key) []byte {
+	return append([]byte(graphPrefixDomainRemove), publicSpend[:]...)
+}
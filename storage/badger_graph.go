@@ -123,6 +123,10 @@ func (s *BadgerStore) WriteSnapshot(snap *common.SnapshotWithTopologicalOrder) e
 	if err != nil {
 		return err
 	}
+	err = pruneCosiNonces(txn, snap.NodeId, snap.PayloadHash())
+	if err != nil {
+		return err
+	}
 	return txn.Commit()
 }
 
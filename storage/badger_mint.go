@@ -102,7 +102,7 @@ func (s *BadgerStore) ReadLastMintDistribution(group string) (*common.MintDistri
 }
 
 func (s *BadgerStore) LockMintInput(mint *common.MintData, tx crypto.Hash, fork bool) error {
-	return s.snapshotsDB.Update(func(txn *badger.Txn) error {
+	return s.updateSnapshots(func(txn *badger.Txn) error {
 		dist, err := readMintInput(txn, mint)
 		if err == badger.ErrKeyNotFound {
 			return writeMintDistribution(txn, mint, tx)
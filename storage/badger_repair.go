@@ -0,0 +1,465 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/dgraph-io/badger/v2"
+)
+
+const (
+	GraphIssueTopologyGap      = "TOPOLOGY GAP"
+	GraphIssueTopologyDangling = "TOPOLOGY DANGLING"
+	GraphIssueSnapshotOrphan   = "SNAPSHOT ORPHAN"
+	GraphIssueUniqueMissing    = "UNIQUE MISSING"
+	GraphIssueUniqueDangling   = "UNIQUE DANGLING"
+	GraphIssueRoundDangling    = "ROUND DANGLING"
+	GraphIssueLinkAhead        = "LINK AHEAD"
+)
+
+type GraphIssue struct {
+	Type    string
+	Detail  string
+	Fixable bool
+}
+
+type GraphRepairReport struct {
+	Topology  uint64
+	Snapshots uint64
+	Uniques   uint64
+	Rounds    uint64
+	Issues    []*GraphIssue
+	Applied   bool
+}
+
+type graphTopologyEntry struct {
+	order    uint64
+	target   uint64
+	key      []byte
+	snapshot *common.SnapshotWithTopologicalOrder
+}
+
+type graphRepair struct {
+	txn    *badger.Txn
+	report *GraphRepairReport
+
+	deletes  [][]byte
+	kept     uint64
+	moves    []*graphTopologyEntry
+	orphans  []*graphTopologyEntry
+	uniques  [][]byte
+	links    map[[2]crypto.Hash]uint64
+	repeated map[string]bool
+	relinked map[crypto.Hash]bool
+	nodes    map[crypto.Hash]crypto.Hash
+}
+
+// RepairGraphEntries checks the topology, snapshot, unique, round and link
+// entries of the graph, and applies all the fixes in one transaction unless
+// in dry run. The topology is renumbered to be contiguous, and the orphaned
+// snapshots are appended to the end. Dangling round references can't be
+// fixed, they are only reported. The kernel must not be running.
+func (s *BadgerStore) RepairGraphEntries(dryRun bool) (*GraphRepairReport, error) {
+	txn := s.snapshotsDB.NewTransaction(!dryRun)
+	defer txn.Discard()
+
+	r := &graphRepair{
+		txn:      txn,
+		report:   &GraphRepairReport{},
+		links:    make(map[[2]crypto.Hash]uint64),
+		repeated: make(map[string]bool),
+		relinked: make(map[crypto.Hash]bool),
+		nodes:    make(map[crypto.Hash]crypto.Hash),
+	}
+	err := r.checkTopology()
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkUniques()
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkRounds()
+	if err != nil {
+		return nil, err
+	}
+
+	fixable := false
+	for _, i := range r.report.Issues {
+		fixable = fixable || i.Fixable
+	}
+	if dryRun || !fixable {
+		return r.report, nil
+	}
+	err = r.apply()
+	if err != nil {
+		return nil, err
+	}
+	err = txn.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.report.Applied = true
+	return r.report, nil
+}
+
+func (r *graphRepair) issue(typ string, fixable bool, format string, args ...interface{}) {
+	r.report.Issues = append(r.report.Issues, &GraphIssue{
+		Type:    typ,
+		Detail:  fmt.Sprintf(format, args...),
+		Fixable: fixable,
+	})
+}
+
+func (r *graphRepair) readSnapshot(key []byte) (*common.SnapshotWithTopologicalOrder, error) {
+	item, err := r.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	var snap common.SnapshotWithTopologicalOrder
+	err = common.DecompressMsgpackUnmarshal(v, &snap)
+	if err != nil {
+		return nil, err
+	}
+	snap.Hash = snap.PayloadHash()
+	return &snap, nil
+}
+
+func (r *graphRepair) readSnapshotByHash(hash crypto.Hash) (*common.SnapshotWithTopologicalOrder, error) {
+	topo, err := r.readValue(graphSnapTopologyKey(hash))
+	if err != nil || topo == nil {
+		return nil, err
+	}
+	val, err := r.readValue(topo)
+	if err != nil || val == nil {
+		return nil, err
+	}
+	snap, err := r.readSnapshot(val)
+	if err != nil || snap == nil || snap.Hash != hash {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (r *graphRepair) readValue(key []byte) ([]byte, error) {
+	item, err := r.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (r *graphRepair) checkTopology() error {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := r.txn.NewIterator(opts)
+	defer it.Close()
+
+	var next uint64
+	prefix := []byte(graphPrefixTopology)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		r.report.Topology += 1
+		key := it.Item().KeyCopy(nil)
+		order := graphTopologyOrder(key)
+		if order > next {
+			r.issue(GraphIssueTopologyGap, true, "%d-%d", next, order-1)
+		}
+		next = order + 1
+
+		val, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		snap, err := r.readSnapshot(val)
+		if err != nil {
+			return err
+		}
+		if snap == nil {
+			r.issue(GraphIssueTopologyDangling, true, "%d %x", order, val)
+			r.deletes = append(r.deletes, key)
+			continue
+		}
+
+		e := &graphTopologyEntry{order: order, target: r.kept, key: val, snapshot: snap}
+		r.kept += 1
+		topo, err := r.readValue(graphSnapTopologyKey(snap.Hash))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(topo, key) {
+			var other []byte
+			if topo != nil {
+				other, err = r.readValue(topo)
+				if err != nil {
+					return err
+				}
+			}
+			if bytes.Equal(other, val) {
+				r.issue(GraphIssueTopologyDangling, true, "%d %s duplicated", order, snap.Hash)
+				r.deletes = append(r.deletes, key)
+				continue
+			}
+			r.issue(GraphIssueTopologyDangling, true, "%d %s relinked", order, snap.Hash)
+			r.relinked[snap.Hash] = true
+			r.nodes[snap.Hash] = snap.NodeId
+			r.moves = append(r.moves, e)
+		} else if e.target != e.order {
+			r.moves = append(r.moves, e)
+		}
+	}
+	it.Close()
+
+	it = r.txn.NewIterator(opts)
+	defer it.Close()
+	prefix = []byte(graphPrefixSnapTopology)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		key := it.Item().KeyCopy(nil)
+		var hash crypto.Hash
+		copy(hash[:], key[len(prefix):])
+		if r.relinked[hash] {
+			continue
+		}
+		snap, err := r.readSnapshotByHash(hash)
+		if err != nil {
+			return err
+		}
+		if snap == nil {
+			r.issue(GraphIssueTopologyDangling, true, "SNAPTOPO %s", hash)
+			r.deletes = append(r.deletes, key)
+		}
+	}
+	return nil
+}
+
+func (r *graphRepair) checkSnapshots() error {
+	it := r.txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := []byte(graphPrefixSnapshot)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		r.report.Snapshots += 1
+		key := it.Item().KeyCopy(nil)
+		snap, err := r.readSnapshot(key)
+		if err != nil {
+			return err
+		}
+
+		topo, err := r.readValue(graphSnapTopologyKey(snap.Hash))
+		if err != nil {
+			return err
+		}
+		var val []byte
+		if topo != nil {
+			val, err = r.readValue(topo)
+			if err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(val, key) && !r.relinked[snap.Hash] {
+			r.issue(GraphIssueSnapshotOrphan, true, "%s %s %d", snap.Hash, snap.NodeId, snap.RoundNumber)
+			r.orphans = append(r.orphans, &graphTopologyEntry{key: key, snapshot: snap})
+			r.nodes[snap.Hash] = snap.NodeId
+		}
+
+		unique := graphUniqueKey(snap.NodeId, snap.Transaction)
+		_, err = r.txn.Get(unique)
+		if err == badger.ErrKeyNotFound {
+			r.issue(GraphIssueUniqueMissing, true, "%s %s", snap.NodeId, snap.Transaction)
+			r.uniques = append(r.uniques, unique)
+		} else if err != nil {
+			return err
+		}
+
+		fin, err := r.readValue(graphFinalizationKey(snap.Transaction))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(fin, snap.Hash[:]) {
+			r.repeated[string(unique)] = true
+		}
+	}
+	return nil
+}
+
+// checkUniques finds the unique entries without snapshot, the snapshot of
+// a unique entry is found through the transaction finalization, or in the
+// repeated snapshots which are not the first one to finalize the transaction.
+func (r *graphRepair) checkUniques() error {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := r.txn.NewIterator(opts)
+	defer it.Close()
+
+	prefix := []byte(graphPrefixUnique)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		r.report.Uniques += 1
+		key := it.Item().KeyCopy(nil)
+		if r.repeated[string(key)] {
+			continue
+		}
+		var node, tx crypto.Hash
+		copy(tx[:], key[len(prefix):])
+		copy(node[:], key[len(prefix)+len(tx):])
+
+		fin, err := r.readValue(graphFinalizationKey(tx))
+		if err != nil {
+			return err
+		}
+		if len(fin) == len(crypto.Hash{}) {
+			var hash crypto.Hash
+			copy(hash[:], fin)
+			if id, found := r.nodes[hash]; found && id == node {
+				continue
+			}
+			snap, err := r.readSnapshotByHash(hash)
+			if err != nil {
+				return err
+			}
+			if snap != nil && snap.NodeId == node {
+				continue
+			}
+		}
+		r.issue(GraphIssueUniqueDangling, true, "%s %s", node, tx)
+		r.deletes = append(r.deletes, key)
+	}
+	return nil
+}
+
+func (r *graphRepair) checkRounds() error {
+	it := r.txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	heads := make(map[crypto.Hash]*common.Round)
+	prefix := []byte(graphPrefixRound)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		r.report.Rounds += 1
+		var hash crypto.Hash
+		copy(hash[:], it.Item().Key()[len(prefix):])
+		v, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		var round common.Round
+		err = common.MsgpackUnmarshal(v, &round)
+		if err != nil {
+			return err
+		}
+		if round.NodeId == hash {
+			heads[hash] = &round
+		}
+		if round.References == nil {
+			continue
+		}
+		for _, ref := range []crypto.Hash{round.References.Self, round.References.External} {
+			old, err := readRound(r.txn, ref)
+			if err != nil {
+				return err
+			}
+			if old == nil {
+				r.issue(GraphIssueRoundDangling, false, "%s %s %d %s", hash, round.NodeId, round.Number, ref)
+			}
+		}
+	}
+
+	for from := range heads {
+		for to, head := range heads {
+			if from == to {
+				continue
+			}
+			link, err := readLink(r.txn, from, to)
+			if err != nil {
+				return err
+			}
+			var final uint64
+			if head.Number > 0 {
+				final = head.Number - 1
+			}
+			if link > final {
+				r.issue(GraphIssueLinkAhead, true, "%s %s %d %d", from, to, link, final)
+				r.links[[2]crypto.Hash{from, to}] = final
+			}
+		}
+	}
+	return nil
+}
+
+// apply writes all the fixes in the transaction, a badger transaction has
+// a size limit, so the error is reported with the count of entries to fix.
+func (r *graphRepair) apply() error {
+	err := r.applyFixes()
+	if err == badger.ErrTxnTooBig {
+		count := len(r.deletes) + len(r.moves) + len(r.orphans) + len(r.uniques) + len(r.links)
+		return fmt.Errorf("graph repair of %d entries too big for one transaction %s", count, err)
+	}
+	return err
+}
+
+// applyFixes deletes the dangling entries first, then renumbers the topology
+// in ascending order, so a new order never overwrites an entry not moved yet.
+// Only the entries moved or relinked are rewritten, and the orphans are
+// appended after all the entries kept.
+func (r *graphRepair) applyFixes() error {
+	for _, key := range r.deletes {
+		err := r.txn.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(r.orphans, func(i, j int) bool {
+		a, b := r.orphans[i].snapshot, r.orphans[j].snapshot
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp < b.Timestamp
+		}
+		return bytes.Compare(a.Hash[:], b.Hash[:]) < 0
+	})
+	for i, e := range r.orphans {
+		e.target = r.kept + uint64(i)
+	}
+	for i, e := range append(r.moves, r.orphans...) {
+		if i < len(r.moves) && e.order != e.target {
+			err := r.txn.Delete(graphTopologyKey(e.order))
+			if err != nil {
+				return err
+			}
+		}
+		e.snapshot.TopologicalOrder = e.target
+		err := r.txn.Set(e.key, common.CompressMsgpackMarshalPanic(e.snapshot))
+		if err != nil {
+			return err
+		}
+		err = writeTopology(r.txn, e.snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range r.uniques {
+		err := r.txn.Set(key, []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	for pair, link := range r.links {
+		err := writeLink(r.txn, pair[0], pair[1], link)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
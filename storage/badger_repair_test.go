@@ -0,0 +1,112 @@
+// +build ed25519 !custom_alg
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairGraphEntries(t *testing.T) {
+	assert := assert.New(t)
+	custom, err := config.Initialize(configFilePath)
+	assert.Nil(err)
+
+	root, err := ioutil.TempDir("", "mixin-repair-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := NewBadgerStore(custom, root)
+	assert.Nil(err)
+	defer store.Close()
+
+	node := crypto.NewHash([]byte("repair-node"))
+	peer := crypto.NewHash([]byte("repair-peer"))
+	var snapshots []*common.SnapshotWithTopologicalOrder
+	txn := store.snapshotsDB.NewTransaction(true)
+	for i := 0; i < 5; i++ {
+		tx := common.NewTransaction(common.XINAssetId)
+		tx.Extra = []byte{byte(i)}
+		ver := tx.AsLatestVersion()
+		assert.Nil(writeTransaction(txn, ver))
+		snap := &common.SnapshotWithTopologicalOrder{
+			Snapshot: common.Snapshot{
+				Version:     common.SnapshotVersion,
+				NodeId:      node,
+				Transaction: ver.PayloadHash(),
+				Timestamp:   uint64(i + 1),
+			},
+			TopologicalOrder: uint64(i),
+		}
+		snap.Hash = snap.PayloadHash()
+		assert.Nil(writeSnapshot(txn, snap, ver))
+		snapshots = append(snapshots, snap)
+	}
+	assert.Nil(txn.Delete(graphTopologyKey(1)))
+	assert.Nil(txn.Delete(graphUniqueKey(node, snapshots[3].Transaction)))
+	assert.Nil(txn.Set(graphUniqueKey(node, crypto.NewHash([]byte("repair-dangling"))), []byte{}))
+	assert.Nil(txn.Set(graphTopologyKey(9), graphSnapshotKey(node, 7, crypto.Hash{})))
+	assert.Nil(writeRound(txn, node, &common.Round{
+		NodeId: node,
+		Number: 2,
+		References: &common.RoundLink{
+			Self:     crypto.NewHash([]byte("repair-self")),
+			External: crypto.NewHash([]byte("repair-external")),
+		},
+	}))
+	assert.Nil(writeRound(txn, peer, &common.Round{NodeId: peer, Number: 1}))
+	assert.Nil(writeLink(txn, node, peer, 5))
+	assert.Nil(txn.Commit())
+
+	count := func(report *GraphRepairReport) map[string]int {
+		types := make(map[string]int)
+		for _, i := range report.Issues {
+			types[i.Type] += 1
+		}
+		return types
+	}
+	report, err := store.RepairGraphEntries(true)
+	assert.Nil(err)
+	assert.False(report.Applied)
+	assert.Equal(uint64(5), report.Topology)
+	assert.Equal(uint64(5), report.Snapshots)
+	assert.Equal(map[string]int{
+		GraphIssueTopologyGap:      2,
+		GraphIssueTopologyDangling: 2,
+		GraphIssueSnapshotOrphan:   1,
+		GraphIssueUniqueMissing:    1,
+		GraphIssueUniqueDangling:   1,
+		GraphIssueRoundDangling:    2,
+		GraphIssueLinkAhead:        1,
+	}, count(report))
+	assert.Equal(uint64(10), store.TopologySequence())
+
+	report, err = store.RepairGraphEntries(false)
+	assert.Nil(err)
+	assert.True(report.Applied)
+	assert.Equal(uint64(5), store.TopologySequence())
+	topology, err := store.ReadSnapshotsSinceTopology(0, 10)
+	assert.Nil(err)
+	assert.Len(topology, 5)
+	for i, s := range topology {
+		assert.Equal(uint64(i), s.TopologicalOrder)
+		snap, err := store.ReadSnapshot(s.Hash)
+		assert.Nil(err)
+		assert.Equal(uint64(i), snap.TopologicalOrder)
+	}
+	assert.Equal(snapshots[1].Hash, topology[4].Hash)
+	link, err := store.ReadLink(node, peer)
+	assert.Nil(err)
+	assert.Equal(uint64(0), link)
+
+	report, err = store.RepairGraphEntries(false)
+	assert.Nil(err)
+	assert.False(report.Applied)
+	assert.Equal(map[string]int{GraphIssueRoundDangling: 2}, count(report))
+}
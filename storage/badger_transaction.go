@@ -39,65 +39,60 @@ func (s *BadgerStore) ReadTransaction(hash crypto.Hash) (*common.VersionedTransa
 }
 
 func (s *BadgerStore) WriteTransaction(ver *common.VersionedTransaction) error {
-	txn := s.snapshotsDB.NewTransaction(true)
-	defer txn.Discard()
+	return s.updateSnapshots(func(txn *badger.Txn) error {
+		// FIXME assert kind checks, not needed at all
+		if config.Debug {
+			txHash := ver.PayloadHash()
+			for _, in := range ver.Inputs {
+				if len(in.Genesis) > 0 {
+					continue
+				}
 
-	// FIXME assert kind checks, not needed at all
-	if config.Debug {
-		txHash := ver.PayloadHash()
-		for _, in := range ver.Inputs {
-			if len(in.Genesis) > 0 {
-				continue
-			}
+				if in.Deposit != nil {
+					ival, err := readDepositInput(txn, in.Deposit)
+					if err != nil {
+						panic(fmt.Errorf("deposit check error %s", err.Error()))
+					}
+					if bytes.Compare(ival, txHash[:]) != 0 {
+						panic(fmt.Errorf("deposit locked for transaction %s", hex.EncodeToString(ival)))
+					}
+					continue
+				}
 
-			if in.Deposit != nil {
-				ival, err := readDepositInput(txn, in.Deposit)
+				if in.Mint != nil {
+					dist, err := readMintInput(txn, in.Mint)
+					if err != nil {
+						panic(fmt.Errorf("mint check error %s", err.Error()))
+					}
+					if dist.Transaction != txHash || dist.Amount.Cmp(in.Mint.Amount) != 0 {
+						panic(fmt.Errorf("mint locked for transaction %s", dist.Transaction.String()))
+					}
+					continue
+				}
+
+				key := graphUtxoKey(in.Hash, in.Index)
+				item, err := txn.Get(key)
 				if err != nil {
-					panic(fmt.Errorf("deposit check error %s", err.Error()))
+					panic(fmt.Errorf("UTXO check error %s %s:%d=>%s", err.Error(), in.Hash.String(), in.Index, txHash.String()))
 				}
-				if bytes.Compare(ival, txHash[:]) != 0 {
-					panic(fmt.Errorf("deposit locked for transaction %s", hex.EncodeToString(ival)))
+				ival, err := item.ValueCopy(nil)
+				if err != nil {
+					panic(fmt.Errorf("UTXO check error %s", err.Error()))
 				}
-				continue
-			}
-
-			if in.Mint != nil {
-				dist, err := readMintInput(txn, in.Mint)
+				var out common.UTXOWithLock
+				err = common.DecompressMsgpackUnmarshal(ival, &out)
 				if err != nil {
-					panic(fmt.Errorf("mint check error %s", err.Error()))
+					panic(fmt.Errorf("UTXO check error %s", err.Error()))
 				}
-				if dist.Transaction != txHash || dist.Amount.Cmp(in.Mint.Amount) != 0 {
-					panic(fmt.Errorf("mint locked for transaction %s", dist.Transaction.String()))
+				if out.LockHash != txHash {
+					panic(fmt.Errorf("utxo locked for transaction %s", out.LockHash))
 				}
-				continue
-			}
-
-			key := graphUtxoKey(in.Hash, in.Index)
-			item, err := txn.Get(key)
-			if err != nil {
-				panic(fmt.Errorf("UTXO check error %s %s:%d=>%s", err.Error(), in.Hash.String(), in.Index, txHash.String()))
-			}
-			ival, err := item.ValueCopy(nil)
-			if err != nil {
-				panic(fmt.Errorf("UTXO check error %s", err.Error()))
-			}
-			var out common.UTXOWithLock
-			err = common.DecompressMsgpackUnmarshal(ival, &out)
-			if err != nil {
-				panic(fmt.Errorf("UTXO check error %s", err.Error()))
-			}
-			if out.LockHash != txHash {
-				panic(fmt.Errorf("utxo locked for transaction %s", out.LockHash))
 			}
 		}
-	}
-	// assert end
+		// assert end
 
-	err := writeTransaction(txn, ver)
-	if err != nil {
-		return err
-	}
-	return txn.Commit()
+		return writeTransaction(txn, ver)
+	})
 }
 
 func (s *BadgerStore) CheckTransactionInNode(nodeId, hash crypto.Hash) (bool, error) {
@@ -227,6 +222,12 @@ func writeUTXO(txn *badger.Txn, utxo *common.UTXO, extra []byte, timestamp uint6
 		return writeNodeRemove(txn, signer, payee, utxo.Hash, timestamp)
 	case common.OutputTypeDomainAccept:
 		return writeDomainAccept(txn, signer, utxo.Hash, timestamp)
+	case common.OutputTypeDomainRemove:
+		return writeDomainRemove(txn, signer, utxo.Hash, timestamp)
+	case common.OutputTypeDomainAssetCustody:
+		return writeDomainCustody(txn, signer, utxo.Asset, utxo.Amount, false)
+	case common.OutputTypeDomainAssetRelease:
+		return writeDomainCustody(txn, signer, utxo.Asset, utxo.Amount, true)
 	}
 
 	return nil
@@ -1,10 +1,9 @@
 package storage
 
 import (
-	"fmt"
-
 	"github.com/MixinNetwork/mixin/common"
 	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/MixinNetwork/mixin/logger"
 	"github.com/dgraph-io/badger/v2"
 )
 
@@ -31,7 +30,9 @@ func (s *BadgerStore) ReadUTXO(hash crypto.Hash, index int) (*common.UTXOWithLoc
 }
 
 func (s *BadgerStore) LockUTXO(hash crypto.Hash, index int, tx crypto.Hash, fork bool) error {
-	return s.snapshotsDB.Update(func(txn *badger.Txn) error {
+	var conflict *common.UTXOConflict
+	err := s.updateSnapshots(func(txn *badger.Txn) error {
+		conflict = nil
 		key := graphUtxoKey(hash, index)
 		item, err := txn.Get(key)
 		if err != nil {
@@ -49,17 +50,32 @@ func (s *BadgerStore) LockUTXO(hash crypto.Hash, index int, tx crypto.Hash, fork
 		}
 
 		if out.LockHash.HasValue() && out.LockHash != tx {
+			conflict = &common.UTXOConflict{
+				Hash:    hash,
+				Index:   index,
+				Locker:  out.LockHash,
+				Spender: tx,
+			}
 			if !fork {
-				return fmt.Errorf("utxo locked for transaction %s", out.LockHash)
+				return common.NewValidationError(common.ErrorCodeInputLocked, "utxo locked for transaction %s", out.LockHash)
 			}
 			err := pruneTransaction(txn, out.LockHash)
 			if err != nil {
+				conflict = nil
 				return err
 			}
+			conflict.Pruned = true
 		}
 		out.LockHash = tx
 		return txn.Set(key, common.CompressMsgpackMarshalPanic(out))
 	})
+	if conflict != nil && (err == nil || !conflict.Pruned) {
+		cerr := s.writeConflict(conflict)
+		if cerr != nil {
+			logger.Printf("LockUTXO writeConflict %s:%d %s %v\n", hash, index, tx, cerr)
+		}
+	}
+	return err
 }
 
 func (s *BadgerStore) CheckGhost(key crypto.Key) (bool, error) {
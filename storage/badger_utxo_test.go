@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/MixinNetwork/mixin/common"
+	"github.com/MixinNetwork/mixin/config"
+	"github.com/MixinNetwork/mixin/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentLockInputs(t *testing.T) {
+	assert := assert.New(t)
+	custom, err := config.Initialize(configFilePath)
+	assert.Nil(err)
+
+	root, err := ioutil.TempDir("", "mixin-utxo-test")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	store, err := NewBadgerStore(custom, root)
+	assert.Nil(err)
+	defer store.Close()
+
+	hash := crypto.NewHash([]byte("utxo"))
+	tx := common.NewTransaction(common.XINAssetId)
+	txn := store.snapshotsDB.NewTransaction(true)
+	for i := 0; i < 4; i++ {
+		utxo := &common.UTXOWithLock{UTXO: common.UTXO{Input: common.Input{Hash: hash, Index: i}}}
+		utxo.Amount = common.NewInteger(1)
+		err = txn.Set(graphUtxoKey(hash, i), common.CompressMsgpackMarshalPanic(utxo))
+		assert.Nil(err)
+		tx.AddInput(hash, i)
+	}
+	err = txn.Commit()
+	assert.Nil(err)
+	tx.AddScriptOutput(nil, common.NewThresholdScript(1), common.NewInteger(4), []byte("seed"))
+	ver := tx.AsLatestVersion()
+
+	// all chains may lock and write the same transaction at the same time
+	var wg sync.WaitGroup
+	errors := make([]error, 16)
+	for i := range errors {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := ver.LockInputs(store, false)
+			if err == nil {
+				err = store.WriteTransaction(ver)
+			}
+			errors[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errors {
+		assert.Nil(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		out, err := store.ReadUTXO(hash, i)
+		assert.Nil(err)
+		assert.Equal(ver.PayloadHash(), out.LockHash)
+	}
+	conflicts, err := store.ReadTransactionConflicts(ver.PayloadHash())
+	assert.Nil(err)
+	assert.Len(conflicts, 0)
+}
@@ -22,6 +22,10 @@ type Store interface {
 
 	ReadUTXO(hash crypto.Hash, index int) (*common.UTXOWithLock, error)
 	LockUTXO(hash crypto.Hash, index int, tx crypto.Hash, fork bool) error
+	ReadUTXOConflicts(hash crypto.Hash, index int) ([]*common.UTXOConflict, error)
+	ReadTransactionConflicts(hash crypto.Hash) ([]*common.UTXOConflict, error)
+	ListConflicts(since uint64, count int) ([]*common.UTXOConflict, error)
+	SubscribeConflicts(hook func(c *common.UTXOConflict))
 	CheckDepositInput(deposit *common.DepositData, tx crypto.Hash) error
 	LockDepositInput(deposit *common.DepositData, tx crypto.Hash, fork bool) error
 	CheckGhost(key crypto.Key) (bool, error)
@@ -32,11 +36,20 @@ type Store interface {
 	ReadRound(hash crypto.Hash) (*common.Round, error)
 	ReadLink(from, to crypto.Hash) (uint64, error)
 	WriteSnapshot(*common.SnapshotWithTopologicalOrder) error
-	ReadDomains() []common.Domain
+	ReadDomains() ([]common.Domain, error)
+	ReadAllDomains() ([]*common.Domain, error)
+	ReadDomainCustodies() ([]*common.DomainCustody, error)
+	ReadDomainCustody(domain crypto.Key, asset crypto.Hash) (common.Integer, error)
 
 	CachePutTransaction(tx *common.VersionedTransaction) error
 	CacheGetTransaction(hash crypto.Hash) (*common.VersionedTransaction, error)
 	CacheListTransactions(hook func(tx *common.VersionedTransaction) error) error
+	CachePutCosiSession(chain, snapshot crypto.Hash, data []byte) error
+	CacheRemoveCosiSession(chain, snapshot crypto.Hash) error
+	CacheListCosiSessions(chain crypto.Hash, hook func(snapshot crypto.Hash, data []byte) error) error
+
+	NextCosiNonce(chain, snapshot crypto.Hash) (uint64, error)
+	CheckCosiNonce(chain, snapshot crypto.Hash, counter uint64, challenge crypto.Hash) (bool, error)
 
 	ReadLastMintDistribution(group string) (*common.MintDistribution, error)
 	LockMintInput(mint *common.MintData, tx crypto.Hash, fork bool) error
@@ -44,4 +57,5 @@ type Store interface {
 
 	RemoveGraphEntries(prefix string) error
 	ValidateGraphEntries(networkId crypto.Hash, depth uint64) (int, int, error)
+	RepairGraphEntries(dryRun bool) (*GraphRepairReport, error)
 }